@@ -0,0 +1,28 @@
+package binary
+
+import (
+	"path/filepath"
+)
+
+// NewAsset instantiates a [Binary] describing a non-executable asset needed by a toolchain,
+// like a directory of headers, json schemas or timezone data, given its name, version and [Origin].
+//
+// Assets are provisioned with the same semantics as binaries, but they're not made executable
+// and there's no command to verify their version. Instead, the install receipt recorded after
+// provisioning is used to check that the expected version is present; assets without a valid
+// receipt are always provisioned again.
+//
+// The asset is placed in the bin directory under the specified name, which is also the path
+// returned by [Binary.BinPath]. When extracting a whole directory from an archive, map the
+// directory to the asset name, e.g. {"protoc/include/": "include/"} for an asset named "include".
+func NewAsset(name, version string, origin Origin, options ...Option) *Binary {
+	bin := New(name, version, origin, options...)
+
+	bin.asset = true
+	bin.versioncmd = SkipVersionCheck
+	bin.template.Asset = true
+	bin.template.Extension = ""
+	bin.template.Cmd = filepath.Join(bin.directory, name)
+
+	return bin
+}
@@ -0,0 +1,87 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAsset(t *testing.T) {
+	var origin *fakeorigin
+	asset := NewAsset("schema.json", "1.0.0", origin)
+
+	assert.Equal(t, filepath.Join(filepath.FromSlash("./bin"), "schema.json"), asset.BinPath())
+	assert.Equal(t, SkipVersionCheck, asset.versioncmd)
+	assert.True(t, asset.template.Asset)
+	assert.Empty(t, asset.template.Extension)
+}
+
+func TestAssetEnsure(t *testing.T) {
+	t.Run("provisions file without executable permissions",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			withTempDir(t)
+
+			asset := NewAsset("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, asset.Ensure(t.Context()))
+
+			info, err := os.Stat(asset.BinPath())
+			require.NoError(t, err)
+			if runtime.GOOS != "windows" {
+				assert.Zero(t, info.Mode().Perm()&0o111)
+			}
+		},
+	)
+
+	t.Run("is trusted while receipt is valid",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			withTempDir(t)
+
+			require.NoError(t, NewAsset("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util")).Ensure(t.Context()))
+
+			// with the server gone, any download attempt would fail
+			srv.Close()
+
+			require.NoError(t, NewAsset("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util")).Ensure(t.Context()))
+		},
+	)
+
+	t.Run("is provisioned again when version changes",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			withTempDir(t)
+
+			require.NoError(t, NewAsset("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util")).Ensure(t.Context()))
+
+			srv.Close()
+
+			err := NewAsset("util", "2.0.0", RemoteBinaryDownload(srv.URL+"/util")).Ensure(t.Context())
+			require.Error(t, err, "a download should have been attempted for a different version")
+		},
+	)
+
+	t.Run("extracts directory from archive",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			withTempDir(t)
+
+			asset := NewAsset(
+				"tools",
+				"1.2.3",
+				RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-{{.Version}}/bin/": "tools/"}),
+			)
+			require.NoError(t, asset.Ensure(t.Context()))
+
+			info, err := os.Stat(filepath.Join(asset.BinPath(), "util"))
+			require.NoError(t, err)
+			if runtime.GOOS != "windows" {
+				assert.Zero(t, info.Mode().Perm()&0o111)
+			}
+		},
+	)
+}
@@ -1,14 +1,17 @@
 package binary
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aexvir/harness/internal"
 )
@@ -29,6 +32,34 @@ type Binary struct {
 
 	// command that will be run to obtain the version of the binary
 	versioncmd string
+	// run the version command even if there's a valid install receipt
+	forceverify bool
+	// non-executable file whose version can only be checked via install receipts
+	asset bool
+	// binary provided by the system, which is never installed
+	system bool
+	// provision the binary through the shared cache, see [WithSharedCache]
+	cache    bool
+	cachedir string
+	// reports the progress of downloads instead of the progress bar
+	progress ProgressFunc
+	// how long the resolution of "latest" is reused, see [WithLatestTTL]
+	latestttl time.Duration
+	// extracts the version from the output of the version command, see [WithVersionRegexp]
+	versionexpr *regexp.Regexp
+	// amount of version components compared, all of them when zero
+	versionprecision int
+	// path of the version in the json output of the version command, see [WithVersionJSONPath]
+	versionjson []string
+
+	// install on every ensure bypassing every cache, see [WithForce]
+	force bool
+	// run before and after the origin installs the binary, see [WithPreInstall]
+	preinstall  []func(Template) error
+	postinstall []func(Template) error
+
+	// invalid options are reported when ensuring, as options can't fail
+	err error
 
 	// origin that will be used to provision the binary
 	origin Origin
@@ -39,15 +70,41 @@ type Binary struct {
 // New instantiates a new [Binary] given a command name, a version and it's [Origin].
 // Origins determine where the binary is provisioned from, if it needs installation and how
 // the installation process is handled.
+// The version and the bin directory can be overridden in the harness.yaml config file,
+// see [github.com/aexvir/harness.LoadConfig], and the bin directory with the HARNESS_BIN_DIR
+// env variable or [WithDirectory]. Configured versions are written like the version specified
+// in code, see [configuredversion], so both "1.2.3" and "v1.2.3" can be configured.
 func New(command, version string, origin Origin, options ...Option) *Binary {
 	var extension string
 	if runtime.GOOS == "windows" {
 		extension = ".exe"
 	}
 
-	bindir := filepath.FromSlash("./bin")
+	config := internal.CurrentConfig()
+	if configured, ok := config.Versions[command]; ok {
+		version = configuredversion(version, configured, origin)
+	}
+
+	// tools declared in go.mod are versioned there
+	if tool, ok := origin.(*gotool); ok && version == "" {
+		if resolved, err := tool.resolve(); err == nil {
+			version = resolved.version
+		}
+	}
+
+	bindir := defaultbindir()
 	cmdQualifiedPath := filepath.Join(bindir, command) + extension
 
+	_, system := origin.(*system)
+	if system {
+		// system binaries are used from PATH, falling back to the command name so
+		// they are reported as missing
+		cmdQualifiedPath = command
+		if path, err := exec.LookPath(command); err == nil {
+			cmdQualifiedPath = path
+		}
+	}
+
 	bin := Binary{
 		command:   command,
 		directory: bindir,
@@ -55,12 +112,23 @@ func New(command, version string, origin Origin, options ...Option) *Binary {
 
 		versioncmd: fmt.Sprintf("%s --version", cmdQualifiedPath),
 
+		system:      system,
+		forceverify: system,
+
+		cachedir:  os.Getenv(CacheDirEnv),
+		latestttl: DefaultLatestTTL,
+
 		origin: origin,
 	}
+	bin.cache = bin.cachedir != ""
 
+	host := hostplatform()
 	bin.template = Template{
-		GOOS:   runtime.GOOS,
-		GOARCH: runtime.GOARCH,
+		GOOS:    runtime.GOOS,
+		GOARCH:  runtime.GOARCH,
+		GOARM:   host.goarm,
+		Libc:    host.libc,
+		Rosetta: host.rosetta,
 
 		Directory:        bin.directory,
 		Name:             command,
@@ -77,11 +145,81 @@ func New(command, version string, origin Origin, options ...Option) *Binary {
 	return &bin
 }
 
+// BinDirEnv is the env variable overriding the directory binaries are provisioned into,
+// e.g. HARNESS_BIN_DIR=~/.harness/bin to share them across repositories.
+const BinDirEnv = "HARNESS_BIN_DIR"
+
+// defaultbindir returns the directory binaries are provisioned into, ./bin unless
+// overridden by the HARNESS_BIN_DIR env variable or in the harness.yaml config file.
+func defaultbindir() string {
+	if dir := os.Getenv(BinDirEnv); dir != "" {
+		return expandhome(filepath.FromSlash(dir))
+	}
+	if dir := internal.CurrentConfig().BinDir; dir != "" {
+		return expandhome(filepath.FromSlash(dir))
+	}
+	return filepath.FromSlash("./bin")
+}
+
+// UserDirectory returns a bin directory in the home of the user, ~/.harness/bin,
+// for binaries shared by every repository, see [WithDirectory].
+func UserDirectory() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home dir: %w", err)
+	}
+	return filepath.Join(home, ".harness", "bin"), nil
+}
+
+// expandhome replaces a leading ~ in the path with the home directory of the user.
+func expandhome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && !os.IsPathSeparator(rest[0])) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
+
+// configuredversion returns the version configured for a binary with or without the "v"
+// prefix, like the version specified in code, as it's usually part of the url templates.
+// When the version in code doesn't tell, e.g. "latest", go modules are versioned with the
+// prefix and every other origin without it.
+func configuredversion(version, configured string, origin Origin) string {
+	if configured == "" || configured == "latest" {
+		return configured
+	}
+
+	var prefixed bool
+	switch origin.(type) {
+	case *gopkg, *gotool:
+		prefixed = true
+	}
+	if version != "" && version != "latest" {
+		prefixed = strings.HasPrefix(version, "v")
+	}
+
+	configured = strings.TrimPrefix(configured, "v")
+	if prefixed {
+		return "v" + configured
+	}
+	return configured
+}
+
 // Name returns the command name of the binary.
 func (b *Binary) Name() string {
 	return b.template.Name
 }
 
+// Version returns the version of the binary, after applying the one configured in the config
+// file, if any, and resolving "latest" once the binary is ensured.
+func (b *Binary) Version() string {
+	return b.version
+}
+
 // BinPath returns the qualified path to the binary.
 // It's recommended to use this method to obtain the binary command string.
 func (b *Binary) BinPath() string {
@@ -89,30 +227,127 @@ func (b *Binary) BinPath() string {
 }
 
 // Ensure the binary is installed and it corresponds to the expected version.
-func (b *Binary) Ensure() error {
+// Cancelling the context aborts the version check and any install in progress.
+func (b *Binary) Ensure(ctx context.Context) error {
 	if b.version == "" {
 		return fmt.Errorf("version must be set")
 	}
+	if b.err != nil {
+		return b.err
+	}
+	if b.force {
+		ctx = withforce(ctx)
+	}
 
-	if b.isInstalled() && b.isExpectedVersion() {
-		return nil
+	if b.system {
+		return b.ensure(ctx)
 	}
 
-	return b.Install()
+	// concurrent calls for the same binary, from other targets or processes, wait
+	// for the install in progress and then find the binary already provisioned
+	return b.withlock(ctx, func() error { return b.ensure(ctx) })
+}
+
+func (b *Binary) ensure(ctx context.Context) error {
+	b.resolvelatest(ctx)
+
+	if b.isInstalled() && (b.system || !forced(ctx)) {
+		if !b.forceverify && b.hasValidReceipt() {
+			b.touchReceipt()
+			return nil
+		}
+
+		if !b.asset && b.isExpectedVersion(ctx) {
+			if !b.system {
+				b.recordReceipt()
+			}
+			return nil
+		}
+	}
+
+	if b.system {
+		return b.origin.Install(ctx, b.template)
+	}
+
+	return b.install(ctx)
 }
 
 // Install the binary.
-func (b *Binary) Install() error {
+// Installs are serialized across processes with a lock file in the bin directory.
+func (b *Binary) Install(ctx context.Context) error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.force {
+		ctx = withforce(ctx)
+	}
+
+	return b.withlock(ctx, func() error {
+		b.resolvelatest(ctx)
+		return b.install(ctx)
+	})
+}
+
+func (b *Binary) install(ctx context.Context) error {
+	if err := b.checkoffline(ctx); err != nil {
+		return err
+	}
+
 	internal.LogStep(fmt.Sprintf("installing %s", b.template.Name))
-	return internal.WithIndeterminateProgressbar(
+	ctx = withprogress(ctx, b.progress)
+	start := time.Now()
+	err := internal.WithIndeterminateProgressbar(
 		func() error {
-			return b.origin.Install(b.template)
+			for _, hook := range b.preinstall {
+				if err := hook(b.template); err != nil {
+					return fmt.Errorf("pre-install hook failed: %w", err)
+				}
+			}
+
+			if b.cacheable() {
+				if err := b.installcached(ctx); err != nil {
+					return err
+				}
+			} else if err := b.origin.Install(ctx, b.template); err != nil {
+				return err
+			}
+
+			for _, hook := range b.postinstall {
+				if err := hook(b.template); err != nil {
+					// remove the binary so the hook runs again on the next install
+					if rmerr := os.RemoveAll(b.template.Cmd); rmerr != nil {
+						err = errors.Join(err, fmt.Errorf("failed to remove %s: %w", b.template.Cmd, rmerr))
+					}
+					return fmt.Errorf("post-install hook failed: %w", err)
+				}
+			}
+			return nil
 		},
 	)
+	internal.Metrics.ObserveProvision(b.template.Name, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+
+	b.recordReceipt()
+	return nil
+}
+
+// recordReceipt writes the install receipt for the binary.
+// Receipts only speed up subsequent checks, so failing to write one isn't fatal.
+func (b *Binary) recordReceipt() {
+	if err := b.writeReceipt(); err != nil {
+		internal.LogDetail(fmt.Sprintf("failed to record install receipt: %s", err))
+	}
 }
 
 // isInstalled returns true if the binary is installed.
 func (b *Binary) isInstalled() bool {
+	if b.system {
+		_, err := exec.LookPath(b.template.Cmd)
+		return err == nil
+	}
+
 	_, err := os.Stat(b.template.Cmd)
 	return err == nil
 }
@@ -120,9 +355,10 @@ func (b *Binary) isInstalled() bool {
 // isExpectedVersion returns true if binary version matches the expected version
 // or latest version was requested.
 // This check can be skipped by setting the version to SkipVersionCheck.
-// If the version is "latest", there's no easy way to verify if the binary is actually
-// the latest version, so it assumes it is, returning true.
-func (b *Binary) isExpectedVersion() bool {
+// If the version is "latest", it's resolved to a concrete version beforehand for origins
+// implementing [LatestResolver]; otherwise there's no easy way to verify if the binary is
+// actually the latest version, so it assumes it is, returning true.
+func (b *Binary) isExpectedVersion(ctx context.Context) bool {
 	if b.version == "latest" {
 		return true
 	}
@@ -135,10 +371,10 @@ func (b *Binary) isExpectedVersion() bool {
 	args := strings.Split(b.versioncmd, " ")
 
 	internal.LogStep(fmt.Sprintf("running %v looking for %s", args, semver))
-	out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
 	if err != nil {
 		return false
 	}
 
-	return bytes.Contains(out, []byte(semver))
+	return b.matchesversion(out)
 }
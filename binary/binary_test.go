@@ -1,16 +1,24 @@
 package binary
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/internal"
 )
 
 // silent output when not verbose
@@ -51,6 +59,81 @@ func TestNew(t *testing.T) {
 		},
 	)
 
+	t.Run("uses defaults from the config file",
+		func(t *testing.T) {
+			internal.SetConfig(&internal.Config{
+				BinDir:   "tools/bin",
+				Versions: map[string]string{"util": "2.0.0"},
+			})
+			t.Cleanup(func() { internal.SetConfig(&internal.Config{}) })
+
+			var origin *fakeorigin
+			b := New("util", "1.0.0", origin)
+
+			assert.Equal(t, filepath.FromSlash("tools/bin"), b.directory)
+			assert.Equal(t, filepath.Join(filepath.FromSlash("tools/bin"), "util")+wantExt, b.BinPath())
+			assert.Equal(t, "2.0.0", b.version)
+		},
+	)
+
+	t.Run("writes configured versions like the version in code",
+		func(t *testing.T) {
+			t.Cleanup(func() { internal.SetConfig(&internal.Config{}) })
+
+			for _, tc := range []struct {
+				version    string
+				configured string
+				origin     Origin
+				want       string
+			}{
+				{version: "1.0.0", configured: "v2.0.0", origin: new(fakeorigin), want: "2.0.0"},
+				{version: "v1.0.0", configured: "2.0.0", origin: new(fakeorigin), want: "v2.0.0"},
+				{version: "latest", configured: "v2.0.0", origin: RemoteBinaryDownload("https://example.com/v{{.Version}}/util"), want: "2.0.0"},
+				{version: "latest", configured: "2.0.0", origin: GoBinary("example.com/util"), want: "v2.0.0"},
+				{version: "v1.0.0", configured: "latest", origin: GoBinary("example.com/util"), want: "latest"},
+			} {
+				internal.SetConfig(&internal.Config{Versions: map[string]string{"util": tc.configured}})
+
+				b := New("util", tc.version, tc.origin)
+				assert.Equal(t, tc.want, b.Version(), "%s configured as %s", tc.version, tc.configured)
+			}
+		},
+	)
+
+	t.Run("env variable overrides the config file",
+		func(t *testing.T) {
+			internal.SetConfig(&internal.Config{BinDir: "tools/bin"})
+			t.Cleanup(func() { internal.SetConfig(&internal.Config{}) })
+			t.Setenv(BinDirEnv, "~/.harness/bin")
+
+			home, err := os.UserHomeDir()
+			require.NoError(t, err)
+
+			var origin *fakeorigin
+			b := New("util", "1.0.0", origin)
+
+			assert.Equal(t, filepath.Join(home, ".harness", "bin"), b.directory)
+			assert.Equal(t, filepath.Join(home, ".harness", "bin", "util")+wantExt, b.BinPath())
+		},
+	)
+
+	t.Run("with directory",
+		func(t *testing.T) {
+			dir := t.TempDir()
+
+			var origin *fakeorigin
+			b := New("util", "1.0.0", origin, WithVersionCmd("%s version"), WithDirectory(dir))
+
+			assert.Equal(t, dir, b.directory)
+			assert.Equal(t, dir, b.template.Directory)
+			assert.Equal(t, filepath.Join(dir, "util")+wantExt, b.BinPath())
+			assert.Equal(t, filepath.Join(dir, "util")+wantExt+" version", b.versioncmd)
+
+			asset := NewAsset("include", "1.0.0", origin, WithDirectory(dir))
+			assert.Equal(t, filepath.Join(dir, "include"), asset.BinPath())
+		},
+	)
+
 	t.Run("with all mapping options",
 		func(t *testing.T) {
 			var origin *fakeorigin
@@ -66,6 +149,21 @@ func TestNew(t *testing.T) {
 		},
 	)
 
+	t.Run("with platform variant mappings",
+		func(t *testing.T) {
+			var origin *fakeorigin
+			b := New("util", "1.0.0", origin)
+			b.template.GOARM = "6"
+			b.template.Libc = "gnu"
+
+			WithGOARMMapping(map[string]string{"6": "hf"})(b)
+			WithLibcMapping(map[string]string{"gnu": "musl"})(b)
+
+			assert.Equal(t, "hf", b.template.GOARM)
+			assert.Equal(t, "musl", b.template.Libc)
+		},
+	)
+
 	t.Run("GOOS mapping without match keeps default",
 		func(t *testing.T) {
 			var origin *fakeorigin
@@ -131,7 +229,7 @@ func TestEnsure(t *testing.T) {
 
 			bin := New("util", "", origin)
 
-			err := bin.Ensure()
+			err := bin.Ensure(t.Context())
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "version must be set")
 		},
@@ -145,7 +243,7 @@ func TestEnsure(t *testing.T) {
 			bin := New("util", "1.0.0", origin,
 				WithVersionCmd(SkipVersionCheck),
 			)
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.True(t, origin.installed, "install should have been called")
 		},
 	)
@@ -159,7 +257,7 @@ func TestEnsure(t *testing.T) {
 				WithVersionCmd(SkipVersionCheck),
 			)
 
-			err := bin.Ensure()
+			err := bin.Ensure(t.Context())
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "download failed")
 		},
@@ -179,7 +277,7 @@ func TestEnsure(t *testing.T) {
 			require.NoError(t, os.MkdirAll(dir, 0o755))
 			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("existing"), 0o755))
 
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.False(t, origin.installed, "install shouldn't have been called for an existing binary without version check")
 		},
 	)
@@ -198,7 +296,7 @@ func TestEnsure(t *testing.T) {
 			require.NoError(t, os.MkdirAll(dir, 0o755))
 			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("existing"), 0o755))
 
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.False(t, origin.installed, "install shouldn't have been called for an existing binary with 'latest' as version")
 		},
 	)
@@ -215,7 +313,7 @@ func TestEnsure(t *testing.T) {
 			require.NoError(t, os.MkdirAll(dir, 0o755))
 			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("#!/bin/sh\necho 'util version 2.5.0'"), 0o755))
 
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.False(t, origin.installed, "install shouldn't have been called since version matches")
 		},
 	)
@@ -232,7 +330,7 @@ func TestEnsure(t *testing.T) {
 			require.NoError(t, os.MkdirAll(dir, 0o755))
 			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("#!/bin/sh\necho 'util version 2.3.0'"), 0o755))
 
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.True(t, origin.installed, "install should have been called since the installed bin is older")
 		},
 	)
@@ -249,12 +347,155 @@ func TestEnsure(t *testing.T) {
 			require.NoError(t, os.MkdirAll(dir, 0o755))
 			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("#!/bin/sh\necho 'util version 2.5.0'"), 0o755))
 
-			require.NoError(t, bin.Ensure())
+			require.NoError(t, bin.Ensure(t.Context()))
 			assert.False(t, origin.installed, "install shouldn't have been called since the 'v' prefix is stripped")
 		},
 	)
 }
 
+func TestInstallReceipt(t *testing.T) {
+	t.Run("is recorded after install",
+		func(t *testing.T) {
+			origin := new(fakeorigin)
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			assert.FileExists(t, bin.receiptPath())
+			assert.True(t, bin.hasValidReceipt())
+		},
+	)
+
+	t.Run("skips version check when valid",
+		func(t *testing.T) {
+			origin := new(fakeorigin)
+			withTempDir(t)
+
+			bin := New("util", "2.5.0", origin)
+
+			dir := filepath.FromSlash("./bin")
+			require.NoError(t, os.MkdirAll(dir, 0o755))
+			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("#!/bin/sh\necho 'util version 2.5.0'"), 0o755))
+			require.NoError(t, bin.Ensure(t.Context()))
+			require.True(t, bin.hasValidReceipt())
+
+			// a version command that always fails proves the receipt is trusted
+			trusting := New("util", "2.5.0", origin, WithVersionCmd("false %s"))
+			require.NoError(t, trusting.Ensure(t.Context()))
+			assert.False(t, origin.installed, "install shouldn't have been called with a valid receipt")
+
+			forced := New("util", "2.5.0", origin, WithVersionCmd("false %s"), WithForceVerify(true))
+			require.NoError(t, forced.Ensure(t.Context()))
+			assert.True(t, origin.installed, "install should have been called when forcing verification")
+		},
+	)
+
+	t.Run("is invalidated by version changes",
+		func(t *testing.T) {
+			origin := new(fakeorigin)
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck)).Ensure(t.Context()))
+
+			bin := New("util", "2.0.0", origin)
+			assert.False(t, bin.hasValidReceipt())
+		},
+	)
+
+	t.Run("is invalidated by origin changes",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			fakeinstall(t, New("util", "1.0.0", RemoteBinaryDownload("http://127.0.0.1:1/uno")))
+
+			bin := New("util", "1.0.0", RemoteBinaryDownload("http://127.0.0.1:1/dos"))
+			assert.False(t, bin.hasValidReceipt())
+		},
+	)
+
+	t.Run("records checksum and install time",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", new(fakeorigin), WithVersionCmd(SkipVersionCheck))
+			before := time.Now()
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			rcpt, err := bin.readReceipt()
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(bin.BinPath())
+			require.NoError(t, err)
+			sum := sha256.Sum256(content)
+			assert.Equal(t, hex.EncodeToString(sum[:]), rcpt.Checksum)
+			assert.False(t, rcpt.Installed.Before(before.Truncate(time.Second)))
+
+			// verifying the same binary again keeps the install time
+			later := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(bin.BinPath(), later, later))
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin)).writeReceipt())
+
+			reverified, err := bin.readReceipt()
+			require.NoError(t, err)
+			assert.True(t, rcpt.Installed.Equal(reverified.Installed))
+			assert.True(t, reverified.ModTime.Equal(later))
+		},
+	)
+
+	t.Run("is invalidated when the binary is modified",
+		func(t *testing.T) {
+			origin := new(fakeorigin)
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			later := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(bin.BinPath(), later, later))
+
+			assert.False(t, bin.hasValidReceipt())
+		},
+	)
+}
+
+func TestOriginDigest(t *testing.T) {
+	remote := func(url, checksum string) Origin {
+		return RemoteBinaryDownload(url,
+			WithChecksum(checksum),
+			WithHTTPClient(&http.Client{Timeout: time.Minute}),
+			WithBearerTokenFromEnv("TOKEN"),
+			WithCosignVerification("identity", "issuer"),
+		)
+	}
+
+	t.Run("is the same for identically built origins",
+		func(t *testing.T) {
+			assert.Equal(t,
+				origindigest(remote("https://example.com/util", "sha256:"+strings.Repeat("a", 64))),
+				origindigest(remote("https://example.com/util", "sha256:"+strings.Repeat("a", 64))),
+			)
+			assert.Equal(t,
+				origindigest(HashicorpRelease("terraform")),
+				origindigest(HashicorpRelease("terraform")),
+			)
+			assert.Equal(t,
+				origindigest(FromScript(func(_ context.Context, _ Template) error { return nil })),
+				origindigest(FromScript(func(_ context.Context, _ Template) error { return nil })),
+			)
+		},
+	)
+
+	t.Run("changes with the configuration",
+		func(t *testing.T) {
+			digest := origindigest(remote("https://example.com/util", "sha256:"+strings.Repeat("a", 64)))
+			assert.NotEqual(t, digest, origindigest(remote("https://example.com/other", "sha256:"+strings.Repeat("a", 64))))
+			assert.NotEqual(t, digest, origindigest(remote("https://example.com/util", "sha256:"+strings.Repeat("b", 64))))
+			assert.NotEqual(t, digest, origindigest(RemoteArchiveDownload("https://example.com/util", nil)))
+		},
+	)
+}
+
 func TestRemoteBinaryDownload(t *testing.T) {
 	srv := setupTestServer(t)
 	withTempDir(t)
@@ -262,13 +503,13 @@ func TestRemoteBinaryDownload(t *testing.T) {
 	origin := RemoteBinaryDownload(srv.URL + "/util")
 	bin := New("util", "1.2.3", origin)
 
-	require.NoError(t, bin.Ensure())
+	require.NoError(t, bin.Ensure(t.Context()))
 	assert.FileExists(t, bin.BinPath())
 
 	// calling Ensure again should be a no-op (binary exists and version matches)
 	origin2 := &fakeorigin{}
 	b2 := New("util", "1.2.3", origin2)
-	require.NoError(t, b2.Ensure())
+	require.NoError(t, b2.Ensure(t.Context()))
 	assert.False(t, origin2.installed, "second Ensure() should not have triggered install")
 }
 
@@ -282,18 +523,74 @@ func TestRemoteArchiveDownload(t *testing.T) {
 	)
 	bin := New("util", "1.2.3", origin)
 
-	require.NoError(t, bin.Ensure())
+	require.NoError(t, bin.Ensure(t.Context()))
 	assert.FileExists(t, bin.BinPath())
 }
 
 // fakeorigin is a mock Origin that records whether Install was called
 // and optionally returns an error.
+func TestInstallHooks(t *testing.T) {
+	t.Run("run around the install",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			var calls []string
+			bin := New("util", "1.0.0", new(fakeorigin),
+				WithPreInstall(func(tmpl Template) error {
+					assert.NoFileExists(t, tmpl.Cmd)
+					calls = append(calls, "pre")
+					return nil
+				}),
+				WithPostInstall(func(tmpl Template) error {
+					assert.FileExists(t, tmpl.Cmd)
+					calls = append(calls, "post")
+					return nil
+				}),
+				WithPostInstall(func(Template) error {
+					calls = append(calls, "post2")
+					return nil
+				}),
+			)
+
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.Equal(t, []string{"pre", "post", "post2"}, calls)
+
+			// hooks don't run for binaries already installed
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.Len(t, calls, 3)
+		},
+	)
+
+	t.Run("failing pre-install hook aborts the install",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			origin := new(fakeorigin)
+			err := New("util", "1.0.0", origin, WithPreInstall(func(Template) error { return fmt.Errorf("boom") })).Ensure(t.Context())
+			require.ErrorContains(t, err, "pre-install hook failed: boom")
+			assert.False(t, origin.installed)
+		},
+	)
+
+	t.Run("failing post-install hook removes the binary",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", new(fakeorigin), WithPostInstall(func(Template) error { return fmt.Errorf("boom") }))
+			err := bin.Ensure(t.Context())
+			require.ErrorContains(t, err, "post-install hook failed: boom")
+			assert.NoFileExists(t, bin.BinPath())
+			assert.NoFileExists(t, bin.receiptPath())
+		},
+	)
+}
+
 type fakeorigin struct {
 	installed bool
 	err       error
 }
 
-func (f *fakeorigin) Install(tmpl Template) error {
+func (f *fakeorigin) Install(_ context.Context, tmpl Template) error {
 	f.installed = true
 	if f.err != nil {
 		return f.err
@@ -305,6 +602,15 @@ func (f *fakeorigin) Install(tmpl Template) error {
 	return os.WriteFile(tmpl.Cmd, []byte("fake"), 0o755)
 }
 
+// fakeinstall creates a fake binary on disk and records its receipt.
+func fakeinstall(t *testing.T, bin *Binary) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(bin.template.Directory, 0o755))
+	require.NoError(t, os.WriteFile(bin.BinPath(), []byte("fake"), 0o755))
+	require.NoError(t, bin.writeReceipt())
+}
+
 // withTempDir changes the working directory to a temp dir for the test
 // and restores it afterward. Returns the temp dir path.
 func withTempDir(t *testing.T) string {
@@ -0,0 +1,207 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// CacheDirEnv is the env variable that enables the shared cache for every binary,
+// pointing at its directory, e.g. HARNESS_CACHE_DIR=~/.cache/harness.
+const CacheDirEnv = "HARNESS_CACHE_DIR"
+
+// cachemarker is written to cache entries once they're fully populated.
+const cachemarker = ".complete"
+
+// WithSharedCache provisions the binary into a cache shared across repositories, keyed by
+// name, version and platform, and links it into the bin directory from there, falling back
+// to copying it if links aren't supported. Once a version of a binary is cached, every other
+// repository using the same cache provisions it without downloading it again.
+//
+// If dir is empty, the harness directory inside the user cache directory is used,
+// e.g. ~/.cache/harness on linux. The cache can also be enabled for every binary by setting
+// the HARNESS_CACHE_DIR env variable.
+//
+// Binaries with version "latest", unless it's resolved to a concrete version, see [LatestResolver],
+// and the ones from [System] or [LocalPath] are never cached.
+func WithSharedCache(dir string) Option {
+	return func(b *Binary) {
+		b.cache = true
+		b.cachedir = dir
+	}
+}
+
+// cacheable returns true if the binary is provisioned through the shared cache.
+func (b *Binary) cacheable() bool {
+	if !b.cache || b.system || b.version == "latest" {
+		return false
+	}
+
+	_, local := b.origin.(*localpath)
+	return !local
+}
+
+// cacheentry returns the directory of the shared cache where the binary is provisioned into.
+func (b *Binary) cacheentry() (string, error) {
+	root := b.cachedir
+	if root == "" {
+		usercache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve cache dir: %w", err)
+		}
+		root = filepath.Join(usercache, "harness")
+	}
+
+	return filepath.Abs(
+		filepath.Join(
+			root,
+			b.template.Name,
+			b.version,
+			cacheplatform(b.template),
+		),
+	)
+}
+
+// cacheplatform returns the platform part of cache entries, like linux_amd64, including the
+// arm version and musl when set, so hosts sharing a cache don't use each other's binaries.
+func cacheplatform(template Template) string {
+	key := fmt.Sprintf("%s_%s", template.GOOS, template.GOARCH)
+	if template.GOARM != "" {
+		key += "v" + template.GOARM
+	}
+	if template.Libc == "musl" {
+		key += "_musl"
+	}
+	return key
+}
+
+// installcached installs the binary into the shared cache, unless it's already there,
+// and links its files into the bin directory.
+func (b *Binary) installcached(ctx context.Context) (err error) {
+	entry, err := b.cacheentry()
+	if err != nil {
+		return err
+	}
+
+	// other repositories may be populating the same entry
+	unlock, err := lock(ctx, entry+".lock")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockerr := unlock(); unlockerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release lock %s.lock: %w", entry, unlockerr))
+		}
+	}()
+
+	if _, err := os.Stat(filepath.Join(entry, cachemarker)); err == nil && !forced(ctx) {
+		internal.LogStep(fmt.Sprintf("using cached %s", entry))
+	} else if err := b.populatecache(ctx, entry); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(b.template.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", b.template.Directory, err)
+	}
+
+	return linkcached(entry, b.template.Directory)
+}
+
+// populatecache installs the binary from its origin into the cache entry.
+// The files are installed in place, as some origins like [NpmPackage] create
+// links that can't be moved, and the entry is only marked as complete at the end.
+func (b *Binary) populatecache(ctx context.Context, entry string) (err error) {
+	internal.LogDetail(fmt.Sprintf("caching %s in %s", b.template.Name, entry))
+
+	// start from scratch, as a previous attempt may have left files behind
+	if err := os.RemoveAll(entry); err != nil {
+		return fmt.Errorf("failed to remove incomplete cache entry %s: %w", entry, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(entry)
+		}
+	}()
+
+	tmpl := b.template
+	tmpl.Directory = entry
+	tmpl.Cmd = filepath.Join(entry, filepath.Base(b.template.Cmd))
+
+	if err := b.origin.Install(ctx, tmpl); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(entry, cachemarker), nil, 0o644); err != nil {
+		return fmt.Errorf("failed to mark cache entry %s as complete: %w", entry, err)
+	}
+	return nil
+}
+
+// linkcached links every file in the root of the cache entry into the destination,
+// replacing previous installs. Hidden files, like the virtualenvs or node_modules of
+// some origins, are only referenced from the cache and not linked.
+func linkcached(entry, destination string) error {
+	files, err := os.ReadDir(entry)
+	if err != nil {
+		return fmt.Errorf("failed to read cache entry %s: %w", entry, err)
+	}
+
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		source := filepath.Join(entry, file.Name())
+		target := filepath.Join(destination, file.Name())
+
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to remove previous install %s: %w", target, err)
+		}
+
+		if err := os.Symlink(source, target); err == nil {
+			continue
+		}
+
+		internal.LogDetail(fmt.Sprintf("failed to link %s, copying it instead", source))
+		if err := copytree(source, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copytree copies the file or directory at source to destination, keeping permissions.
+func copytree(source, destination string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve cached file %s: %w", path, err)
+		}
+		target := filepath.Join(destination, rel)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+
+		if info.IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			}
+			return nil
+		}
+
+		return copyfile(path, target, info.Mode().Perm())
+	})
+}
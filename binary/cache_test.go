@@ -0,0 +1,98 @@
+package binary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedCache(t *testing.T) {
+	// counts the downloads to check the cache is used across repositories
+	var downloads int
+	sub := setupTestServer(t)
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			downloads++
+			http.Redirect(w, r, sub.URL+r.URL.Path, http.StatusFound)
+		}),
+	)
+	t.Cleanup(srv.Close)
+
+	t.Run("provisions repositories from the cache",
+		func(t *testing.T) {
+			downloads = 0
+			cache := t.TempDir()
+			origin := RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"})
+
+			for range 2 {
+				withTempDir(t)
+
+				bin := New("util", "1.2.3", origin, WithVersionCmd(SkipVersionCheck), WithSharedCache(cache))
+				require.NoError(t, bin.Ensure(t.Context()))
+
+				content, err := os.ReadFile(bin.BinPath())
+				require.NoError(t, err)
+				assert.NotEmpty(t, content)
+			}
+
+			assert.Equal(t, 1, downloads)
+			assert.FileExists(t, filepath.Join(cache, "util", "1.2.3", platformdir(), "util"))
+		},
+	)
+
+	t.Run("is enabled via env",
+		func(t *testing.T) {
+			cache := t.TempDir()
+			t.Setenv(CacheDirEnv, cache)
+			withTempDir(t)
+
+			bin := New("util", "1.2.3", new(fakeorigin), WithVersionCmd(SkipVersionCheck))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			link, err := os.Readlink(bin.BinPath())
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(cache, "util", "1.2.3", platformdir(), filepath.Base(bin.BinPath())), link)
+		},
+	)
+
+	t.Run("skips latest versions",
+		func(t *testing.T) {
+			cache := t.TempDir()
+			withTempDir(t)
+
+			bin := New("util", "latest", new(fakeorigin), WithSharedCache(cache))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			entries, err := os.ReadDir(cache)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		},
+	)
+
+	t.Run("discards incomplete entries",
+		func(t *testing.T) {
+			cache := t.TempDir()
+			withTempDir(t)
+
+			failing := &fakeorigin{err: assert.AnError}
+			bin := New("util", "1.0.0", failing, WithVersionCmd(SkipVersionCheck), WithSharedCache(cache))
+			require.Error(t, bin.Ensure(t.Context()))
+			assert.NoDirExists(t, filepath.Join(cache, "util", "1.0.0", platformdir()))
+
+			origin := new(fakeorigin)
+			bin = New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck), WithSharedCache(cache))
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.True(t, origin.installed, "install should have been called")
+		},
+	)
+}
+
+func platformdir() string {
+	return runtime.GOOS + "_" + runtime.GOARCH
+}
@@ -0,0 +1,63 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// cargocrate implements [Origin] for binaries installed with cargo.
+type cargocrate struct {
+	crate string
+}
+
+// CargoInstall creates a new Origin that installs a binary using 'cargo install'
+// targetting the local bin directory.
+// The crate parameter should be a crate installable using the cargo cli.
+// e.g. cargo-deny
+func CargoInstall(crate string) Origin {
+	return &cargocrate{
+		crate: crate,
+	}
+}
+
+func (o *cargocrate) digest() any {
+	return struct{ Crate string }{o.crate}
+}
+
+func (o *cargocrate) Install(ctx context.Context, template Template) error {
+	// cargo installs into the bin directory of the root, along with its metadata,
+	// so use a root of its own and move the binary from there
+	root, err := filepath.Abs(filepath.Join(template.Directory, ".cargo", template.Name))
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir %s: %w", template.Directory, err)
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", root, err)
+	}
+
+	args := []string{"install", "--root", root, "--locked", "--force", o.crate}
+	if template.Version != "latest" {
+		args = append(args, "--version", template.Version)
+	}
+
+	internal.LogDetail(fmt.Sprintf("running cargo install %s@%s", o.crate, template.Version))
+	if err := runcommand(ctx, "cargo", args...); err != nil {
+		return fmt.Errorf("unable to install executable: %w", err)
+	}
+
+	// rename if binary name is different from template
+	installed := filepath.Join(root, "bin", template.Name+template.Extension)
+	if _, err := os.Stat(installed); err != nil {
+		installed = filepath.Join(root, "bin", o.crate+template.Extension)
+	}
+
+	if err := os.Rename(installed, template.Cmd); err != nil {
+		return fmt.Errorf("failed to move binary %s: %w", installed, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCargoInstallOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake cargo is a shell script")
+	}
+
+	// fake cargo records its arguments and installs a binary named after the crate
+	pathdir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/cargo.args"
+mkdir -p "$3/bin"
+printf '#!/bin/sh\necho "$6 0.16.1"\n' > "$3/bin/$6"
+chmod +x "$3/bin/$6"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(pathdir, "cargo"), []byte(script), 0o755))
+	t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Run("installs crate into bin directory",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("cargo-deny", "0.16.1", CargoInstall("cargo-deny"))
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.FileExists(t, bin.BinPath())
+
+			args, err := os.ReadFile(filepath.Join(pathdir, "cargo.args"))
+			require.NoError(t, err)
+			root, err := filepath.Abs(filepath.Join("bin", ".cargo", "cargo-deny"))
+			require.NoError(t, err)
+			assert.Equal(t, "install --root "+root+" --locked --force cargo-deny --version 0.16.1\n", string(args))
+		},
+	)
+
+	t.Run("renames binary when crate name differs from template name",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			tmpl := mktemplate("bin", "deny", "0.16.1")
+			require.NoError(t, CargoInstall("cargo-deny").Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join("bin", "deny"))
+		},
+	)
+}
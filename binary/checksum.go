@@ -1,13 +1,18 @@
 package binary
 
 import (
+	"bufio"
+	"context"
 	"crypto"
 	_ "crypto/sha256" // register sha224, sha256
 	_ "crypto/sha512" // register sha384, sha512
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path"
 	"strings"
 )
 
@@ -24,6 +29,97 @@ type Checksum struct {
 	Value     string
 }
 
+// checksumalgorithms maps the algorithm prefixes accepted by [ParseChecksum] to their hash.
+var checksumalgorithms = map[string]crypto.Hash{
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// ParseChecksum parses a checksum in the "algorithm:value" format, e.g. "sha256:abc...".
+// Supported algorithms are sha224, sha256, sha384 and sha512.
+func ParseChecksum(checksum string) (Checksum, error) {
+	name, value, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return Checksum{}, fmt.Errorf("invalid checksum %q: expected algorithm:value", checksum)
+	}
+
+	algorithm, ok := checksumalgorithms[strings.ToLower(name)]
+	if !ok {
+		return Checksum{}, fmt.Errorf("invalid checksum %q: unsupported algorithm %s", checksum, name)
+	}
+
+	if _, err := hex.DecodeString(value); err != nil || len(value) != algorithm.Size()*2 {
+		return Checksum{}, fmt.Errorf("invalid checksum %q: expected %d hex characters", checksum, algorithm.Size()*2)
+	}
+
+	return Checksum{Algorithm: algorithm, Value: value}, nil
+}
+
+// fetchchecksum downloads the checksums file at sumsurl and returns the checksum
+// listed for the artifact.
+func (c origincfg) fetchchecksum(ctx context.Context, sumsurl, artifact string) (sum Checksum, err error) {
+	resp, err := c.get(ctx, sumsurl)
+	if err != nil {
+		return Checksum{}, fmt.Errorf("failed to download checksums file: %w", err)
+	}
+	defer func() {
+		if closerr := resp.Body.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close http response body: %w", closerr))
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Checksum{}, responseerror("checksums file", sumsurl, resp)
+	}
+
+	sum, err = findchecksum(resp.Body, artifact)
+	if err != nil {
+		return Checksum{}, fmt.Errorf("invalid checksums file %s: %w", sumsurl, err)
+	}
+	return sum, nil
+}
+
+// findchecksum returns the checksum of the artifact listed in a checksums file,
+// in the "<hash>  <name>" format written by sha256sum and similar tools.
+// Names prefixed with "*", as written in binary mode, and "./" are matched too.
+func findchecksum(reader io.Reader, artifact string) (Checksum, error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		if name != artifact {
+			continue
+		}
+
+		// every supported algorithm has a different hash size
+		for name, algorithm := range checksumalgorithms {
+			if len(fields[0]) == algorithm.Size()*2 {
+				return ParseChecksum(name + ":" + fields[0])
+			}
+		}
+		return Checksum{}, fmt.Errorf("unknown hash algorithm for %s", artifact)
+	}
+	if err := scanner.Err(); err != nil {
+		return Checksum{}, err
+	}
+
+	return Checksum{}, fmt.Errorf("no checksum found for %s", artifact)
+}
+
+// artifactname returns the name of the file downloaded from rawurl.
+func artifactname(rawurl string) string {
+	if parsed, err := url.Parse(rawurl); err == nil {
+		return path.Base(parsed.Path)
+	}
+	return path.Base(rawurl)
+}
+
 // crcreader wraps r so bytes are fed into a hasher as they're read.
 // The returned check function validates the accumulated hash against sum.
 func crcreader(reader io.Reader, sum Checksum) (io.Reader, func() error, error) {
@@ -0,0 +1,142 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// CosignOpt configures the verification enabled via [WithCosignVerification].
+type CosignOpt func(c *cosignconf)
+
+type cosignconf struct {
+	identity string
+	issuer   string
+
+	bin       string
+	bundle    string
+	signature string
+	cert      string
+}
+
+// WithCosignVerification verifies the downloaded file against its keyless Sigstore signature
+// before installing it, requiring the signing certificate to be issued to identity by the
+// OIDC issuer, e.g. for binaries released from GitHub Actions:
+//
+//	binary.WithCosignVerification(
+//		"https://github.com/foo/bar/.github/workflows/release.yml@refs/tags/v1.2.3",
+//		"https://token.actions.githubusercontent.com",
+//	)
+//
+// Verification is done by running `cosign verify-blob`, using the cosign binary found in
+// PATH unless another one is specified via [WithCosignBinary], e.g. one provisioned with
+// this package.
+// By default, the signature is expected in a Sigstore bundle published next to the file,
+// under the same URL with the .sigstore.json extension; use [WithCosignBundle] or
+// [WithCosignSignature] to point to other locations.
+func WithCosignVerification(identity, issuer string, options ...CosignOpt) OriginOption {
+	conf := cosignconf{
+		identity: identity,
+		issuer:   issuer,
+		bin:      "cosign",
+	}
+
+	for _, opt := range options {
+		opt(&conf)
+	}
+
+	return func(c *origincfg) {
+		c.verifiers = append(c.verifiers, conf.verify)
+		c.verifications = append(c.verifications, fmt.Sprintf("cosign %+v", conf))
+	}
+}
+
+// WithCosignBinary sets the cosign binary used to verify signatures.
+func WithCosignBinary(path string) CosignOpt {
+	return func(c *cosignconf) {
+		c.bin = path
+	}
+}
+
+// WithCosignBundle sets the URL of the Sigstore bundle containing the signature.
+// The URL can contain template variables that will be resolved using the [Template] values.
+func WithCosignBundle(url string) CosignOpt {
+	return func(c *cosignconf) {
+		c.bundle = url
+		c.signature = ""
+		c.cert = ""
+	}
+}
+
+// WithCosignSignature sets the URLs of the detached signature and the signing certificate,
+// for releases that don't publish Sigstore bundles.
+// The URLs can contain template variables that will be resolved using the [Template] values.
+func WithCosignSignature(signature, cert string) CosignOpt {
+	return func(c *cosignconf) {
+		c.bundle = ""
+		c.signature = signature
+		c.cert = cert
+	}
+}
+
+// verify checks the signature of the file downloaded from url.
+func (c cosignconf) verify(ctx context.Context, config origincfg, template Template, url, file string) error {
+	internal.LogDetail(fmt.Sprintf("verifying cosign signature of %s", url))
+
+	args := []string{
+		"verify-blob",
+		"--certificate-identity", c.identity,
+		"--certificate-oidc-issuer", c.issuer,
+	}
+
+	if c.signature != "" {
+		signature, err := c.fetch(ctx, config, template, c.signature)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(signature)
+
+		cert, err := c.fetch(ctx, config, template, c.cert)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(cert)
+
+		args = append(args, "--signature", signature, "--certificate", cert)
+	} else {
+		bundleurl := url + ".sigstore.json"
+		if c.bundle != "" {
+			bundleurl = c.bundle
+		}
+
+		bundle, err := c.fetch(ctx, config, template, bundleurl)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(bundle)
+
+		args = append(args, "--bundle", bundle)
+	}
+
+	if err := runcommand(ctx, c.bin, append(args, file)...); err != nil {
+		return fmt.Errorf("cosign verification failed: %w", err)
+	}
+
+	return nil
+}
+
+// fetch downloads a signature file, resolving its url template.
+func (c cosignconf) fetch(ctx context.Context, config origincfg, template Template, urlformat string) (string, error) {
+	url, err := template.Resolve(urlformat)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve signature URL: %w", err)
+	}
+
+	path, err := config.fetchtemp(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download signature: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,132 @@
+package binary
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCosignVerification(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake cosign binary is a shell script")
+	}
+
+	// fakecosign writes a cosign replacement that records its arguments and
+	// exits with the given status
+	fakecosign := func(t *testing.T, status string) (bin, argsfile string) {
+		t.Helper()
+		dir := t.TempDir()
+		bin = filepath.Join(dir, "cosign")
+		argsfile = filepath.Join(dir, "args")
+		script := "#!/bin/sh\necho \"$@\" > " + argsfile + "\necho 'signature mismatch' >&2\nexit " + status + "\n"
+		require.NoError(t, os.WriteFile(bin, []byte(script), 0o755))
+		return bin, argsfile
+	}
+
+	serve := func(t *testing.T) *httptest.Server {
+		t.Helper()
+		sub, err := fs.Sub(testdata, "testdata")
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		mux.Handle("/", http.FileServer(http.FS(sub)))
+		for _, name := range []string{"/util.tar.gz.sigstore.json", "/custom.bundle", "/util.sig", "/util.pem"} {
+			mux.HandleFunc(name, func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, name)
+			})
+		}
+
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	t.Run("verifies archive with bundle published next to it",
+		func(t *testing.T) {
+			srv := serve(t)
+			bin, argsfile := fakecosign(t, "0")
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithCosignVerification("https://example.com/release.yml", "https://issuer.example.com", WithCosignBinary(bin)),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+
+			args, err := os.ReadFile(argsfile)
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "verify-blob --certificate-identity https://example.com/release.yml --certificate-oidc-issuer https://issuer.example.com --bundle ")
+			assert.True(t, strings.HasSuffix(strings.TrimSpace(string(args)), filepath.Join(dir, "util.tar.gz")))
+		},
+	)
+
+	t.Run("uses detached signature and certificate",
+		func(t *testing.T) {
+			srv := serve(t)
+			bin, argsfile := fakecosign(t, "0")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(
+				srv.URL+"/util",
+				WithCosignVerification(
+					"id", "issuer",
+					WithCosignBinary(bin),
+					WithCosignSignature(srv.URL+"/{{.Name}}.sig", srv.URL+"/{{.Name}}.pem"),
+				),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+
+			args, err := os.ReadFile(argsfile)
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "--signature ")
+			assert.Contains(t, string(args), "--certificate ")
+			assert.NotContains(t, string(args), "--bundle")
+		},
+	)
+
+	t.Run("fails and removes binary when signature is invalid",
+		func(t *testing.T) {
+			srv := serve(t)
+			bin, _ := fakecosign(t, "1")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(
+				srv.URL+"/util",
+				WithCosignVerification("id", "issuer", WithCosignBinary(bin), WithCosignBundle(srv.URL+"/custom.bundle")),
+			)
+
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "cosign verification failed")
+			assert.Contains(t, err.Error(), "signature mismatch")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails when signature can't be downloaded",
+		func(t *testing.T) {
+			srv := serve(t)
+			bin, _ := fakecosign(t, "0")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(srv.URL+"/util", WithCosignVerification("id", "issuer", WithCosignBinary(bin)))
+
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http404")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+}
@@ -6,12 +6,40 @@
 // binary from.
 //
 // Origins implement the logic needed to provision the binary and ensure
-// the version matches expectations. Currently there are only three origins
-// implemented:
+// the version matches expectations. The following origins are implemented:
 // - [GoBinary]: provisions binaries by running `go install`
+// - [GoTool]: provisions tools declared in the go.mod file of the project, at the version required there
+// - [CargoInstall]: provisions binaries by running `cargo install`
+// - [NpmPackage]: provisions executables of node packages by running `npm install`
+// - [PythonPackage]: provisions executables of python packages into isolated virtualenvs
 // - [RemoteBinaryDownload]: for binaries that can be downloaded directly from a url
 // - [RemoteArchiveDownload]: for binaries contained in archives that can be downloaded from a url
-// If any other source is needed, a new origin can be implemented by just fulfilling the [Origin] interface.
+// - [GitLabRelease]: for binaries attached to releases of GitLab projects
+// - [GitLabPackage]: for binaries published in the generic package registry of GitLab projects
+// - [HashicorpRelease]: for products released on releases.hashicorp.com, like terraform
+// - [ObjectStorage]: for binaries stored in S3 or GCS buckets
+// - [LocalPath]: for binaries already present on the filesystem, like vendored tools
+// - [System]: for binaries expected to be installed on the system, which are only verified
+// If any other source is needed, a new origin can be implemented by just fulfilling the [Origin] interface,
+// or for one-off tools, with [FromScript] or [InstallScriptURL].
+//
+// Non-executable files a toolchain needs, like include directories or schemas, can be provisioned
+// from the same origins by using [NewAsset] instead of [New].
+//
+// Binaries can also be declared in a yaml manifest and loaded with [FromManifest], keeping
+// versions out of Go code.
+//
+// Binaries with version "latest" are reinstalled when upstream moves for origins that can resolve
+// it to a concrete version, like [GoBinary] or downloads using [WithLatestGitHubRelease].
+//
+// Binaries are provisioned into ./bin by default, which can be changed in the harness.yaml config
+// file, with the HARNESS_BIN_DIR env variable or per binary with [WithDirectory].
+//
+// Downloads can be shared across repositories with [WithSharedCache] or the HARNESS_CACHE_DIR
+// env variable, which provision binaries into a cache and link them into the bin directory.
+//
+// Setting the HARNESS_OFFLINE env variable makes provisioning fail fast for binaries that would
+// have to be downloaded, while binaries already present keep working, see [OfflineEnv].
 //
 // Each origin defines its own inputs that are required in order to work.
 // Additionally, the template passed as argument to the Install function will contain all the
@@ -35,7 +63,7 @@
 //
 //	// ensure the binary is present
 //	// this will download or update the binary if necessary
-//	if err := commitsar.Ensure(); err != nil {
+//	if err := commitsar.Ensure(ctx); err != nil {
 //		return fmt.Errorf("failed to provision commitsar binary: %w", err)
 //	}
 //
@@ -45,6 +73,9 @@
 //	// use via harness
 //	harness.Run(ctx, commitsar.BinPath(), harness.WithArgs("--help"))
 //
+//	// or let the binary ensure itself before running
+//	commitsar.Run(ctx, harness.WithArgs("--help"))
+//
 //	// or via os/exec
 //	exec.Command(commitsar.BinPath(), "--help").Run()
 package binary
@@ -0,0 +1,206 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Diagnosis is the state of a binary as found by [Binary.Diagnose].
+type Diagnosis struct {
+	// Name of the binary
+	Name string
+	// Path the binary is expected at
+	Path string
+	// Version expected
+	Version string
+
+	// Installed is true if the binary is present
+	Installed bool
+	// VersionMismatch is true if the installed binary doesn't report the expected version,
+	// or if it can't be verified, like assets without a valid install receipt
+	VersionMismatch bool
+	// ChecksumDrift is true if the binary was modified since its install receipt was recorded
+	ChecksumDrift bool
+	// Unwritable is set if binaries can't be installed into the bin directory
+	Unwritable error
+	// Shadowed is the path of another executable with the same name found in PATH, which is
+	// run instead of the binary when invoked by name
+	Shadowed string
+	// Unreachable is set if the origin can't be reached; only checked for origins
+	// downloading from urls or the go module proxy, and not in offline mode
+	Unreachable error
+}
+
+// Healthy returns true if the binary is installed at the expected version and can be provisioned.
+// Binaries shadowed in PATH are still healthy, as their path is used to run them.
+func (d Diagnosis) Healthy() bool {
+	return d.Installed && !d.VersionMismatch && !d.ChecksumDrift && d.Unwritable == nil && d.Unreachable == nil
+}
+
+// probetimeout bounds the time spent checking if an origin is reachable.
+var probetimeout = 5 * time.Second
+
+// prober is implemented by origins that can check if their source is reachable.
+type prober interface {
+	probe(ctx context.Context, template Template) error
+}
+
+// Diagnose checks the state of the binary without changing anything: whether it's installed
+// at the expected version, modified since it was installed, shadowed by other executables in
+// PATH, and whether the bin directory is writable and its origin reachable.
+func (b *Binary) Diagnose(ctx context.Context) Diagnosis {
+	diagnosis := Diagnosis{
+		Name:      b.template.Name,
+		Path:      b.template.Cmd,
+		Version:   b.version,
+		Installed: b.isInstalled(),
+	}
+
+	if diagnosis.Installed {
+		switch {
+		case !b.system && b.hasValidReceipt():
+		case b.asset:
+			diagnosis.VersionMismatch = true
+		default:
+			diagnosis.VersionMismatch = !b.isExpectedVersion(ctx)
+		}
+	}
+
+	if b.system {
+		return diagnosis
+	}
+
+	if diagnosis.Installed {
+		diagnosis.ChecksumDrift = b.checksumdrift()
+	}
+	diagnosis.Unwritable = writable(b.template.Directory)
+
+	if !b.asset {
+		if path, err := exec.LookPath(b.template.Name); err == nil && !samefile(path, b.template.Cmd) {
+			diagnosis.Shadowed = path
+		}
+	}
+
+	if origin, ok := b.origin.(prober); ok && !offline() {
+		probectx, cancel := context.WithTimeout(ctx, probetimeout)
+		defer cancel()
+		diagnosis.Unreachable = origin.probe(probectx, b.template)
+	}
+
+	return diagnosis
+}
+
+// checksumdrift returns true if the checksum recorded in the install receipt
+// doesn't match the binary on disk.
+func (b *Binary) checksumdrift() bool {
+	rcpt, err := b.readReceipt()
+	if err != nil || rcpt.Checksum == "" {
+		return false
+	}
+
+	sum, err := sha256file(b.template.Cmd)
+	return err == nil && sum != rcpt.Checksum
+}
+
+// writable returns an error if files can't be created in the directory,
+// or in its closest existing parent if it doesn't exist yet.
+func writable(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s isn't a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) || filepath.Dir(dir) == dir {
+			return fmt.Errorf("failed to stat %s: %w", dir, err)
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	file, err := os.CreateTemp(dir, ".harness-doctor-*")
+	if err != nil {
+		return fmt.Errorf("%s isn't writable: %w", dir, err)
+	}
+	return errors.Join(file.Close(), os.Remove(file.Name()))
+}
+
+// samefile returns true if both paths point at the same file.
+func samefile(a, b string) bool {
+	first, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	second, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(first, second)
+}
+
+// probe checks that the url can be reached, sending a HEAD request with the configured headers.
+func (c origincfg) probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return err
+	}
+	c.setheaders(req)
+
+	resp, err := c.httpclient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("failed to close http response body: %w", err)
+	}
+
+	// servers that don't support HEAD requests are still reachable
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("unexpected response from %s: http%d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// probe checks that the url of the binary can be reached.
+func (r *remotebin) probe(ctx context.Context, template Template) error {
+	url, err := template.Resolve(r.urlformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve url: %w", err)
+	}
+	return r.config.withprimary(template, r.urlformat).probe(ctx, url)
+}
+
+// probe checks that the url of the archive can be reached.
+func (r *remotearchive) probe(ctx context.Context, template Template) error {
+	url, err := template.Resolve(r.urlformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve url: %w", err)
+	}
+	return r.config.withprimary(template, r.urlformat).probe(ctx, url)
+}
+
+// probe checks that the go module proxy can be reached.
+func (o *gopkg) probe(ctx context.Context, _ Template) error {
+	return o.config.probeproxy(ctx)
+}
+
+// probe checks that the go module proxy can be reached.
+func (o *gotool) probe(ctx context.Context, _ Template) error {
+	return o.config.probeproxy(ctx)
+}
+
+// probeproxy checks that the go module proxy can be reached, if one is configured.
+func (c origincfg) probeproxy(ctx context.Context) error {
+	proxy, err := goproxy()
+	if err != nil {
+		return nil
+	}
+	return c.probe(ctx, proxy+"/")
+}
@@ -0,0 +1,115 @@
+package binary
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binary is a shell script")
+	}
+
+	srv := setupTestServer(t)
+
+	t.Run("healthy",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			diagnosis := bin.Diagnose(t.Context())
+			assert.True(t, diagnosis.Healthy(), "%+v", diagnosis)
+			assert.Equal(t, bin.BinPath(), diagnosis.Path)
+		},
+	)
+
+	t.Run("missing binary with unreachable origin",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			diagnosis := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/missing")).Diagnose(t.Context())
+			assert.False(t, diagnosis.Healthy())
+			assert.False(t, diagnosis.Installed)
+			require.Error(t, diagnosis.Unreachable)
+			assert.Contains(t, diagnosis.Unreachable.Error(), "http404")
+			assert.NoDirExists(t, "bin", "diagnosing shouldn't change anything")
+		},
+	)
+
+	t.Run("version mismatch",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util")).Ensure(t.Context()))
+
+			diagnosis := New("util", "2.0.0", RemoteBinaryDownload(srv.URL+"/util")).Diagnose(t.Context())
+			assert.True(t, diagnosis.Installed)
+			assert.True(t, diagnosis.VersionMismatch)
+			assert.False(t, diagnosis.Healthy())
+		},
+	)
+
+	t.Run("checksum drift",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, bin.Ensure(t.Context()))
+			require.NoError(t, os.WriteFile(bin.BinPath(), []byte("#!/bin/sh\necho 'util version 1.2.3 patched'\n"), 0o755))
+
+			diagnosis := bin.Diagnose(t.Context())
+			assert.False(t, diagnosis.VersionMismatch)
+			assert.True(t, diagnosis.ChecksumDrift)
+			assert.False(t, diagnosis.Healthy())
+		},
+	)
+
+	t.Run("unwritable bin directory",
+		func(t *testing.T) {
+			withTempDir(t)
+			require.NoError(t, os.WriteFile("bin", nil, 0o644))
+
+			diagnosis := New("util", "1.2.3", new(fakeorigin)).Diagnose(t.Context())
+			require.Error(t, diagnosis.Unwritable)
+			assert.Contains(t, diagnosis.Unwritable.Error(), "isn't a directory")
+		},
+	)
+
+	t.Run("shadowed in PATH",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			pathdir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(pathdir, "util"), []byte("#!/bin/sh\n"), 0o755))
+			t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			diagnosis := bin.Diagnose(t.Context())
+			assert.Equal(t, filepath.Join(pathdir, "util"), diagnosis.Shadowed)
+			assert.True(t, diagnosis.Healthy())
+		},
+	)
+
+	t.Run("servers without HEAD support are reachable",
+		func(t *testing.T) {
+			head := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}))
+			t.Cleanup(head.Close)
+
+			var cfg origincfg
+			require.NoError(t, cfg.probe(t.Context(), head.URL))
+		},
+	)
+}
@@ -0,0 +1,66 @@
+package binary
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrVersionMismatch is returned when the installed binary doesn't report the expected
+// version and it can't be replaced, like binaries from [System] or in offline mode.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// DownloadError is returned when downloading a file or metadata fails, carrying the details
+// of the request so callers can inspect them via errors.As.
+type DownloadError struct {
+	// URL of the request that failed.
+	URL string
+	// Status of the response, or zero if no response was received, like on network errors.
+	Status int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DownloadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// Transient returns true if the download may succeed when retried, like on network errors,
+// timeouts, rate limits or server errors, as opposed to missing files or denied access.
+func (e *DownloadError) Transient() bool {
+	if e.Status == 0 {
+		// an untrusted certificate won't become trusted by retrying
+		var certerr *tls.CertificateVerificationError
+		return !errors.As(e.Err, &certerr)
+	}
+
+	return e.Status >= 500 ||
+		e.Status == http.StatusTooManyRequests ||
+		e.Status == http.StatusRequestTimeout
+}
+
+// UnsupportedArchiveError is returned when a downloaded archive isn't in any of the
+// supported formats.
+type UnsupportedArchiveError struct {
+	// File that couldn't be extracted.
+	File string
+	// MIME type detected from the contents of the file.
+	MIME string
+}
+
+func (e *UnsupportedArchiveError) Error() string {
+	return fmt.Sprintf("unsupported format: %s", e.MIME)
+}
+
+// IsTransient returns true if the error is caused by a failure that may go away when retried,
+// like a network error while downloading, see [DownloadError.Transient]. Other errors, like
+// version mismatches, invalid checksums or unsupported archives, need configuration changes.
+func IsTransient(err error) bool {
+	var dlerr *DownloadError
+	return errors.As(err, &dlerr) && dlerr.Transient()
+}
@@ -0,0 +1,105 @@
+package binary
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadErrorTransient(t *testing.T) {
+	tests := map[string]struct {
+		err  DownloadError
+		want bool
+	}{
+		"network error":         {err: DownloadError{Err: errors.New("connection refused")}, want: true},
+		"server error":          {err: DownloadError{Status: http.StatusBadGateway}, want: true},
+		"rate limited":          {err: DownloadError{Status: http.StatusTooManyRequests}, want: true},
+		"request timeout":       {err: DownloadError{Status: http.StatusRequestTimeout}, want: true},
+		"not found":             {err: DownloadError{Status: http.StatusNotFound}, want: false},
+		"forbidden":             {err: DownloadError{Status: http.StatusForbidden}, want: false},
+		"untrusted certificate": {err: DownloadError{Err: fmt.Errorf("tls: %w", new(tls.CertificateVerificationError))}, want: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name,
+			func(t *testing.T) {
+				assert.Equal(t, test.want, test.err.Transient())
+				assert.Equal(t, test.want, IsTransient(fmt.Errorf("failed: %w", &test.err)))
+			},
+		)
+	}
+
+	assert.False(t, IsTransient(errors.New("not a download error")))
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/notes.txt":
+			fmt.Fprint(w, "release notes, not an archive")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	backoff := downloadbackoff
+	downloadbackoff = 0
+	t.Cleanup(func() { downloadbackoff = backoff })
+
+	t.Run("missing files aren't transient",
+		func(t *testing.T) {
+			err := RemoteBinaryDownload(srv.URL+"/missing").Install(t.Context(), mktemplate(t.TempDir(), "util", "1.2.3"))
+
+			var dlerr *DownloadError
+			require.ErrorAs(t, err, &dlerr)
+			assert.Equal(t, srv.URL+"/missing", dlerr.URL)
+			assert.Equal(t, http.StatusNotFound, dlerr.Status)
+			assert.False(t, IsTransient(err))
+		},
+	)
+
+	t.Run("server errors are transient",
+		func(t *testing.T) {
+			err := RemoteBinaryDownload(srv.URL+"/unavailable").Install(t.Context(), mktemplate(t.TempDir(), "util", "1.2.3"))
+
+			var dlerr *DownloadError
+			require.ErrorAs(t, err, &dlerr)
+			assert.Equal(t, http.StatusServiceUnavailable, dlerr.Status)
+			assert.True(t, IsTransient(err))
+		},
+	)
+
+	t.Run("network errors are transient",
+		func(t *testing.T) {
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+
+			err := RemoteBinaryDownload(closed.URL+"/util").Install(t.Context(), mktemplate(t.TempDir(), "util", "1.2.3"))
+
+			var dlerr *DownloadError
+			require.ErrorAs(t, err, &dlerr)
+			assert.Zero(t, dlerr.Status)
+			assert.True(t, IsTransient(err))
+		},
+	)
+
+	t.Run("unsupported archives",
+		func(t *testing.T) {
+			err := RemoteArchiveDownload(srv.URL+"/notes.txt", map[string]string{"util": "util"}).Install(t.Context(), mktemplate(t.TempDir(), "util", "1.2.3"))
+
+			var archerr *UnsupportedArchiveError
+			require.ErrorAs(t, err, &archerr)
+			assert.Equal(t, "text/plain; charset=utf-8", archerr.MIME)
+			assert.False(t, IsTransient(err))
+		},
+	)
+}
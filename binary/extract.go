@@ -3,28 +3,243 @@ package binary
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"compress/bzip2"
 	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/aexvir/harness/internal"
 )
 
-// handles .tar.gz files
-func untar(file io.Reader, destination string, processor func(path string) *string) (err error) {
-	decompressor, err := gzip.NewReader(file)
+// promote moves every file extracted in the staging directory to the destination,
+// keeping the relative paths they have inside the staging directory.
+func promote(staging, destination string) error {
+	return filepath.WalkDir(staging, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(staging, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve staged file %s: %w", path, err)
+		}
+		target := filepath.Join(destination, rel)
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+		}
+
+		if err := os.Rename(path, target); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", path, target, err)
+		}
+
+		return nil
+	})
+}
+
+// entrypath validates the path an archive entry is extracted to, rejecting absolute paths
+// and paths escaping the destination, like the ones crafted for zip-slip attacks.
+func entrypath(name string) (string, error) {
+	path := filepath.FromSlash(name)
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(path) || !filepath.IsLocal(path) {
+		return "", fmt.Errorf("illegal path %q in archive", name)
+	}
+	return filepath.Clean(path), nil
+}
+
+// extractlink creates the symlink entry at path pointing to target.
+// Links are only extracted when their target stays within the destination, so an archive
+// can't expose files outside of it, see [verifylinks]; links that can't be created, e.g. on
+// windows without the needed privileges, are skipped.
+func extractlink(root *os.Root, path, target string) error {
+	resolved := filepath.Join(filepath.Dir(path), filepath.FromSlash(target))
+	if strings.HasPrefix(target, "/") || filepath.IsAbs(target) || !filepath.IsLocal(resolved) {
+		return fmt.Errorf("illegal link %s to %q in archive", path, target)
+	}
+
+	if err := root.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	if err := root.Symlink(target, path); err != nil {
+		internal.LogDetail(fmt.Sprintf("skipping link %s: %s", path, err))
+	}
+	return nil
+}
+
+// verifylinks checks that every extracted link resolves to a file extracted from the archive.
+// Link targets are validated when extracting them, but links pointing through other links
+// can only be resolved once every file is in place.
+func verifylinks(root *os.Root, links []string) error {
+	for _, link := range links {
+		// skipped links
+		if _, err := root.Lstat(link); err != nil {
+			continue
+		}
+
+		if _, err := root.Stat(link); err != nil {
+			return fmt.Errorf("illegal link %s in archive: %w", link, err)
+		}
+	}
+	return nil
+}
+
+// extractfile writes the contents of the file entry at path with the given permissions.
+func extractfile(root *os.Root, path string, mode os.FileMode, contents io.Reader) (err error) {
+	if err := root.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	out, err := root.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	defer func() {
+		if closerr := out.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", path, closerr))
+		}
+	}()
+
+	// the mode passed to open is subject to umask
+	if err := root.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+
+	if _, err := io.Copy(out, contents); err != nil {
+		return fmt.Errorf("failed to copy data to file %s: %w", path, err)
+	}
+	return nil
+}
+
+// permissions resolves the permissions of the file extracted to path, given the mode stored
+// for it in the archive, which is zero for single compressed files.
+type permissions func(path string, archived os.FileMode) os.FileMode
+
+// compression is a compression format archives or single files can be distributed in.
+type compression struct {
+	name  string
+	magic []byte
+	// extensions of the files in this format, used when the magic bytes aren't found
+	extensions []string
+	// reader returns a reader decompressing r
+	reader func(r io.Reader) (io.ReadCloser, error)
+}
+
+// compressions are the supported compression formats,
+// detected by the magic bytes at the start of the file.
+var compressions = []compression{
+	{
+		name:       "gzip",
+		magic:      []byte{0x1f, 0x8b},
+		extensions: []string{".gz", ".tgz"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		name:       "bzip2",
+		magic:      []byte("BZh"),
+		extensions: []string{".bz2", ".tbz2", ".tbz"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+	},
+	{
+		name:       "xz",
+		magic:      []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		extensions: []string{".xz", ".txz"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			decompressor, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(decompressor), nil
+		},
+	},
+	{
+		name:       "zstd",
+		magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+		extensions: []string{".zst", ".tzst"},
+		reader: func(r io.Reader) (io.ReadCloser, error) {
+			decompressor, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return decompressor.IOReadCloser(), nil
+		},
+	},
+}
+
+// istar returns true if header is the start of a tar archive.
+func istar(header []byte) bool {
+	// both posix and gnu archives have the ustar magic at the end of the first header
+	return len(header) >= 262 && string(header[257:262]) == "ustar"
+}
+
+// handles compressed tar archives, like .tar.gz, .tar.xz, and single compressed files, like .gz
+func decompress(file io.Reader, compression compression, destination, name string, perms permissions, processor func(path string) *string) (err error) {
+	decompressor, err := compression.reader(file)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return fmt.Errorf("failed to create %s reader: %w", compression.name, err)
 	}
 	defer func() {
 		if closerr := decompressor.Close(); closerr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to close gzip reader: %w", closerr))
+			err = errors.Join(err, fmt.Errorf("failed to close %s reader: %w", compression.name, closerr))
 		}
 	}()
 
-	reader := tar.NewReader(decompressor)
+	reader := bufio.NewReaderSize(decompressor, 512)
+	header, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read %s stream: %w", compression.name, err)
+	}
+
+	if istar(header) {
+		return untar(reader, destination, perms, processor)
+	}
+
+	root, err := os.OpenRoot(destination)
+	if err != nil {
+		return fmt.Errorf("failed to open destination %s: %w", destination, err)
+	}
+	defer root.Close() //nolint:errcheck
+
+	path, err := entrypath(name)
+	if err != nil {
+		return err
+	}
+
+	internal.LogDetail(fmt.Sprintf("  decompressing single file to %s", path))
+	return extractfile(root, path, perms(path, 0), reader)
+}
+
+// handles .tar files
+func untar(file io.Reader, destination string, perms permissions, processor func(path string) *string) (err error) {
+	// every file is written through the root, so nothing can be written outside of it,
+	// not even following links extracted earlier
+	root, err := os.OpenRoot(destination)
+	if err != nil {
+		return fmt.Errorf("failed to open destination %s: %w", destination, err)
+	}
+	defer root.Close() //nolint:errcheck
+
+	reader := tar.NewReader(file)
 
+	var links []string
 	for {
 		header, err := reader.Next()
 		if err != nil {
@@ -38,94 +253,194 @@ func untar(file io.Reader, destination string, processor func(path string) *stri
 		if processed == nil {
 			continue
 		}
-		target := filepath.Join(destination, *processed)
+		path, err := entrypath(*processed)
+		if err != nil {
+			return err
+		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(target, 0o755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", target, err)
+			if err := root.MkdirAll(path, 0o755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", path, err)
 			}
 		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
-			}
-
-			out, err := os.Create(target)
-			if err != nil {
-				return fmt.Errorf("failed to create file %s: %w", target, err)
+			if err := extractfile(root, path, perms(path, header.FileInfo().Mode()), reader); err != nil {
+				return err
 			}
-			defer func() {
-				if closerr := out.Close(); closerr != nil {
-					err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", target, closerr))
-				}
-			}()
-
-			if err := os.Chmod(target, 0o755); err != nil {
-				return fmt.Errorf("failed to set permissions on %s: %w", target, err)
-			}
-
-			if _, err := io.Copy(out, reader); err != nil {
-				return fmt.Errorf("failed to copy data to file %s: %w", target, err)
+		case tar.TypeSymlink:
+			if err := extractlink(root, path, header.Linkname); err != nil {
+				return err
 			}
+			links = append(links, path)
+		default:
+			internal.LogDetail(fmt.Sprintf("skipping unsupported entry %s", header.Name))
 		}
 	}
 
-	return nil
+	return verifylinks(root, links)
 }
 
 // handles .zip files
-func unzip(file io.ReaderAt, size int64, destination string, processor func(path string) *string) (err error) {
+func unzip(file io.ReaderAt, size int64, destination string, perms permissions, processor func(path string) *string) (err error) {
 	reader, err := zip.NewReader(file, size)
 	if err != nil {
 		return fmt.Errorf("failed to create zip reader: %w", err)
 	}
 
+	root, err := os.OpenRoot(destination)
+	if err != nil {
+		return fmt.Errorf("failed to open destination %s: %w", destination, err)
+	}
+	defer root.Close() //nolint:errcheck
+
+	var links []string
 	for _, file := range reader.File {
 		processed := processor(file.Name)
 		if processed == nil {
 			continue
 		}
-		target := filepath.Join(destination, *processed)
+		path, err := entrypath(*processed)
+		if err != nil {
+			return err
+		}
 
-		if file.FileInfo().IsDir() {
-			if err := os.MkdirAll(target, 0o755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", target, err)
-			}
-			continue
+		if err := unzipentry(root, file, path, perms(path, zipmode(file))); err != nil {
+			return err
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			links = append(links, path)
 		}
+	}
 
-		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
+	return verifylinks(root, links)
+}
+
+// zipmode returns the mode stored for the zip entry, which is only meaningful
+// for archives created on unix, zero otherwise.
+func zipmode(file *zip.File) os.FileMode {
+	switch file.CreatorVersion >> 8 {
+	case 3, 19: // unix, macos
+		return file.Mode()
+	default:
+		return 0
+	}
+}
+
+// unzipentry extracts a single zip entry to path.
+func unzipentry(root *os.Root, file *zip.File, path string, mode os.FileMode) (err error) {
+	if file.FileInfo().IsDir() {
+		if err := root.MkdirAll(path, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", path, err)
 		}
+		return nil
+	}
 
-		out, err := os.Create(target)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %w", target, err)
+	contents, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open compressed file %s: %w", file.Name, err)
+	}
+	defer func() {
+		if closerr := contents.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close compressed file %s: %w", file.Name, closerr))
 		}
-		defer func() {
-			if closerr := out.Close(); closerr != nil {
-				err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", target, closerr))
-			}
-		}()
+	}()
 
-		if err := os.Chmod(target, 0o755); err != nil {
-			return fmt.Errorf("failed to set permissions on %s: %w", target, err)
+	// the target of links is stored as their contents
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(contents)
+		if err != nil {
+			return fmt.Errorf("failed to read link %s: %w", file.Name, err)
 		}
+		return extractlink(root, path, string(target))
+	}
+
+	return extractfile(root, path, mode, contents)
+}
 
-		contents, err := file.Open()
+// sevenzipmagic are the magic bytes at the start of 7z archives.
+var sevenzipmagic = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+
+// handles .7z files, including self-extracting ones, as the reader looks for the archive
+// signature past the executable stub
+func un7z(file io.ReaderAt, size int64, destination string, perms permissions, processor func(path string) *string) (err error) {
+	reader, err := sevenzip.NewReader(file, size)
+	if err != nil {
+		return fmt.Errorf("failed to create 7z reader: %w", err)
+	}
+
+	root, err := os.OpenRoot(destination)
+	if err != nil {
+		return fmt.Errorf("failed to open destination %s: %w", destination, err)
+	}
+	defer root.Close() //nolint:errcheck
+
+	var links []string
+	for _, file := range reader.File {
+		processed := processor(file.Name)
+		if processed == nil {
+			continue
+		}
+		path, err := entrypath(*processed)
 		if err != nil {
-			return fmt.Errorf("failed to open compressed file %s: %w", file.Name, err)
+			return err
 		}
-		defer func() {
-			if closerr := contents.Close(); closerr != nil {
-				err = errors.Join(err, fmt.Errorf("failed to close compressed file %s: %w", file.Name, closerr))
-			}
-		}()
 
-		if _, err := io.Copy(out, contents); err != nil {
-			return fmt.Errorf("failed to copy data to file %s: %w", target, err)
+		if err := un7zentry(root, file, path, perms(path, sevenzipmode(file))); err != nil {
+			return err
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			links = append(links, path)
 		}
 	}
 
-	return nil
+	return verifylinks(root, links)
+}
+
+// sevenzipmode returns the mode stored for the 7z entry, which is only meaningful
+// when it has unix attributes, zero otherwise.
+func sevenzipmode(file *sevenzip.File) os.FileMode {
+	if file.Attributes&0x8000 == 0 {
+		return 0
+	}
+	return file.Mode()
+}
+
+// un7zentry extracts a single 7z entry to path.
+func un7zentry(root *os.Root, file *sevenzip.File, path string, mode os.FileMode) (err error) {
+	if file.FileInfo().IsDir() {
+		if err := root.MkdirAll(path, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", path, err)
+		}
+		return nil
+	}
+
+	contents, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open compressed file %s: %w", file.Name, err)
+	}
+	defer func() {
+		if closerr := contents.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close compressed file %s: %w", file.Name, closerr))
+		}
+	}()
+
+	// like zip, the target of links is stored as their contents
+	if file.Mode()&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(contents)
+		if err != nil {
+			return fmt.Errorf("failed to read link %s: %w", file.Name, err)
+		}
+		return extractlink(root, path, string(target))
+	}
+
+	return extractfile(root, path, mode, contents)
+}
+
+// unsfx handles self-extracting archives, windows executables with a zip or 7z archive appended.
+func unsfx(file io.ReaderAt, size int64, destination string, perms permissions, processor func(path string) *string) error {
+	// the zip reader locates the central directory from the end of the file, so prefixed data is fine
+	if _, err := zip.NewReader(file, size); err == nil {
+		return unzip(file, size, destination, perms, processor)
+	}
+	return un7z(file, size, destination, perms, processor)
 }
@@ -0,0 +1,272 @@
+package binary
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractPathTraversal(t *testing.T) {
+	keep := func(path string) *string { return &path }
+	executable := func(string, os.FileMode) os.FileMode { return 0o755 }
+
+	for _, name := range []string{"../evil", "nested/../../evil", "/tmp/evil"} {
+		t.Run("tar rejects "+name,
+			func(t *testing.T) {
+				dir := t.TempDir()
+				destination := filepath.Join(dir, "dest")
+				require.NoError(t, os.Mkdir(destination, 0o755))
+
+				archive := mktargz(t, &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: 4, Mode: 0o644})
+
+				err := decompress(bytes.NewReader(archive), compressions[0], destination, "util", executable, keep)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "illegal path")
+				assert.NoFileExists(t, filepath.Join(dir, "evil"))
+			},
+		)
+
+		t.Run("zip rejects "+name,
+			func(t *testing.T) {
+				dir := t.TempDir()
+				destination := filepath.Join(dir, "dest")
+				require.NoError(t, os.Mkdir(destination, 0o755))
+
+				archive := mkzip(t, &zip.FileHeader{Name: name})
+
+				err := unzip(bytes.NewReader(archive), int64(len(archive)), destination, executable, keep)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "illegal path")
+				assert.NoFileExists(t, filepath.Join(dir, "evil"))
+			},
+		)
+	}
+
+	t.Run("rejects links escaping the destination",
+		func(t *testing.T) {
+			destination := t.TempDir()
+			archive := mktargz(t, &tar.Header{Name: "bin/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"})
+
+			err := decompress(bytes.NewReader(archive), compressions[0], destination, "util", executable, keep)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "illegal link")
+		},
+	)
+
+	t.Run("extracts links within the destination",
+		func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating links requires privileges on windows")
+			}
+
+			destination := t.TempDir()
+			archive := mktargz(t,
+				&tar.Header{Name: "lib/util", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644},
+				&tar.Header{Name: "bin/util", Typeflag: tar.TypeSymlink, Linkname: "../lib/util"},
+			)
+
+			require.NoError(t, decompress(bytes.NewReader(archive), compressions[0], destination, "util", executable, keep))
+
+			target, err := os.Readlink(filepath.Join(destination, "bin", "util"))
+			require.NoError(t, err)
+			assert.Equal(t, "../lib/util", target)
+		},
+	)
+
+	t.Run("doesn't write through links",
+		func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating links requires privileges on windows")
+			}
+
+			dir := t.TempDir()
+			destination := filepath.Join(dir, "nested", "dest")
+			require.NoError(t, os.MkdirAll(destination, 0o755))
+
+			// the links look local, but esc resolves two levels above the destination
+			archive := mktargz(t,
+				&tar.Header{Name: "s", Typeflag: tar.TypeSymlink, Linkname: "."},
+				&tar.Header{Name: "esc", Typeflag: tar.TypeSymlink, Linkname: "s/s/../.."},
+				&tar.Header{Name: "esc/evil", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644},
+			)
+
+			require.Error(t, decompress(bytes.NewReader(archive), compressions[0], destination, "util", executable, keep))
+			assert.NoFileExists(t, filepath.Join(dir, "evil"))
+		},
+	)
+
+	t.Run("rejects links resolving outside the destination through other links",
+		func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("creating links requires privileges on windows")
+			}
+
+			archive := mktargz(t,
+				&tar.Header{Name: "s", Typeflag: tar.TypeSymlink, Linkname: "."},
+				&tar.Header{Name: "esc", Typeflag: tar.TypeSymlink, Linkname: "s/s/../.."},
+			)
+
+			err := decompress(bytes.NewReader(archive), compressions[0], t.TempDir(), "util", executable, keep)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "illegal link esc")
+		},
+	)
+}
+
+func TestExtractPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions aren't supported on windows")
+	}
+
+	archive := mktargz(t,
+		&tar.Header{Name: "pkg/util", Typeflag: tar.TypeReg, Size: 4, Mode: 0o644},
+		&tar.Header{Name: "pkg/README.md", Typeflag: tar.TypeReg, Size: 4, Mode: 0o600},
+		&tar.Header{Name: "pkg/lib/helper", Typeflag: tar.TypeReg, Size: 4, Mode: 0o750},
+	)
+	binaries := map[string]string{"pkg/util": "util", "pkg/README.md": "README.md", "pkg/lib/": "lib/"}
+
+	perm := func(t *testing.T, path string) os.FileMode {
+		t.Helper()
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return info.Mode().Perm()
+	}
+
+	t.Run("keeps archived permissions",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+			path := filepath.Join(tmpl.Directory, "util.tar.gz")
+			require.NoError(t, os.WriteFile(path, archive, 0o644))
+
+			require.NoError(t, unarchive(tmpl, path, binaries, false))
+			assert.Equal(t, os.FileMode(0o644), perm(t, filepath.Join(tmpl.Directory, "util")))
+			assert.Equal(t, os.FileMode(0o600), perm(t, filepath.Join(tmpl.Directory, "README.md")))
+			assert.Equal(t, os.FileMode(0o750), perm(t, filepath.Join(tmpl.Directory, "lib", "helper")))
+		},
+	)
+
+	t.Run("makes mapped binaries executable",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+			path := filepath.Join(tmpl.Directory, "util.tar.gz")
+			require.NoError(t, os.WriteFile(path, archive, 0o644))
+
+			require.NoError(t, unarchive(tmpl, path, map[string]string{"pkg/util": "util", "pkg/lib/": "lib/"}, true))
+			assert.Equal(t, os.FileMode(0o755), perm(t, filepath.Join(tmpl.Directory, "util")))
+			assert.Equal(t, os.FileMode(0o750), perm(t, filepath.Join(tmpl.Directory, "lib", "helper")))
+		},
+	)
+
+	t.Run("falls back to the template mode for entries without permissions",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+			path := filepath.Join(tmpl.Directory, "util.zip")
+			require.NoError(t, os.WriteFile(path, mkzip(t, &zip.FileHeader{Name: "util"}), 0o644))
+
+			require.NoError(t, unarchive(tmpl, path, nil, false))
+			assert.Equal(t, os.FileMode(0o755), perm(t, filepath.Join(tmpl.Directory, "util")))
+		},
+	)
+}
+
+func TestExtractByExtension(t *testing.T) {
+	keep := func(path string) *string { return &path }
+	executable := func(string, os.FileMode) os.FileMode { return 0o755 }
+
+	// v7 tar archives have no magic bytes
+	var v7 bytes.Buffer
+	tw := tar.NewWriter(&v7)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "util", Typeflag: tar.TypeReg, Size: 4, Mode: 0o755, Format: tar.FormatGNU}))
+	_, err := tw.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	// GNU headers are only distinguished from v7 ones by their magic bytes,
+	// so clear them and recompute the header checksum
+	v7tar := v7.Bytes()
+	copy(v7tar[257:265], make([]byte, 8))
+	copy(v7tar[148:156], "        ")
+	var sum int
+	for _, b := range v7tar[:512] {
+		sum += int(b)
+	}
+	copy(v7tar[148:156], fmt.Sprintf("%06o\x00 ", sum))
+
+	tests := map[string][]byte{
+		"util.tar": v7tar,
+		// servers and installers may prepend data to zip archives
+		"util.zip": append([]byte("#!/bin/sh\nexit 0\n"), mkzip(t, &zip.FileHeader{Name: "util"})...),
+	}
+
+	for name, archive := range tests {
+		t.Run(name,
+			func(t *testing.T) {
+				dir := t.TempDir()
+				destination := filepath.Join(dir, "dest")
+				require.NoError(t, os.Mkdir(destination, 0o755))
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(path, archive, 0o644))
+
+				require.NoError(t, extract(path, destination, "util", executable, keep))
+				assert.FileExists(t, filepath.Join(destination, "util"))
+			},
+		)
+	}
+
+	t.Run("corrupt archive fails with the format of the extension",
+		func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "util.tar.xz")
+			require.NoError(t, os.WriteFile(path, []byte("not found"), 0o644))
+
+			err := extract(path, t.TempDir(), "util", executable, keep)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "xz")
+		},
+	)
+}
+
+// mktargz builds a tar.gz archive with the given entries; regular files are filled with
+// as many bytes as their size.
+func mktargz(t *testing.T, headers ...*tar.Header) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, header := range headers {
+		require.NoError(t, tw.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := tw.Write(bytes.Repeat([]byte("x"), int(header.Size)))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+// mkzip builds a zip archive with the given entries, each containing some bytes.
+func mkzip(t *testing.T, headers ...*zip.FileHeader) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, header := range headers {
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
@@ -0,0 +1,33 @@
+package binary
+
+import (
+	"context"
+)
+
+// WithForce makes [Binary.Ensure] install the binary every time, bypassing the binary already
+// installed, previous downloads, the shared cache and the cached resolution of "latest".
+// To force a single install, use [Binary.ForceInstall] instead.
+func WithForce() Option {
+	return func(b *Binary) {
+		b.force = true
+	}
+}
+
+// ForceInstall installs the binary bypassing previous downloads, the shared cache and the
+// cached resolution of "latest", e.g. to recover from a corrupted install.
+func (b *Binary) ForceInstall(ctx context.Context) error {
+	return b.Install(withforce(ctx))
+}
+
+type forcekey struct{}
+
+// withforce returns a context making installs bypass every cache.
+func withforce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcekey{}, true)
+}
+
+// forced returns true if installs should bypass every cache, see [WithForce].
+func forced(ctx context.Context) bool {
+	force, _ := ctx.Value(forcekey{}).(bool)
+	return force
+}
@@ -0,0 +1,69 @@
+package binary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForce(t *testing.T) {
+	t.Run("reinstalls on every ensure",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin), WithVersionCmd(SkipVersionCheck)).Ensure(t.Context()))
+
+			origin := new(fakeorigin)
+			require.NoError(t, New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck), WithForce()).Ensure(t.Context()))
+			assert.True(t, origin.installed)
+		},
+	)
+
+	t.Run("bypasses the shared cache",
+		func(t *testing.T) {
+			withTempDir(t)
+			cache := t.TempDir()
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin), WithSharedCache(cache)).Install(t.Context()))
+
+			origin := new(fakeorigin)
+			require.NoError(t, New("util", "1.0.0", origin, WithSharedCache(cache)).ForceInstall(t.Context()))
+			assert.True(t, origin.installed)
+		},
+	)
+
+	t.Run("resolves latest again",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			latest := "1.0.0"
+			origin := &fakeresolver{latest: func() string { return latest }}
+			require.NoError(t, New("util", "latest", origin, WithLatestTTL(time.Hour)).Install(t.Context()))
+
+			latest = "1.1.0"
+			origin.installed = false // keep the digest of the origin
+			bin := New("util", "latest", origin, WithLatestTTL(time.Hour))
+			require.NoError(t, bin.Install(t.Context()))
+			assert.Equal(t, "1.0.0", bin.version)
+
+			origin.installed = false
+			bin = New("util", "latest", origin, WithLatestTTL(time.Hour))
+			require.NoError(t, bin.ForceInstall(t.Context()))
+			assert.Equal(t, "1.1.0", bin.version)
+		},
+	)
+}
+
+// fakeresolver is a [fakeorigin] resolving latest with a func, which doesn't
+// change the digest of the origin when it resolves to other versions.
+type fakeresolver struct {
+	fakeorigin
+	latest func() string
+}
+
+func (f *fakeresolver) LatestVersion(_ context.Context, _ Template) (string, error) {
+	return f.latest(), nil
+}
@@ -0,0 +1,272 @@
+package binary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// gitlabcfg holds the options of the GitLab origins.
+type gitlabcfg struct {
+	url   string
+	token string
+}
+
+// gitlabrelease implements [Origin] for assets attached to GitLab releases.
+type gitlabrelease struct {
+	project string
+	asset   string
+	config  origincfg
+}
+
+// GitLabRelease creates a new Origin that downloads an asset attached to a release of a
+// GitLab project, given its id or full path, e.g. "group/project".
+// The asset is the name of the release asset link to download; it can contain template
+// variables that will be resolved using the [Template] values and glob patterns, e.g.
+// "tool_{{.GOOS}}_{{.GOARCH}}*".
+//
+// The release is looked up by the tag "v{{.Version}}", which can be changed via [WithReleaseTag].
+// The asset is installed as the binary unless it's an archive, in which case the files to extract
+// are specified via [WithArchiveFiles].
+//
+// Requests are made to the GitLab instance the job runs on, or gitlab.com, and are authenticated
+// with the GITLAB_TOKEN env variable or the CI_JOB_TOKEN of the job, if present; see
+// [WithGitLabURL] and [WithGitLabToken] to use others.
+func GitLabRelease(project, asset string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &gitlabrelease{
+		project: project,
+		asset:   asset,
+		config:  cfg,
+	}
+}
+
+func (g *gitlabrelease) digest() any {
+	return struct {
+		Project, Asset string
+		Config         any
+	}{g.project, g.asset, g.config.digest()}
+}
+
+func (g *gitlabrelease) Install(ctx context.Context, template Template) error {
+	base, cfg, err := g.config.gitlabauth()
+	if err != nil {
+		return err
+	}
+
+	tagformat := g.config.tag
+	if tagformat == "" {
+		tagformat = "v{{.Version}}"
+	}
+	tag, err := template.Resolve(tagformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve release tag: %w", err)
+	}
+
+	pattern, err := template.Resolve(g.asset)
+	if err != nil {
+		return fmt.Errorf("failed to resolve asset name: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/releases/%s", base, url.PathEscape(g.project), url.PathEscape(tag))
+	internal.LogStep(fmt.Sprintf("looking up release %s of %s", tag, g.project))
+
+	var release struct {
+		Assets struct {
+			Links []struct {
+				Name           string `json:"name"`
+				URL            string `json:"url"`
+				DirectAssetURL string `json:"direct_asset_url"`
+			} `json:"links"`
+		} `json:"assets"`
+	}
+	if err := cfg.getjson(ctx, endpoint, &release); err != nil {
+		return fmt.Errorf("failed to look up release %s of %s: %w", tag, g.project, err)
+	}
+
+	for _, link := range release.Assets.Links {
+		if matched, _ := path.Match(pattern, link.Name); !matched {
+			continue
+		}
+
+		asset := link.DirectAssetURL
+		if asset == "" {
+			asset = link.URL
+		}
+		return cfg.install(ctx, template, asset)
+	}
+
+	return fmt.Errorf("release %s of %s has no asset matching %s", tag, g.project, pattern)
+}
+
+// gitlabpackage implements [Origin] for files in the generic package registry of a GitLab project.
+type gitlabpackage struct {
+	project string
+	pkg     string
+	file    string
+	config  origincfg
+}
+
+// GitLabPackage creates a new Origin that downloads a file from the generic package registry
+// of a GitLab project, given its id or full path, e.g. "group/project".
+// The package version is the version of the binary, while the file name can contain template
+// variables that will be resolved using the [Template] values, e.g. "tool_{{.GOOS}}_{{.GOARCH}}".
+//
+// The file is installed as the binary unless it's an archive, in which case the files to extract
+// are specified via [WithArchiveFiles].
+// Requests are authenticated the same way as for [GitLabRelease].
+func GitLabPackage(project, pkg, file string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &gitlabpackage{
+		project: project,
+		pkg:     pkg,
+		file:    file,
+		config:  cfg,
+	}
+}
+
+func (g *gitlabpackage) digest() any {
+	return struct {
+		Project, Package, File string
+		Config                 any
+	}{g.project, g.pkg, g.file, g.config.digest()}
+}
+
+func (g *gitlabpackage) Install(ctx context.Context, template Template) error {
+	base, cfg, err := g.config.gitlabauth()
+	if err != nil {
+		return err
+	}
+
+	file, err := template.Resolve(g.file)
+	if err != nil {
+		return fmt.Errorf("failed to resolve file name: %w", err)
+	}
+
+	return cfg.install(
+		ctx,
+		template,
+		fmt.Sprintf(
+			"%s/api/v4/projects/%s/packages/generic/%s/%s/%s",
+			base, url.PathEscape(g.project), url.PathEscape(g.pkg), url.PathEscape(template.Version), url.PathEscape(file),
+		),
+	)
+}
+
+// WithGitLabURL sets the URL of the GitLab instance the GitLab origins download from.
+// By default, the instance the CI job runs on is used, or gitlab.com otherwise.
+func WithGitLabURL(url string) OriginOption {
+	return func(c *origincfg) {
+		c.gitlab.url = url
+	}
+}
+
+// WithGitLabToken sets the private, project or group access token the GitLab origins
+// authenticate with, instead of the GITLAB_TOKEN or CI_JOB_TOKEN env variables.
+func WithGitLabToken(token string) OriginOption {
+	return func(c *origincfg) {
+		c.gitlab.token = token
+	}
+}
+
+// WithReleaseTag sets the format of the tag releases are looked up by, e.g. "{{.Name}}-{{.Version}}".
+// The format can contain template variables that will be resolved using the [Template] values.
+func WithReleaseTag(format string) OriginOption {
+	return func(c *origincfg) {
+		c.tag = format
+	}
+}
+
+// WithArchiveFiles marks the file downloaded by origins that resolve the download URL on
+// their own, like [GitLabRelease], as an archive, specifying the files to extract from it
+// the same way as for [RemoteArchiveDownload].
+func WithArchiveFiles(binaries map[string]string) OriginOption {
+	return func(c *origincfg) {
+		c.files = binaries
+	}
+}
+
+// gitlabauth returns the base URL of the GitLab instance, along with the configuration
+// including the headers needed to authenticate against it.
+func (c origincfg) gitlabauth() (string, origincfg, error) {
+	base := c.gitlab.url
+	if base == "" {
+		base = os.Getenv("CI_SERVER_URL")
+	}
+	if base == "" {
+		base = "https://gitlab.com"
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	parsed, err := url.Parse(base)
+	if err != nil || parsed.Host == "" {
+		return "", c, fmt.Errorf("invalid gitlab url %q", base)
+	}
+
+	header := func(key, value string) headerrule {
+		return headerrule{hosts: []string{parsed.Host}, key: key, value: func() string { return value }}
+	}
+
+	// the instance is the main host, even when assets are linked from somewhere else
+	c.primaryhost = parsed.Host
+
+	// clip so the headers of the origin aren't modified
+	c.headers = slices.Clip(c.headers)
+	switch {
+	case c.gitlab.token != "":
+		c.headers = append(c.headers, header("PRIVATE-TOKEN", c.gitlab.token))
+	case os.Getenv("GITLAB_TOKEN") != "":
+		c.headers = append(c.headers, header("PRIVATE-TOKEN", os.Getenv("GITLAB_TOKEN")))
+	case os.Getenv("CI_JOB_TOKEN") != "":
+		c.headers = append(c.headers, header("JOB-TOKEN", os.Getenv("CI_JOB_TOKEN")))
+	}
+
+	return base, c, nil
+}
+
+// getjson performs a GET request to url decoding the json response into target.
+func (c origincfg) getjson(ctx context.Context, url string, target any) (err error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closerr := resp.Body.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close http response body: %w", closerr))
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseerror("metadata", url, resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// install downloads the file at url as a binary, or as an archive if [WithArchiveFiles] is set.
+func (c origincfg) install(ctx context.Context, template Template, url string) error {
+	// the url is already resolved, escape it so it's not resolved again as a template
+	urlformat := fmt.Sprintf("{{%q}}", url)
+
+	if c.files != nil {
+		return (&remotearchive{urlformat: urlformat, binaries: c.files, config: c}).Install(ctx, template)
+	}
+	return (&remotebin{urlformat: urlformat, config: c}).Install(ctx, template)
+}
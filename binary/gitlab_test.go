@@ -0,0 +1,140 @@
+package binary
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitLabOrigins(t *testing.T) {
+	// fakegitlab serves the release api and the package registry, serving testdata files
+	// as assets, and records the tokens sent on each request
+	fakegitlab := func(t *testing.T, assethost string) (*httptest.Server, *sync.Map) {
+		t.Helper()
+		sub, err := fs.Sub(testdata, "testdata")
+		require.NoError(t, err)
+		files := http.FileServer(http.FS(sub))
+
+		tokens := new(sync.Map)
+		var srv *httptest.Server
+		srv = httptest.NewServer(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokens.Store(r.URL.Path, r.Header.Get("PRIVATE-TOKEN")+r.Header.Get("JOB-TOKEN"))
+
+				switch r.URL.EscapedPath() {
+				case "/api/v4/projects/group%2Ftool/releases/v1.2.3":
+					host := assethost
+					if host == "" {
+						host = srv.URL
+					}
+					_ = json.NewEncoder(w).Encode(map[string]any{
+						"assets": map[string]any{
+							"links": []map[string]string{
+								{"name": "util.zip", "url": host + "/util.zip"},
+								{"name": "util.tar.gz", "url": "unused", "direct_asset_url": host + "/util.tar.gz"},
+							},
+						},
+					})
+				case "/api/v4/projects/group%2Ftool/packages/generic/tool/1.2.3/util":
+					r.URL.Path = "/util"
+					files.ServeHTTP(w, r)
+				default:
+					files.ServeHTTP(w, r)
+				}
+			}),
+		)
+		t.Cleanup(srv.Close)
+		return srv, tokens
+	}
+
+	t.Run("downloads release asset matching pattern",
+		func(t *testing.T) {
+			srv, tokens := fakegitlab(t, "")
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := GitLabRelease(
+				"group/tool",
+				"{{.Name}}*.tar.gz",
+				WithGitLabURL(srv.URL),
+				WithGitLabToken("secret"),
+				WithArchiveFiles(map[string]string{"util": "util"}),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+
+			token, _ := tokens.Load("/util.tar.gz")
+			assert.Equal(t, "secret", token)
+		},
+	)
+
+	t.Run("uses job token and custom tag",
+		func(t *testing.T) {
+			srv, tokens := fakegitlab(t, "")
+			t.Setenv("GITLAB_TOKEN", "")
+			t.Setenv("CI_JOB_TOKEN", "job")
+			t.Setenv("CI_SERVER_URL", srv.URL)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := GitLabRelease(
+				"group/tool",
+				"util.zip",
+				WithReleaseTag("{{.Version}}"),
+			)
+
+			// the tag doesn't exist in the fake api
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http404")
+
+			token, _ := tokens.Load("/api/v4/projects/group/tool/releases/1.2.3")
+			assert.Equal(t, "job", token)
+		},
+	)
+
+	t.Run("doesn't send token to other hosts",
+		func(t *testing.T) {
+			assets, assettokens := fakegitlab(t, "")
+			srv, _ := fakegitlab(t, assets.URL)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := GitLabRelease("group/tool", "util.tar.gz", WithGitLabURL(srv.URL), WithGitLabToken("secret"), WithArchiveFiles(map[string]string{"util": "util"}))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+
+			token, _ := assettokens.Load("/util.tar.gz")
+			assert.Empty(t, token)
+		},
+	)
+
+	t.Run("fails when no asset matches",
+		func(t *testing.T) {
+			srv, _ := fakegitlab(t, "")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := GitLabRelease("group/tool", "util_{{.GOOS}}", WithGitLabURL(srv.URL)).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "has no asset matching util_")
+		},
+	)
+
+	t.Run("downloads file from generic package registry",
+		func(t *testing.T) {
+			srv, tokens := fakegitlab(t, "")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := GitLabPackage("group/tool", "tool", "{{.Name}}", WithGitLabURL(srv.URL), WithGitLabToken("secret"))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+
+			token, _ := tokens.Load("/api/v4/projects/group/tool/packages/generic/tool/1.2.3/util")
+			assert.Equal(t, "secret", token)
+		},
+	)
+}
@@ -0,0 +1,94 @@
+package binary
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// WithGPGVerification verifies the downloaded file against a detached gpg signature, like the
+// .asc files many projects publish next to their releases, before installing it.
+// The public key is the armored key the signature must be made with, and the signature URL can
+// contain template variables that will be resolved using the [Template] values, e.g.
+// "https://example.com/bar-{{.Version}}.tar.gz.asc".
+//
+// Signatures are verified with openpgp in-process, so gpg doesn't need to be installed;
+// both armored and binary signatures are supported.
+func WithGPGVerification(publickey, signature string) OriginOption {
+	conf := gpgconf{
+		publickey: publickey,
+		signature: signature,
+	}
+
+	return func(c *origincfg) {
+		c.verifiers = append(c.verifiers, conf.verify)
+		c.verifications = append(c.verifications, fmt.Sprintf("gpg %+v", conf))
+	}
+}
+
+type gpgconf struct {
+	publickey string
+	signature string
+}
+
+// verify checks the signature of the file downloaded from url.
+func (c gpgconf) verify(ctx context.Context, config origincfg, template Template, url, file string) error {
+	internal.LogDetail(fmt.Sprintf("verifying gpg signature of %s", url))
+
+	sigurl, err := template.Resolve(c.signature)
+	if err != nil {
+		return fmt.Errorf("failed to resolve signature URL: %w", err)
+	}
+
+	signature, err := config.fetchtemp(ctx, sigurl)
+	if err != nil {
+		return fmt.Errorf("failed to download signature: %w", err)
+	}
+	defer os.Remove(signature)
+
+	if err := gpgverify(c.publickey, signature, file); err != nil {
+		return fmt.Errorf("gpg verification failed: %w", err)
+	}
+
+	return nil
+}
+
+// gpgverify verifies the detached signature of file was made with the armored public key.
+func gpgverify(publickey, signature, file string) (err error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publickey))
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	sig, err := os.ReadFile(signature)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	signed, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer func() {
+		if closerr := signed.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close %s: %w", file, closerr))
+		}
+	}()
+
+	check := openpgp.CheckDetachedSignature
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN")) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+
+	if _, err := check(keyring, signed, bytes.NewReader(sig), nil); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package binary
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGPGVerification(t *testing.T) {
+	publickey, sign := testgpgkey(t)
+
+	data, err := testdata.ReadFile("testdata/util")
+	require.NoError(t, err)
+	signature := sign(data)
+
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/util":
+				_, _ = w.Write(data)
+			case "/util.asc":
+				_, _ = w.Write(signature)
+			case "/util.sig":
+				block, err := armor.Decode(bytes.NewReader(signature))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				_, _ = io.Copy(w, block.Body)
+			case "/tampered.asc":
+				_, _ = w.Write(append([]byte("tampered"), data...))
+			default:
+				http.NotFound(w, r)
+			}
+		}),
+	)
+	t.Cleanup(srv.Close)
+
+	t.Run("verifies binary signed with the key",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(srv.URL+"/util", WithGPGVerification(publickey, srv.URL+"/{{.Name}}.asc"))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("verifies binary signatures",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(srv.URL+"/util", WithGPGVerification(publickey, srv.URL+"/{{.Name}}.sig"))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails and removes binary on invalid signature",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(srv.URL+"/util", WithGPGVerification(publickey, srv.URL+"/tampered.asc"))
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "gpg verification failed")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails on invalid public key",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(srv.URL+"/util", WithGPGVerification("not a key", srv.URL+"/util.asc"))
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to read public key")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+}
+
+// testgpgkey generates a throwaway gpg key, returning the armored public key and a function
+// returning the armored detached signature of some data.
+func testgpgkey(t *testing.T) (string, func(data []byte) []byte) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("harness", "", "harness@example.com", nil)
+	require.NoError(t, err)
+
+	var publickey bytes.Buffer
+	writer, err := armor.Encode(&publickey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(writer))
+	require.NoError(t, writer.Close())
+
+	sign := func(data []byte) []byte {
+		t.Helper()
+		var signature bytes.Buffer
+		require.NoError(t, openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(data), nil))
+		return signature.Bytes()
+	}
+
+	return publickey.String(), sign
+}
@@ -0,0 +1,150 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+const (
+	hashicorpreleases = "https://releases.hashicorp.com"
+	hashicorpkey      = "https://www.hashicorp.com/.well-known/pgp-key.txt"
+)
+
+// hashicorpcfg holds the options of the [HashicorpRelease] origin.
+type hashicorpcfg struct {
+	mirror string
+	key    string
+}
+
+// hashicorp implements [Origin] for products released on releases.hashicorp.com.
+type hashicorp struct {
+	product string
+	config  origincfg
+}
+
+// HashicorpRelease creates a new Origin that provisions a product released on
+// releases.hashicorp.com, like terraform, vault, consul or packer.
+//
+// The archive for the current platform is verified against the SHA256SUMS file of the release,
+// whose signature is verified in turn with HashiCorp's public gpg key.
+// The key is downloaded from hashicorp.com unless it's specified via [WithHashicorpKey].
+// Use [WithHashicorpMirror] to download releases from a mirror with the same layout.
+func HashicorpRelease(product string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &hashicorp{
+		product: product,
+		config:  cfg,
+	}
+}
+
+// WithHashicorpMirror sets the URL of a mirror of releases.hashicorp.com [HashicorpRelease]
+// downloads from.
+func WithHashicorpMirror(url string) OriginOption {
+	return func(c *origincfg) {
+		c.hashicorp.mirror = url
+	}
+}
+
+// WithHashicorpKey sets the armored public key the checksums of HashiCorp releases are
+// verified with, instead of downloading it from hashicorp.com.
+func WithHashicorpKey(publickey string) OriginOption {
+	return func(c *origincfg) {
+		c.hashicorp.key = publickey
+	}
+}
+
+func (h *hashicorp) digest() any {
+	return struct {
+		Product string
+		Config  any
+	}{h.product, h.config.digest()}
+}
+
+func (h *hashicorp) Install(ctx context.Context, template Template) error {
+	if template.Version == "latest" {
+		return fmt.Errorf("hashicorp releases require a specific version")
+	}
+
+	base := hashicorpreleases
+	if h.config.hashicorp.mirror != "" {
+		base = strings.TrimSuffix(h.config.hashicorp.mirror, "/")
+	}
+
+	// the escaped base is resolved as it is, like the urls of [origincfg.install]
+	config := h.config.withprimary(template, fmt.Sprintf("{{%q}}", base))
+
+	version := strings.TrimPrefix(template.Version, "v")
+	release := fmt.Sprintf("%s/%s/%s/%s_%s", base, h.product, version, h.product, version)
+	archive := fmt.Sprintf("%s_%s_%s.zip", release, template.GOOS, template.GOARCH)
+
+	sums, err := config.fetchtemp(ctx, release+"_SHA256SUMS")
+	if err != nil {
+		return fmt.Errorf("failed to download checksums: %w", err)
+	}
+	defer os.Remove(sums)
+
+	if err := h.verifysums(ctx, config, sums, release+"_SHA256SUMS.sig"); err != nil {
+		return err
+	}
+
+	file, err := os.Open(sums)
+	if err != nil {
+		return fmt.Errorf("failed to open checksums: %w", err)
+	}
+	sum, err := findchecksum(file, artifactname(archive))
+	if closerr := file.Close(); closerr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to close checksums: %w", closerr))
+	}
+	if err != nil {
+		return fmt.Errorf("invalid checksums for %s %s: %w", h.product, version, err)
+	}
+
+	cfg := config
+	cfg.checksums = nil
+	cfg.checksumfile = ""
+	cfg.fallback = &sum
+	cfg.files = map[string]string{
+		h.product + "{{.Extension}}": "{{.Name}}{{.Extension}}",
+	}
+
+	return cfg.install(ctx, template, archive)
+}
+
+// verifysums verifies the signature of the checksums file.
+func (h *hashicorp) verifysums(ctx context.Context, config origincfg, sums, sigurl string) error {
+	key := h.config.hashicorp.key
+	if key == "" {
+		path, err := config.fetchtemp(ctx, hashicorpkey)
+		if err != nil {
+			return fmt.Errorf("failed to download hashicorp public key: %w", err)
+		}
+		defer os.Remove(path)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read hashicorp public key: %w", err)
+		}
+		key = string(data)
+	}
+
+	signature, err := config.fetchtemp(ctx, sigurl)
+	if err != nil {
+		return fmt.Errorf("failed to download checksums signature: %w", err)
+	}
+	defer os.Remove(signature)
+
+	internal.LogDetail(fmt.Sprintf("verifying gpg signature of %s checksums", h.product))
+	if err := gpgverify(key, signature, sums); err != nil {
+		return fmt.Errorf("gpg verification of checksums failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashicorpReleaseOrigin(t *testing.T) {
+	publickey, sign := testgpgkey(t)
+
+	archive, err := testdata.ReadFile("testdata/util.zip")
+	require.NoError(t, err)
+	artifact := fmt.Sprintf("util_1.2.3_%s_%s.zip", runtime.GOOS, runtime.GOARCH)
+
+	// fakereleases serves a release of util with the given checksums and signature
+	fakereleases := func(t *testing.T, sums, signature []byte) *httptest.Server {
+		t.Helper()
+		mux := http.NewServeMux()
+		mux.HandleFunc("/util/1.2.3/"+artifact, func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(archive) })
+		mux.HandleFunc("/util/1.2.3/util_1.2.3_SHA256SUMS", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(sums) })
+		mux.HandleFunc("/util/1.2.3/util_1.2.3_SHA256SUMS.sig", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(signature) })
+
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	sums := []byte(fmt.Sprintf("%s  %s\n", sha256hex(t, "testdata/util.zip"), artifact))
+
+	t.Run("installs verified release",
+		func(t *testing.T) {
+			srv := fakereleases(t, sums, sign(sums))
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "v1.2.3")
+
+			origin := HashicorpRelease("util", WithHashicorpMirror(srv.URL), WithHashicorpKey(publickey))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+		},
+	)
+
+	t.Run("fails when checksums signature is invalid",
+		func(t *testing.T) {
+			srv := fakereleases(t, sums, sign([]byte("other")))
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			err := HashicorpRelease("util", WithHashicorpMirror(srv.URL), WithHashicorpKey(publickey)).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "gpg verification of checksums failed")
+			assert.NoFileExists(t, filepath.Join(dir, "util"))
+		},
+	)
+
+	t.Run("fails when archive doesn't match checksums",
+		func(t *testing.T) {
+			tampered := []byte(fmt.Sprintf("%s  %s\n", sha256hex(t, "testdata/util"), artifact))
+			srv := fakereleases(t, tampered, sign(tampered))
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			err := HashicorpRelease("util", WithHashicorpMirror(srv.URL), WithHashicorpKey(publickey)).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+			assert.NoFileExists(t, filepath.Join(dir, "util"))
+		},
+	)
+}
@@ -0,0 +1,81 @@
+package binary
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+)
+
+// defaultclient is the client used by download origins unless one is set via [WithHTTPClient].
+var defaultclient atomic.Pointer[http.Client]
+
+// SetHTTPClient sets the client used by all download origins, e.g. one created via
+// [NewHTTPClient] for environments with proxies or internal certificate authorities.
+// The client is process-wide, and can be overridden per origin via [WithHTTPClient];
+// passing nil restores the default client.
+func SetHTTPClient(client *http.Client) {
+	defaultclient.Store(client)
+}
+
+// WithHTTPClient sets the client used by the origin for its downloads.
+func WithHTTPClient(client *http.Client) OriginOption {
+	return func(c *origincfg) {
+		c.client = client
+	}
+}
+
+// NewHTTPClient creates a client using the proxy and trusting the certificate authorities
+// in the pem encoded bundles, besides the ones of the system; this allows downloads through
+// TLS-intercepting proxies without disabling certificate verification.
+// When proxy is empty, the proxy configured via the HTTPS_PROXY, HTTP_PROXY and NO_PROXY env
+// variables is used, same as with the default client.
+func NewHTTPClient(proxy string, cabundles ...string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyurl, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %s: %w", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyurl)
+	}
+
+	if len(cabundles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		for _, bundle := range cabundles {
+			pem, err := os.ReadFile(bundle)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in ca bundle %s", bundle)
+			}
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// httpclient returns the client the origin uses for its downloads.
+func (c origincfg) httpclient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+	if client := defaultclient.Load(); client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,90 @@
+package binary
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient(t *testing.T) {
+	data, err := testdata.ReadFile("testdata/util")
+	require.NoError(t, err)
+	serve := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(data) })
+
+	srv := httptest.NewTLSServer(serve)
+	t.Cleanup(srv.Close)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, cert, 0o644))
+
+	t.Run("fails with unknown certificate authority",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "certificate")
+		},
+	)
+
+	t.Run("trusts certificate authorities in bundle",
+		func(t *testing.T) {
+			client, err := NewHTTPClient("", bundle)
+			require.NoError(t, err)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util", WithHTTPClient(client)).Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("uses process-wide client",
+		func(t *testing.T) {
+			client, err := NewHTTPClient("", bundle)
+			require.NoError(t, err)
+			SetHTTPClient(client)
+			t.Cleanup(func() { SetHTTPClient(nil) })
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl))
+		},
+	)
+
+	t.Run("downloads through proxy",
+		func(t *testing.T) {
+			var proxied string
+			proxy := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					proxied = r.URL.String()
+					serve(w, r)
+				}),
+			)
+			t.Cleanup(proxy.Close)
+
+			client, err := NewHTTPClient(proxy.URL)
+			require.NoError(t, err)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.NoError(t, RemoteBinaryDownload("http://artifacts.example.com/util", WithHTTPClient(client)).Install(t.Context(), tmpl))
+			assert.Equal(t, "http://artifacts.example.com/util", proxied)
+		},
+	)
+
+	t.Run("returns error for invalid bundle",
+		func(t *testing.T) {
+			empty := filepath.Join(t.TempDir(), "empty.pem")
+			require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+			_, err := NewHTTPClient("", empty)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no certificates found")
+		},
+	)
+}
@@ -0,0 +1,250 @@
+package binary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// LatestResolver is implemented by origins that can resolve the "latest" version of a binary
+// to a concrete one, so binaries with version "latest" are reinstalled when upstream moves.
+type LatestResolver interface {
+	LatestVersion(ctx context.Context, template Template) (string, error)
+}
+
+// errNoLatest is returned by origins that can't resolve the latest version as configured.
+var errNoLatest = errors.New("latest version can't be resolved")
+
+// DefaultLatestTTL is how long the resolution of "latest" to a concrete version is reused.
+const DefaultLatestTTL = 24 * time.Hour
+
+// WithLatestTTL sets how long the resolution of the version "latest" to a concrete version
+// is reused before checking upstream again, [DefaultLatestTTL] by default.
+// A ttl of zero checks upstream every time the binary is ensured.
+func WithLatestTTL(ttl time.Duration) Option {
+	return func(b *Binary) {
+		b.latestttl = ttl
+	}
+}
+
+// latesttimeout bounds the time spent resolving the latest version upstream.
+var latesttimeout = 10 * time.Second
+
+// latestresolution is the last resolution of "latest", cached next to the binary.
+type latestresolution struct {
+	Version  string    `json:"version"`
+	Origin   string    `json:"origin"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// latestPath returns the path of the file caching the resolution of "latest" for the binary.
+func (b *Binary) latestPath() string {
+	return filepath.Join(b.template.Directory, fmt.Sprintf(".%s.latest.json", b.template.Name))
+}
+
+// resolvelatest replaces the version "latest" with the concrete version it resolves to,
+// if the origin supports it, so the binary is verified and installed like any other version.
+// When it can't be resolved, "latest" is kept and any installed binary is trusted.
+func (b *Binary) resolvelatest(ctx context.Context) {
+	if b.version != "latest" || b.system {
+		return
+	}
+
+	resolver, ok := b.origin.(LatestResolver)
+	if !ok {
+		return
+	}
+
+	digest := origindigest(b.origin)
+
+	var cached latestresolution
+	if data, err := os.ReadFile(b.latestPath()); err == nil && json.Unmarshal(data, &cached) == nil {
+		if cached.Origin == digest && cached.Version != "" && time.Since(cached.Resolved) < b.latestttl && !forced(ctx) {
+			b.setversion(cached.Version)
+			return
+		}
+	}
+
+	if offline() {
+		if cached.Origin == digest && cached.Version != "" {
+			b.setversion(cached.Version)
+		}
+		return
+	}
+
+	// resolving is best effort, so don't hold the install back for long
+	resolvectx, cancel := context.WithTimeout(ctx, latesttimeout)
+	defer cancel()
+
+	version, err := resolver.LatestVersion(resolvectx, b.template)
+	if err != nil {
+		if !errors.Is(err, errNoLatest) {
+			internal.LogDetail(fmt.Sprintf("failed to resolve latest version of %s: %s", b.template.Name, err))
+		}
+		// an outdated resolution is better than none
+		if cached.Origin == digest && cached.Version != "" {
+			b.setversion(cached.Version)
+		}
+		return
+	}
+
+	internal.LogDetail(fmt.Sprintf("resolved latest version of %s to %s", b.template.Name, version))
+	b.setversion(version)
+
+	data, err := json.Marshal(latestresolution{Version: version, Origin: digest, Resolved: time.Now()})
+	if err == nil {
+		err = os.MkdirAll(b.template.Directory, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(b.latestPath(), data, 0o644)
+	}
+	if err != nil {
+		internal.LogDetail(fmt.Sprintf("failed to cache latest version of %s: %s", b.template.Name, err))
+	}
+}
+
+// setversion sets the version the binary is verified and installed with.
+func (b *Binary) setversion(version string) {
+	b.version = version
+	b.template.Version = version
+}
+
+// goproxy returns the url of the module proxy in GOPROXY, or the default one.
+func goproxy() (string, error) {
+	value := os.Getenv("GOPROXY")
+	if value == "" {
+		return "https://proxy.golang.org", nil
+	}
+
+	for _, proxy := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.HasPrefix(proxy, "https://") || strings.HasPrefix(proxy, "http://") {
+			return strings.TrimSuffix(proxy, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no module proxy in GOPROXY=%s", errNoLatest, value)
+}
+
+// LatestVersion resolves the latest version of the module providing the package
+// through the module proxy.
+func (o *gopkg) LatestVersion(ctx context.Context, _ Template) (string, error) {
+	proxy, err := goproxy()
+	if err != nil {
+		return "", err
+	}
+
+	// the module is the longest prefix of the package path the proxy knows about
+	for module := o.pkg; module != "." && module != "/"; module = path.Dir(module) {
+		version, found, err := o.config.proxylatest(ctx, proxy, module)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return version, nil
+		}
+	}
+
+	return "", fmt.Errorf("no module providing %s found in %s", o.pkg, proxy)
+}
+
+// proxylatest queries the latest version of the module from the proxy,
+// returning false if there's no such module.
+func (c origincfg) proxylatest(ctx context.Context, proxy, module string) (version string, found bool, err error) {
+	url := fmt.Sprintf("%s/%s/@latest", proxy, escapemodule(module))
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to query %s: %w", url, err)
+	}
+	defer func() {
+		if closerr := resp.Body.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close http response body: %w", closerr))
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return "", false, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return "", false, responseerror("latest version", url, resp)
+	}
+
+	var info struct {
+		Version string `json:"Version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", false, fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+	return info.Version, info.Version != "", nil
+}
+
+// escapemodule escapes the module path for module proxy urls,
+// where upper case letters are replaced by "!" and the lower case letter.
+func escapemodule(module string) string {
+	var escaped strings.Builder
+	for _, r := range module {
+		if unicode.IsUpper(r) {
+			escaped.WriteRune('!')
+			r = unicode.ToLower(r)
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// githubapi is the base url of the GitHub api.
+var githubapi = "https://api.github.com"
+
+// WithLatestGitHubRelease resolves the version "latest" of binaries downloaded from
+// [RemoteBinaryDownload] or [RemoteArchiveDownload] to the tag of the latest release of the
+// GitHub repository, e.g. "aevea/commitsar", without its "v" prefix.
+// Pair it with [WithBearerTokenFromEnv], allowing the token to be sent to the api,
+// e.g. WithBearerTokenFromEnv("GITHUB_TOKEN", "api.github.com"), to avoid the rate limits
+// of anonymous requests.
+func WithLatestGitHubRelease(repository string) OriginOption {
+	return func(c *origincfg) {
+		c.githubrepo = repository
+	}
+}
+
+// latestrelease resolves the latest release of the GitHub repository configured with
+// [WithLatestGitHubRelease].
+func (c origincfg) latestrelease(ctx context.Context) (string, error) {
+	if c.githubrepo == "" {
+		return "", errNoLatest
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", githubapi, c.githubrepo)
+	if err := c.getjson(ctx, url, &release); err != nil {
+		return "", err
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("latest release of %s has no tag", c.githubrepo)
+	}
+
+	return strings.TrimPrefix(release.TagName, "v"), nil
+}
+
+// LatestVersion resolves the latest version from the GitHub releases of the binary,
+// see [WithLatestGitHubRelease].
+func (r *remotebin) LatestVersion(ctx context.Context, _ Template) (string, error) {
+	return r.config.latestrelease(ctx)
+}
+
+// LatestVersion resolves the latest version from the GitHub releases of the archive,
+// see [WithLatestGitHubRelease].
+func (r *remotearchive) LatestVersion(ctx context.Context, _ Template) (string, error) {
+	return r.config.latestrelease(ctx)
+}
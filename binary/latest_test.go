@@ -0,0 +1,125 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoBinaryLatestVersion(t *testing.T) {
+	var queried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queried = append(queried, r.URL.Path)
+		if r.URL.Path != "/golang.org/x/tools/@latest" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"Version":"v0.33.0","Time":"2025-05-01T00:00:00Z"}`)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GOPROXY", srv.URL+",direct")
+
+	version, err := GoBinary("golang.org/x/tools/cmd/goimports").(LatestResolver).LatestVersion(t.Context(), Template{})
+	require.NoError(t, err)
+	assert.Equal(t, "v0.33.0", version)
+	assert.Equal(t,
+		[]string{
+			"/golang.org/x/tools/cmd/goimports/@latest",
+			"/golang.org/x/tools/cmd/@latest",
+			"/golang.org/x/tools/@latest",
+		},
+		queried,
+	)
+
+	t.Setenv("GOPROXY", "off")
+	_, err = GoBinary("golang.org/x/tools/cmd/goimports").(LatestResolver).LatestVersion(t.Context(), Template{})
+	require.ErrorIs(t, err, errNoLatest)
+}
+
+func TestEscapeModule(t *testing.T) {
+	assert.Equal(t, "github.com/!burnt!sushi/toml", escapemodule("github.com/BurntSushi/toml"))
+}
+
+func TestLatestResolution(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binary is a shell script")
+	}
+
+	content, err := testdata.ReadFile("testdata/util")
+	require.NoError(t, err)
+
+	var tag atomic.Value
+	var lookups, downloads atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/util/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		fmt.Fprintf(w, `{"tag_name":%q}`, tag.Load())
+	})
+	mux.HandleFunc("/util", func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write(content) //nolint:errcheck
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	api := githubapi
+	githubapi = srv.URL
+	t.Cleanup(func() { githubapi = api })
+
+	origin := RemoteBinaryDownload(srv.URL+"/util", WithLatestGitHubRelease("acme/util"))
+
+	t.Run("installs the resolved version and reuses the resolution",
+		func(t *testing.T) {
+			withTempDir(t)
+			lookups.Store(0)
+			downloads.Store(0)
+			tag.Store("v1.2.3")
+
+			require.NoError(t, New("util", "latest", origin).Ensure(t.Context()))
+			require.NoError(t, New("util", "latest", origin).Ensure(t.Context()))
+
+			assert.EqualValues(t, 1, lookups.Load())
+			assert.EqualValues(t, 1, downloads.Load())
+		},
+	)
+
+	t.Run("reinstalls when upstream moves",
+		func(t *testing.T) {
+			withTempDir(t)
+			lookups.Store(0)
+			downloads.Store(0)
+			tag.Store("v1.2.3")
+
+			require.NoError(t, New("util", "latest", origin, WithLatestTTL(0)).Ensure(t.Context()))
+			assert.EqualValues(t, 1, downloads.Load())
+
+			tag.Store("v1.3.0")
+			bin := New("util", "latest", origin, WithLatestTTL(0))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			assert.EqualValues(t, 2, lookups.Load())
+			assert.EqualValues(t, 2, downloads.Load())
+			assert.Equal(t, "1.3.0", bin.version)
+		},
+	)
+
+	t.Run("keeps latest when it can't be resolved",
+		func(t *testing.T) {
+			withTempDir(t)
+			downloads.Store(0)
+
+			bin := New("util", "latest", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, bin.Ensure(t.Context()))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			assert.Equal(t, "latest", bin.version)
+			assert.EqualValues(t, 1, downloads.Load())
+		},
+	)
+}
@@ -0,0 +1,114 @@
+package binary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Installed describes a binary provisioned into a bin directory.
+type Installed struct {
+	// name the binary was provisioned with
+	Name string
+	// qualified path to the binary
+	Path string
+	// version the binary was verified to have, which can be "latest"
+	Version string
+	// kind of origin the binary was provisioned from, e.g. "remotebin" or "gopkg";
+	// empty for binaries provisioned before it was recorded
+	Origin string
+	// size of the binary in bytes, or of all the files of assets that are directories
+	Size int64
+	// when the binary was installed; zero if unknown
+	Installed time.Time
+	// when the binary was last ensured
+	LastUsed time.Time
+}
+
+// List returns the binaries provisioned into the bin directory, sorted by name.
+// Binaries are found through the install receipts recorded next to them, so binaries that
+// were removed or never verified aren't listed. If dir is empty, the default bin directory
+// is used, ./bin unless overridden in the harness.yaml config file.
+func List(dir string) ([]Installed, error) {
+	if dir == "" {
+		dir = defaultbindir()
+	}
+
+	receipts, err := filepath.Glob(filepath.Join(dir, ".*.receipt.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list receipts in %s: %w", dir, err)
+	}
+
+	installed := make([]Installed, 0, len(receipts))
+	for _, path := range receipts {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read receipt %s: %w", path, err)
+		}
+		var rcpt receipt
+		if err := json.Unmarshal(data, &rcpt); err != nil {
+			return nil, fmt.Errorf("invalid receipt %s: %w", path, err)
+		}
+
+		file := rcpt.File
+		if file == "" {
+			file = rcpt.Tool
+		}
+		size, err := diskusage(filepath.Join(dir, file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		used, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat receipt %s: %w", path, err)
+		}
+
+		installed = append(installed,
+			Installed{
+				Name:      rcpt.Tool,
+				Path:      filepath.Join(dir, file),
+				Version:   rcpt.Version,
+				Origin:    rcpt.Source,
+				Size:      size,
+				Installed: rcpt.Installed,
+				LastUsed:  used.ModTime(),
+			},
+		)
+	}
+
+	slices.SortFunc(installed, func(a, b Installed) int { return strings.Compare(a.Name, b.Name) })
+	return installed, nil
+}
+
+// diskusage returns the size of the file, or of all the files in it if it's a directory.
+func diskusage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path,
+		func(_ string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.Type().IsRegular() {
+				info, err := entry.Info()
+				if err != nil {
+					return err
+				}
+				size += info.Size()
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute size of %s: %w", path, err)
+	}
+	return size, nil
+}
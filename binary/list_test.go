@@ -0,0 +1,86 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	t.Run("lists binaries with valid receipts",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin)).Install(t.Context()))
+			require.NoError(t, New("another", "2.0.0", new(fakeorigin)).Install(t.Context()))
+			// binaries without receipts aren't listed
+			require.NoError(t, os.WriteFile(filepath.Join("bin", "stray"), []byte("stray"), 0o755))
+
+			installed, err := List("")
+			require.NoError(t, err)
+			require.Len(t, installed, 2)
+
+			assert.Equal(t, "another", installed[0].Name)
+			assert.Equal(t, "2.0.0", installed[0].Version)
+			assert.Equal(t, "util", installed[1].Name)
+			assert.Equal(t, "1.0.0", installed[1].Version)
+			assert.Equal(t, "fakeorigin", installed[1].Origin)
+			assert.Equal(t, New("util", "1.0.0", new(fakeorigin)).BinPath(), installed[1].Path)
+
+			info, err := os.Stat(installed[1].Path)
+			require.NoError(t, err)
+			assert.Equal(t, info.Size(), installed[1].Size)
+			assert.WithinDuration(t, time.Now(), installed[1].Installed, time.Minute)
+		},
+	)
+
+	t.Run("ensuring updates the last used time",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", new(fakeorigin))
+			require.NoError(t, bin.Install(t.Context()))
+
+			past := time.Now().Add(-48 * time.Hour)
+			require.NoError(t, os.Chtimes(bin.receiptPath(), past, past))
+
+			installed, err := List("")
+			require.NoError(t, err)
+			require.Len(t, installed, 1)
+			assert.WithinDuration(t, past, installed[0].LastUsed, time.Second)
+
+			require.NoError(t, bin.Ensure(t.Context()))
+			installed, err = List("")
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now(), installed[0].LastUsed, time.Minute)
+		},
+	)
+
+	t.Run("skips removed binaries",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", new(fakeorigin))
+			require.NoError(t, bin.Install(t.Context()))
+			require.NoError(t, os.Remove(bin.BinPath()))
+
+			installed, err := List("")
+			require.NoError(t, err)
+			assert.Empty(t, installed)
+		},
+	)
+
+	t.Run("empty when there's no bin directory",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			installed, err := List("")
+			require.NoError(t, err)
+			assert.Empty(t, installed)
+		},
+	)
+}
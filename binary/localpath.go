@@ -0,0 +1,163 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// localpath implements [Origin] for binaries already present on the filesystem.
+type localpath struct {
+	pathformat string
+	config     origincfg
+}
+
+// LocalPath creates a new Origin that installs a binary already present in the repository or
+// elsewhere on the filesystem, like vendored tools or the ones available in air-gapped
+// environments. The path can contain template variables that will be resolved using the
+// [Template] values during installation.
+// e.g. "tools/prebuilt/{{.GOOS}}_{{.GOARCH}}/{{.Name}}{{.Extension}}"
+//
+// The file is copied into the bin directory, or symlinked when [WithSymlink] is passed, unless
+// it's an archive, in which case the files to extract are specified via [WithArchiveFiles].
+//
+// Pass [WithChecksum] or [WithChecksums] to verify the file against a known hash.
+func LocalPath(path string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &localpath{
+		pathformat: path,
+		config:     cfg,
+	}
+}
+
+// WithSymlink makes [LocalPath] symlink the binary into the bin directory instead of copying it.
+func WithSymlink() OriginOption {
+	return func(c *origincfg) {
+		c.symlink = true
+	}
+}
+
+func (l *localpath) digest() any {
+	return struct {
+		Path   string
+		Config any
+	}{l.pathformat, l.config.digest()}
+}
+
+func (l *localpath) Install(ctx context.Context, template Template) (err error) {
+	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
+	}
+
+	source, err := template.Resolve(l.pathformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	source, err = filepath.Abs(filepath.FromSlash(source))
+	if err != nil {
+		return fmt.Errorf("failed to resolve path %s: %w", source, err)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", source, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", source)
+	}
+
+	sum, err := l.config.checksum(ctx, template, source)
+	if err != nil {
+		return err
+	}
+	if sum != nil {
+		if err := crcfile(source, *sum); err != nil {
+			return err
+		}
+	}
+
+	if err := l.config.verify(ctx, template, source, source); err != nil {
+		return err
+	}
+
+	if l.config.files != nil {
+		// extraction removes the archive, so extract a copy
+		archive := filepath.Join(template.Directory, filepath.Base(source))
+		if err := copyfile(source, archive, 0o644); err != nil {
+			return err
+		}
+		return unarchive(template, archive, l.config.files, l.config.executable)
+	}
+
+	if l.config.symlink {
+		internal.LogStep(fmt.Sprintf("linking %s", source))
+		return relink(source, template.Cmd)
+	}
+
+	internal.LogStep(fmt.Sprintf("copying %s", source))
+	return copyfile(source, template.Cmd, template.FileMode())
+}
+
+// relink links the executable at destination, replacing any previous install.
+func relink(executable, destination string) error {
+	if err := os.Remove(destination); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove previous install %s: %w", destination, err)
+	}
+
+	if err := os.Symlink(executable, destination); err != nil {
+		return fmt.Errorf("failed to link %s: %w", executable, err)
+	}
+	return nil
+}
+
+// copyfile copies the file at source to destination with the given permissions.
+// The file is copied into a temporary file next to destination which is then renamed,
+// so destination is never left with partial contents.
+func copyfile(source, destination string, mode os.FileMode) (err error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer func() {
+		if closerr := in.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", source, closerr))
+		}
+	}()
+
+	out, err := os.CreateTemp(filepath.Dir(destination), fmt.Sprintf(".%s.tmp-", filepath.Base(destination)))
+	if err != nil {
+		return fmt.Errorf("failed to create output file %s: %w", destination, err)
+	}
+	defer func() {
+		_ = out.Close()
+		if err != nil {
+			_ = os.Remove(out.Name())
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", source, destination, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", out.Name(), err)
+	}
+
+	if err := os.Chmod(out.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", destination, err)
+	}
+
+	if err := os.Rename(out.Name(), destination); err != nil {
+		return fmt.Errorf("failed to move file to %s: %w", destination, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalPathOrigin(t *testing.T) {
+	t.Run("copies binary into bin directory",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := LocalPath("testdata/{{.Name}}", WithChecksum("sha256:"+sha256hex(t, "testdata/util")))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+
+			info, err := os.Lstat(tmpl.Cmd)
+			require.NoError(t, err)
+			assert.True(t, info.Mode().IsRegular())
+			if runtime.GOOS != "windows" {
+				assert.NotZero(t, info.Mode().Perm()&0o111)
+			}
+		},
+	)
+
+	t.Run("symlinks binary into bin directory",
+		func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("symlinks require privileges on windows")
+			}
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.NoError(t, LocalPath("testdata/util", WithSymlink()).Install(t.Context(), tmpl))
+
+			target, err := os.Readlink(tmpl.Cmd)
+			require.NoError(t, err)
+			expected, err := filepath.Abs("testdata/util")
+			require.NoError(t, err)
+			assert.Equal(t, expected, target)
+
+			// installing again replaces the link
+			require.NoError(t, LocalPath("testdata/util", WithSymlink()).Install(t.Context(), tmpl))
+		},
+	)
+
+	t.Run("extracts archive without removing it",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			require.NoError(t, LocalPath("testdata/util.tar.gz", WithArchiveFiles(map[string]string{"util": "util"})).Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+			assert.FileExists(t, "testdata/util.tar.gz")
+		},
+	)
+
+	t.Run("fails on checksum mismatch",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := LocalPath("testdata/util", WithChecksum("sha256:"+sha256hex(t, "testdata/util.zip"))).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails when file doesn't exist",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := LocalPath("testdata/{{.GOOS}}/util").Install(t.Context(), tmpl)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		},
+	)
+}
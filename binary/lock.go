@@ -0,0 +1,75 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// lockpoll is how often a lock held by someone else is checked while waiting for it.
+var lockpoll = 100 * time.Millisecond
+
+// lock acquires an exclusive advisory lock on the file at path, creating it if needed,
+// waiting for other processes holding it to release it or for the context to be cancelled.
+// Returns a function that releases the lock.
+func lock(ctx context.Context, path string) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	for waiting := false; ; waiting = true {
+		locked, err := trylockfile(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+
+		if !waiting {
+			internal.LogDetail(fmt.Sprintf("waiting for lock %s held by another install", path))
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, ctx.Err())
+		case <-time.After(lockpoll):
+		}
+	}
+
+	return func() error {
+		return errors.Join(unlockfile(file), file.Close())
+	}, nil
+}
+
+// lockPath returns the path of the lock file that serializes installs of the binary.
+func (b *Binary) lockPath() string {
+	return filepath.Join(b.template.Directory, fmt.Sprintf(".%s.lock", b.template.Name))
+}
+
+// withlock runs fn while holding the install lock of the binary.
+func (b *Binary) withlock(ctx context.Context, fn func() error) (err error) {
+	unlock, err := lock(ctx, b.lockPath())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockerr := unlock(); unlockerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release lock %s: %w", b.lockPath(), unlockerr))
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package binary
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// trylockfile acquires an exclusive lock on the file if it's available,
+// returning false if it's held by someone else.
+func trylockfile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unlockfile releases the lock held on the file.
+func unlockfile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,101 @@
+package binary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentEnsure(t *testing.T) {
+	withTempDir(t)
+
+	origin := new(sloworigin)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Go(func() {
+			errs[i] = New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck)).Ensure(t.Context())
+		})
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, origin.installs.Load(), "binary should have been installed only once")
+	assert.EqualValues(t, 1, origin.peak.Load(), "installs shouldn't have run concurrently")
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".util.lock")
+
+	unlock, err := lock(t.Context(), path)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := lock(t.Context(), path)
+		assert.NoError(t, err)
+		close(acquired)
+		assert.NoError(t, unlock())
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock was acquired while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, unlock())
+	<-acquired
+}
+
+func TestLockCancellation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".util.lock")
+
+	unlock, err := lock(t.Context(), path)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, unlock()) })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = lock(ctx, path)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// sloworigin is an Origin that takes a while to install, recording how many
+// installs happened and the peak of concurrent installs.
+type sloworigin struct {
+	installs atomic.Int32
+	running  atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *sloworigin) Install(_ context.Context, tmpl Template) error {
+	s.installs.Add(1)
+	running := s.running.Add(1)
+	defer s.running.Add(-1)
+
+	for {
+		peak := s.peak.Load()
+		if running <= peak || s.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := os.MkdirAll(tmpl.Directory, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(tmpl.Cmd, []byte("slow"), 0o755)
+}
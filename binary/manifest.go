@@ -0,0 +1,243 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifest is the declarative list of tools read by [FromManifest].
+type manifest struct {
+	Tools []manifesttool `yaml:"tools"`
+}
+
+// manifesttool describes a single binary, or asset, in a tools manifest.
+type manifesttool struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Origin is one of "go", "binary" or "archive".
+	Origin string `yaml:"origin"`
+	// Package installed via go install, for the "go" origin.
+	Package string `yaml:"package"`
+	// URL template of the binary or archive to download.
+	URL string `yaml:"url"`
+	// FallbackURLs tried in order if downloading from the main URL fails, see [WithFallbackURLs].
+	FallbackURLs []string `yaml:"fallback_urls"`
+	// Files to extract from the archive, mapping archive paths to names in the bin directory.
+	Files map[string]string `yaml:"files"`
+	// Checksum of the downloaded file on every platform, e.g. "sha256:abc...".
+	Checksum string `yaml:"checksum"`
+	// Checksums of the downloaded file keyed by platform, e.g. "linux/amd64": "sha256:abc...".
+	Checksums map[string]string `yaml:"checksums"`
+	// ChecksumFile is the URL template of a checksums file listing the downloaded file,
+	// see [WithChecksumFile].
+	ChecksumFile string `yaml:"checksum_file"`
+	// Asset provisions a non-executable file instead of a binary, see [NewAsset].
+	Asset bool `yaml:"asset"`
+	// VersionCmd is the format of the command that outputs the version, see [WithVersionCmd].
+	VersionCmd *string `yaml:"versioncmd"`
+	// Vars are custom fields available to url templates, see [WithTemplateVar].
+	Vars map[string]string `yaml:"vars"`
+
+	Mappings struct {
+		GOOS             map[string]string `yaml:"goos"`
+		GOARCH           map[string]string `yaml:"goarch"`
+		ArchiveExtension map[string]string `yaml:"archive_extension"`
+		GOARM            map[string]string `yaml:"goarm"`
+		Libc             map[string]string `yaml:"libc"`
+	} `yaml:"mappings"`
+}
+
+// FromManifest reads a declarative list of tools from a yaml file and returns the binaries
+// it describes, ready to be passed to commons.Provision.
+// Keeping versions in a manifest instead of Go code allows tools like Renovate or Dependabot
+// to keep them up to date.
+//
+// example manifest
+//
+//	tools:
+//	  - name: goimports
+//	    version: 0.28.0
+//	    origin: go
+//	    package: golang.org/x/tools/cmd/goimports
+//	    versioncmd: ""
+//	  - name: commitsar
+//	    version: 0.20.1
+//	    origin: archive
+//	    url: https://github.com/aevea/commitsar/releases/download/v{{.Version}}/commitsar_{{.Version}}_{{.GOOS}}_{{.GOARCH}}{{.ArchiveExtension}}
+//	    files:
+//	      commitsar: commitsar
+//	    checksum_file: https://github.com/aevea/commitsar/releases/download/v{{.Version}}/checksums.txt
+//	  - name: jq
+//	    version: 1.7.1
+//	    origin: binary
+//	    url: https://github.com/jqlang/jq/releases/download/jq-{{.Version}}/jq-{{.GOOS}}-{{.GOARCH}}
+//	    checksums:
+//	      linux/amd64: sha256:5942c9b0934e510ee61eb3e30273f1b3fe2590df93933a93d7c58b81d19c8ff5
+//	    mappings:
+//	      goos:
+//	        darwin: macos
+//
+// The options passed are applied to every binary, after the ones derived from the manifest.
+func FromManifest(path string, options ...Option) ([]*Binary, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tools manifest: %w", err)
+	}
+
+	var m manifest
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse tools manifest %s: %w", path, err)
+	}
+
+	binaries := make([]*Binary, 0, len(m.Tools))
+	seen := make(map[string]bool, len(m.Tools))
+	for i, tool := range m.Tools {
+		if tool.Name == "" {
+			return nil, fmt.Errorf("invalid tools manifest %s: tool %d has no name", path, i+1)
+		}
+		if seen[tool.Name] {
+			return nil, fmt.Errorf("invalid tools manifest %s: tool %s is defined more than once", path, tool.Name)
+		}
+		seen[tool.Name] = true
+
+		bin, err := tool.binary(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tools manifest %s: tool %s: %w", path, tool.Name, err)
+		}
+		binaries = append(binaries, bin)
+	}
+
+	return binaries, nil
+}
+
+// binary builds the [Binary] described by the manifest entry.
+func (t manifesttool) binary(options []Option) (*Binary, error) {
+	if t.Version == "" {
+		return nil, fmt.Errorf("version must be set")
+	}
+
+	origin, err := t.origin()
+	if err != nil {
+		return nil, err
+	}
+
+	// the archive extension mapping is keyed by GOOS, so it's applied before GOOS is remapped
+	var opts []Option
+	if t.Mappings.ArchiveExtension != nil {
+		opts = append(opts, WithGOOSArchiveExtensionMapping(t.Mappings.ArchiveExtension))
+	}
+	if t.Mappings.GOOS != nil {
+		opts = append(opts, WithGOOSMapping(t.Mappings.GOOS))
+	}
+	if t.Mappings.GOARCH != nil {
+		opts = append(opts, WithGOARCHMapping(t.Mappings.GOARCH))
+	}
+	if t.Mappings.GOARM != nil {
+		opts = append(opts, WithGOARMMapping(t.Mappings.GOARM))
+	}
+	if t.Mappings.Libc != nil {
+		opts = append(opts, WithLibcMapping(t.Mappings.Libc))
+	}
+	if t.VersionCmd != nil {
+		if t.Asset {
+			return nil, fmt.Errorf("assets have no version command")
+		}
+		opts = append(opts, WithVersionCmd(*t.VersionCmd))
+	}
+	for key, value := range t.Vars {
+		opts = append(opts, WithTemplateVar(key, value))
+	}
+	opts = append(opts, options...)
+
+	if t.Asset {
+		return NewAsset(t.Name, t.Version, origin, opts...), nil
+	}
+	return New(t.Name, t.Version, origin, opts...), nil
+}
+
+// origin builds the [Origin] described by the manifest entry.
+func (t manifesttool) origin() (Origin, error) {
+	options, err := t.originoptions()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Origin {
+	case "go":
+		if t.Package == "" {
+			return nil, fmt.Errorf("package must be set for the go origin")
+		}
+		if len(options) > 0 {
+			return nil, fmt.Errorf("checksums and fallback urls aren't supported by the go origin")
+		}
+		return GoBinary(t.Package), nil
+
+	case "binary":
+		if t.URL == "" {
+			return nil, fmt.Errorf("url must be set for the binary origin")
+		}
+		return RemoteBinaryDownload(t.URL, options...), nil
+
+	case "archive":
+		if t.URL == "" {
+			return nil, fmt.Errorf("url must be set for the archive origin")
+		}
+		if len(t.Files) == 0 {
+			return nil, fmt.Errorf("files must be set for the archive origin")
+		}
+		return RemoteArchiveDownload(t.URL, t.Files, options...), nil
+
+	case "":
+		return nil, fmt.Errorf("origin must be set")
+
+	default:
+		return nil, fmt.Errorf("unknown origin %q; expected go, binary or archive", t.Origin)
+	}
+}
+
+// originoptions builds the options of the download origins described by the manifest entry.
+func (t manifesttool) originoptions() ([]OriginOption, error) {
+	var options []OriginOption
+
+	if t.Checksum != "" {
+		if _, err := ParseChecksum(t.Checksum); err != nil {
+			return nil, err
+		}
+		options = append(options, WithChecksum(t.Checksum))
+	}
+
+	if len(t.Checksums) > 0 {
+		checksums := make(map[Platform]Checksum, len(t.Checksums))
+		for platform, checksum := range t.Checksums {
+			goos, goarch, ok := strings.Cut(platform, "/")
+			if !ok || goos == "" || goarch == "" {
+				return nil, fmt.Errorf("invalid checksum platform %q: expected os/arch", platform)
+			}
+
+			sum, err := ParseChecksum(checksum)
+			if err != nil {
+				return nil, err
+			}
+			checksums[Platform{OS: goos, Arch: goarch}] = sum
+		}
+		options = append(options, WithChecksums(checksums))
+	}
+
+	if t.ChecksumFile != "" {
+		options = append(options, WithChecksumFile(t.ChecksumFile))
+	}
+
+	if len(t.FallbackURLs) > 0 {
+		options = append(options, WithFallbackURLs(t.FallbackURLs...))
+	}
+
+	return options, nil
+}
@@ -0,0 +1,157 @@
+package binary
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromManifest(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "tools.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("parses all origins",
+		func(t *testing.T) {
+			path := write(t, `
+tools:
+  - name: goimports
+    version: 0.28.0
+    origin: go
+    package: golang.org/x/tools/cmd/goimports
+    versioncmd: ""
+  - name: util
+    version: 1.2.3
+    origin: archive
+    url: https://example.com/{{.Vars.channel}}/util_{{.GOOS}}{{.ArchiveExtension}}
+    files:
+      util: util
+    vars:
+      channel: stable
+    mappings:
+      goos:
+        `+runtime.GOOS+`: custom
+      archive_extension:
+        `+runtime.GOOS+`: .zip
+      libc:
+        "": none
+        gnu: none
+        musl: none
+  - name: schema.json
+    version: 2.0.0
+    origin: binary
+    url: https://example.com/schema.json
+    asset: true
+`)
+
+			binaries, err := FromManifest(path)
+			require.NoError(t, err)
+			require.Len(t, binaries, 3)
+
+			assert.Equal(t, "goimports", binaries[0].Name())
+			assert.Equal(t, "0.28.0", binaries[0].version)
+			assert.Equal(t, &gopkg{pkg: "golang.org/x/tools/cmd/goimports"}, binaries[0].origin)
+			assert.Equal(t, SkipVersionCheck, binaries[0].versioncmd)
+
+			assert.Equal(t, "util", binaries[1].Name())
+			assert.IsType(t, &remotearchive{}, binaries[1].origin)
+			assert.Equal(t, "custom", binaries[1].template.GOOS)
+			assert.Equal(t, ".zip", binaries[1].template.ArchiveExtension)
+			assert.Equal(t, map[string]string{"channel": "stable"}, binaries[1].template.Vars)
+			assert.Equal(t, "none", binaries[1].template.Libc)
+
+			assert.Equal(t, "schema.json", binaries[2].Name())
+			assert.IsType(t, &remotebin{}, binaries[2].origin)
+			assert.True(t, binaries[2].asset)
+		},
+	)
+
+	t.Run("applies options to every binary",
+		func(t *testing.T) {
+			path := write(t, `
+tools:
+  - name: util
+    version: 1.2.3
+    origin: binary
+    url: https://example.com/util
+`)
+
+			binaries, err := FromManifest(path, WithVersionCmd("%s version"))
+			require.NoError(t, err)
+			require.Len(t, binaries, 1)
+			assert.Equal(t, binaries[0].BinPath()+" version", binaries[0].versioncmd)
+		},
+	)
+
+	t.Run("parses download options",
+		func(t *testing.T) {
+			path := write(t, `
+tools:
+  - name: util
+    version: 1.2.3
+    origin: binary
+    url: https://example.com/util
+    checksum: sha256:`+strings.Repeat("0", 64)+`
+    checksums:
+      linux/amd64: sha512:`+strings.Repeat("1", 128)+`
+    checksum_file: https://example.com/checksums.txt
+    fallback_urls:
+      - https://mirror.example.com/util
+`)
+
+			binaries, err := FromManifest(path)
+			require.NoError(t, err)
+			require.Len(t, binaries, 1)
+
+			origin, ok := binaries[0].origin.(*remotebin)
+			require.True(t, ok)
+			assert.Equal(t, &Checksum{Algorithm: crypto.SHA256, Value: strings.Repeat("0", 64)}, origin.config.fallback)
+			assert.Equal(
+				t,
+				map[Platform]Checksum{{OS: "linux", Arch: "amd64"}: {Algorithm: crypto.SHA512, Value: strings.Repeat("1", 128)}},
+				origin.config.checksums,
+			)
+			assert.Equal(t, "https://example.com/checksums.txt", origin.config.checksumfile)
+			assert.Equal(t, []string{"https://mirror.example.com/util"}, origin.config.fallbacks)
+		},
+	)
+
+	t.Run("returns error for invalid manifests",
+		func(t *testing.T) {
+			tests := map[string]string{
+				"missing name":      "tools:\n  - version: 1.0.0\n    origin: go\n    package: foo\n",
+				"missing version":   "tools:\n  - name: foo\n    origin: go\n    package: foo\n",
+				"unknown origin":    "tools:\n  - name: foo\n    version: 1.0.0\n    origin: brew\n",
+				"missing url":       "tools:\n  - name: foo\n    version: 1.0.0\n    origin: binary\n",
+				"missing files":     "tools:\n  - name: foo\n    version: 1.0.0\n    origin: archive\n    url: https://example.com\n",
+				"duplicated tool":   "tools:\n  - name: foo\n    version: 1.0.0\n    origin: go\n    package: foo\n  - name: foo\n    version: 1.0.0\n    origin: go\n    package: foo\n",
+				"invalid checksum":  "tools:\n  - name: foo\n    version: 1.0.0\n    origin: binary\n    url: https://example.com\n    checksum: sha256:foo\n",
+				"invalid platform":  "tools:\n  - name: foo\n    version: 1.0.0\n    origin: binary\n    url: https://example.com\n    checksums:\n      linux: sha256:foo\n",
+				"go with checksums": "tools:\n  - name: foo\n    version: 1.0.0\n    origin: go\n    package: foo\n    checksum: sha256:" + strings.Repeat("0", 64) + "\n",
+				"unknown field":     "tools:\n  - name: foo\n    verison: 1.0.0\n",
+			}
+
+			for name, content := range tests {
+				t.Run(name, func(t *testing.T) {
+					_, err := FromManifest(write(t, content))
+					assert.Error(t, err)
+				})
+			}
+		},
+	)
+
+	t.Run("returns error for missing manifest",
+		func(t *testing.T) {
+			_, err := FromManifest(filepath.Join(t.TempDir(), "tools.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		},
+	)
+}
@@ -0,0 +1,67 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// npmpkg implements [Origin] for executables of node packages.
+type npmpkg struct {
+	pkg string
+	bin string
+}
+
+// NpmPackage creates a new Origin that installs a node package with npm, exposing one of
+// its executables as the binary, e.g. NpmPackage("@biomejs/biome", "biome").
+//
+// The package is installed into its own node_modules inside the bin directory, isolated from
+// the node_modules of the project, and the executable is linked into the bin directory.
+// npm needs to be in PATH.
+func NpmPackage(pkg, bin string) Origin {
+	return &npmpkg{
+		pkg: pkg,
+		bin: bin,
+	}
+}
+
+func (n *npmpkg) digest() any {
+	return struct{ Package, Bin string }{n.pkg, n.bin}
+}
+
+func (n *npmpkg) Install(ctx context.Context, template Template) error {
+	prefix, err := filepath.Abs(filepath.Join(template.Directory, ".npm", template.Name))
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir %s: %w", template.Directory, err)
+	}
+
+	if err := os.MkdirAll(prefix, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", prefix, err)
+	}
+
+	internal.LogStep(fmt.Sprintf("installing %s@%s", n.pkg, template.Version))
+	err = runcommand(
+		ctx,
+		"npm", "install",
+		"--prefix", prefix,
+		"--no-save", "--no-package-lock", "--no-audit", "--no-fund",
+		fmt.Sprintf("%s@%s", n.pkg, template.Version),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to install package: %w", err)
+	}
+
+	executable := filepath.Join(prefix, "node_modules", ".bin", n.bin)
+	if runtime.GOOS == "windows" {
+		executable += ".cmd"
+	}
+	if _, err := os.Stat(executable); err != nil {
+		return fmt.Errorf("package %s has no executable %s: %w", n.pkg, n.bin, err)
+	}
+
+	return relink(executable, template.Cmd)
+}
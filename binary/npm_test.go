@@ -0,0 +1,63 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNpmPackageOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake npm is a shell script")
+	}
+
+	// fake npm creates the executable of the package in the prefix it's called with
+	// and records its arguments
+	pathdir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > ` + filepath.Join(pathdir, "npm.args") + `
+while [ "$1" != "--prefix" ]; do shift; done
+mkdir -p "$2/node_modules/.bin"
+printf '#!/bin/sh\necho 3.3.3\n' > "$2/node_modules/.bin/prettier"
+chmod +x "$2/node_modules/.bin/prettier"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(pathdir, "npm"), []byte(script), 0o755))
+	t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Run("links executable of installed package",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("prettier", "3.3.3", NpmPackage("prettier", "prettier"))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			target, err := os.Readlink(bin.BinPath())
+			require.NoError(t, err)
+			assert.True(t, filepath.IsAbs(target))
+			assert.True(t, strings.HasSuffix(target, filepath.Join("node_modules", ".bin", "prettier")))
+
+			args, err := os.ReadFile(filepath.Join(pathdir, "npm.args"))
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "install --prefix ")
+			assert.Contains(t, string(args), "prettier@3.3.3")
+
+			// the version is verified through the link
+			require.NoError(t, New("prettier", "3.3.3", NpmPackage("prettier", "prettier"), WithForceVerify(true)).Ensure(t.Context()))
+		},
+	)
+
+	t.Run("fails when package has no such executable",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			err := New("fmt", "3.3.3", NpmPackage("prettier", "fmt")).Ensure(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "package prettier has no executable fmt")
+		},
+	)
+}
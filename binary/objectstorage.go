@@ -0,0 +1,117 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// objectstorage implements [Origin] for files stored in cloud object storage buckets.
+type objectstorage struct {
+	urlformat string
+	config    origincfg
+}
+
+// ObjectStorage creates a new Origin that downloads a file from an S3 or GCS bucket, given its
+// s3:// or gs:// URL. The URL can contain template variables that will be resolved using the
+// [Template] values during installation.
+// e.g. "s3://artifacts/tool/{{.Version}}/tool_{{.GOOS}}_{{.GOARCH}}"
+//
+// Files are copied with the aws and gcloud cli respectively, so the ambient cloud credentials
+// are used, like the ones of the ci job or the user's session; the cli needs to be in PATH.
+// The file is installed as the binary unless it's an archive, in which case the files to extract
+// are specified via [WithArchiveFiles].
+//
+// Pass [WithChecksum] or [WithChecksums] to verify the downloaded file against a known hash.
+func ObjectStorage(url string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &objectstorage{
+		urlformat: url,
+		config:    cfg,
+	}
+}
+
+func (o *objectstorage) digest() any {
+	return struct {
+		URL    string
+		Config any
+	}{o.urlformat, o.config.digest()}
+}
+
+func (o *objectstorage) Install(ctx context.Context, template Template) (err error) {
+	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
+	}
+
+	location, err := template.Resolve(o.urlformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve URL: %w", err)
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid object storage url %s: %w", location, err)
+	}
+
+	var cli []string
+	switch parsed.Scheme {
+	case "s3":
+		cli = []string{"aws", "s3", "cp", "--only-show-errors"}
+	case "gs":
+		cli = []string{"gcloud", "storage", "cp"}
+	default:
+		return fmt.Errorf("unsupported object storage url %s: expected s3:// or gs://", location)
+	}
+
+	// binaries are staged next to their final path until they're verified
+	destination := filepath.Join(template.Directory, fmt.Sprintf(".%s.download", filepath.Base(template.Cmd)))
+	if o.config.files != nil {
+		destination = filepath.Join(template.Directory, artifactname(location))
+	}
+
+	// never leave a partial or unverified file behind
+	defer func() {
+		if err != nil {
+			_ = os.Remove(destination)
+		}
+	}()
+
+	internal.LogStep(fmt.Sprintf("downloading from %s", location))
+	if err := runcommand(ctx, cli[0], append(cli[1:], location, destination)...); err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+
+	sum, err := o.config.checksum(ctx, template, location)
+	if err != nil {
+		return err
+	}
+	if sum != nil {
+		if err := crcfile(destination, *sum); err != nil {
+			return err
+		}
+	}
+
+	if err := o.config.verify(ctx, template, location, destination); err != nil {
+		return err
+	}
+
+	if o.config.files != nil {
+		return unarchive(template, destination, o.config.files, o.config.executable)
+	}
+
+	if err := os.Chmod(destination, template.FileMode()); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", destination, err)
+	}
+
+	if err := os.Rename(destination, template.Cmd); err != nil {
+		return fmt.Errorf("failed to move binary to %s: %w", template.Cmd, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectStorageOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake cloud clis are shell scripts")
+	}
+
+	// fake aws and gcloud clis copy files from testdata, named after the last
+	// element of the url, recording the arguments they were called with
+	bindir := t.TempDir()
+	testdatadir, err := filepath.Abs("testdata")
+	require.NoError(t, err)
+	for _, cli := range []string{"aws", "gcloud"} {
+		script := "#!/bin/sh\n" +
+			"echo \"$@\" > " + filepath.Join(bindir, cli+".args") + "\n" +
+			"for last; do :; done\n" +
+			"src=$(eval echo \\${$(($#-1))})\n" +
+			"cp \"" + testdatadir + "/$(basename \"$src\")\" \"$last\"\n"
+		require.NoError(t, os.WriteFile(filepath.Join(bindir, cli), []byte(script), 0o755))
+	}
+	t.Setenv("PATH", bindir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Run("downloads binary from s3",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := ObjectStorage(
+				"s3://artifacts/{{.Version}}/{{.Name}}",
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+
+			args, err := os.ReadFile(filepath.Join(bindir, "aws.args"))
+			require.NoError(t, err)
+			// the binary is staged until it's verified
+			staged := filepath.Join(tmpl.Directory, ".util.download")
+			assert.Equal(t, "s3 cp --only-show-errors s3://artifacts/1.2.3/util "+staged+"\n", string(args))
+			assert.NoFileExists(t, staged)
+		},
+	)
+
+	t.Run("extracts archive from gcs",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := ObjectStorage("gs://artifacts/util.tar.gz", WithArchiveFiles(map[string]string{"util": "util"}))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+			assert.NoFileExists(t, filepath.Join(dir, "util.tar.gz"))
+
+			args, err := os.ReadFile(filepath.Join(bindir, "gcloud.args"))
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "storage cp gs://artifacts/util.tar.gz")
+		},
+	)
+
+	t.Run("fails and removes file on checksum mismatch",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := ObjectStorage("s3://artifacts/util", WithChecksum("sha256:"+sha256hex(t, "testdata/util.zip"))).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails when copy fails",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := ObjectStorage("s3://artifacts/missing").Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to download file")
+		},
+	)
+
+	t.Run("rejects other urls",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := ObjectStorage("https://example.com/util").Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "expected s3:// or gs://")
+		},
+	)
+}
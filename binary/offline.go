@@ -0,0 +1,59 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// OfflineEnv is the env variable enabling offline mode, e.g. HARNESS_OFFLINE=1.
+//
+// In offline mode binaries that are already present are used as they are, while ensuring
+// binaries that would have to be downloaded fails right away with [ErrOffline], so air-gapped
+// builds behave deterministically. Binaries from [LocalPath], [System] or found in the shared
+// cache are still provisioned, and "latest" is resolved from the last known resolution.
+const OfflineEnv = "HARNESS_OFFLINE"
+
+// ErrOffline is returned when provisioning a binary would require network access in offline mode.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+// offline returns true if offline mode is enabled with the HARNESS_OFFLINE env variable.
+func offline() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(OfflineEnv))
+	return enabled
+}
+
+// checkoffline returns an error if offline mode is enabled and installing the binary
+// would require network access.
+func (b *Binary) checkoffline(ctx context.Context) error {
+	if !offline() || b.installsoffline(ctx) {
+		return nil
+	}
+
+	if b.isInstalled() {
+		return fmt.Errorf("%w: %w: %s is installed but doesn't match version %s, and can't be updated (%s is set)", ErrOffline, ErrVersionMismatch, b.template.Name, b.version, OfflineEnv)
+	}
+	return fmt.Errorf("%w: %s %s isn't installed and can't be downloaded (%s is set)", ErrOffline, b.template.Name, b.version, OfflineEnv)
+}
+
+// installsoffline returns true if the binary can be installed without network access.
+func (b *Binary) installsoffline(ctx context.Context) bool {
+	switch b.origin.(type) {
+	case *localpath, *system:
+		return true
+	}
+
+	// forced installs bypass the shared cache
+	if !b.cacheable() || forced(ctx) {
+		return false
+	}
+	entry, err := b.cacheentry()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(entry, cachemarker))
+	return err == nil
+}
@@ -0,0 +1,92 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffline(t *testing.T) {
+	t.Run("fails fast when the binary isn't installed",
+		func(t *testing.T) {
+			withTempDir(t)
+			t.Setenv(OfflineEnv, "1")
+
+			origin := new(fakeorigin)
+			err := New("util", "1.0.0", origin).Ensure(t.Context())
+			require.ErrorIs(t, err, ErrOffline)
+			assert.Contains(t, err.Error(), "util 1.0.0 isn't installed")
+			assert.False(t, origin.installed)
+		},
+	)
+
+	t.Run("uses binaries already present",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin), WithVersionCmd(SkipVersionCheck)).Ensure(t.Context()))
+
+			t.Setenv(OfflineEnv, "true")
+			origin := new(fakeorigin)
+			require.NoError(t, New("util", "1.0.0", origin, WithVersionCmd(SkipVersionCheck)).Ensure(t.Context()))
+			assert.False(t, origin.installed)
+		},
+	)
+
+	t.Run("fails when the installed version doesn't match",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin)).Ensure(t.Context()))
+
+			t.Setenv(OfflineEnv, "1")
+			err := New("util", "2.0.0", new(fakeorigin), WithVersionCmd("%s version")).Ensure(t.Context())
+			require.ErrorIs(t, err, ErrOffline)
+			assert.Contains(t, err.Error(), "doesn't match version 2.0.0")
+		},
+	)
+
+	t.Run("installs from local paths",
+		func(t *testing.T) {
+			testdata, err := filepath.Abs("testdata")
+			require.NoError(t, err)
+			withTempDir(t)
+			t.Setenv(OfflineEnv, "1")
+
+			bin := New("util", "1.2.3", LocalPath(filepath.Join(testdata, "util")), WithVersionCmd(SkipVersionCheck))
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.FileExists(t, bin.BinPath())
+		},
+	)
+
+	t.Run("installs from the shared cache",
+		func(t *testing.T) {
+			withTempDir(t)
+			cache := t.TempDir()
+
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin), WithSharedCache(cache)).Ensure(t.Context()))
+			require.NoError(t, os.RemoveAll("bin"))
+
+			t.Setenv(OfflineEnv, "1")
+			origin := new(fakeorigin)
+			bin := New("util", "1.0.0", origin, WithSharedCache(cache))
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.False(t, origin.installed)
+			assert.FileExists(t, bin.BinPath())
+		},
+	)
+
+	t.Run("disabled by false values",
+		func(t *testing.T) {
+			withTempDir(t)
+			t.Setenv(OfflineEnv, "0")
+
+			origin := new(fakeorigin)
+			require.NoError(t, New("util", "1.0.0", origin).Ensure(t.Context()))
+			assert.True(t, origin.installed)
+		},
+	)
+}
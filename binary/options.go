@@ -2,6 +2,11 @@ package binary
 
 import (
 	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
 )
 
 type Option func(b *Binary)
@@ -36,6 +41,35 @@ func WithGOARCHMapping(mapping map[string]string) Option {
 	}
 }
 
+// WithGOARMMapping allows remapping the value of GOARM in the template
+// before triggering the installation.
+// This is useful for binaries distributed as `binname-armv7` when using the
+// `binname-arm{{ GOARM }}` template isn't enough, e.g. when armv6 builds are named `binname-armhf`.
+// The key of the map is the GOARM value and the value is the wanted
+// replacement; for the case mentioned earlier, pass {"6": "hf"}.
+func WithGOARMMapping(mapping map[string]string) Option {
+	return func(b *Binary) {
+		if replacement, ok := mapping[b.template.GOARM]; ok {
+			b.template.GOARM = replacement
+		}
+	}
+}
+
+// WithLibcMapping allows remapping the value of Libc in the template
+// before triggering the installation.
+// This is useful for binaries distributed for both C libraries on linux, like
+// `binname-x86_64-unknown-linux-musl` and `binname-x86_64-unknown-linux-gnu`, or
+// only as static musl builds, where glibc systems can use the musl build too.
+// The key of the map is the Libc value and the value is the wanted
+// replacement; for the latter case, pass {"gnu": "musl"}.
+func WithLibcMapping(mapping map[string]string) Option {
+	return func(b *Binary) {
+		if replacement, ok := mapping[b.template.Libc]; ok {
+			b.template.Libc = replacement
+		}
+	}
+}
+
 // WithGOOSArchiveExtensionMapping allows remapping the value of ArchiveExtension in the template
 // before triggering the installation.
 // This is useful for example in cases where different compression methods are used
@@ -50,6 +84,61 @@ func WithGOOSArchiveExtensionMapping(mapping map[string]string) Option {
 	}
 }
 
+// WithDirectory provisions the binary into the directory instead of the default bin directory,
+// ./bin unless overridden by the HARNESS_BIN_DIR env variable or in the harness.yaml config file.
+// A leading ~ is replaced with the home directory, and absolute paths like the one returned by
+// [UserDirectory] share the binary across repositories. It has no effect on [System] binaries.
+func WithDirectory(dir string) Option {
+	return func(b *Binary) {
+		if b.system || dir == "" {
+			return
+		}
+
+		dir = expandhome(filepath.FromSlash(dir))
+		previous := b.template.Cmd
+
+		b.directory = dir
+		b.template.Directory = dir
+		b.template.Cmd = filepath.Join(dir, filepath.Base(previous))
+
+		// version commands already formatted with the previous path
+		if rest, ok := strings.CutPrefix(b.versioncmd, previous); ok {
+			b.versioncmd = b.template.Cmd + rest
+		}
+	}
+}
+
+// WithPreInstall runs the hook before the binary is installed, with the template passed to
+// its origin, e.g. to clean up files left by previous versions.
+// If the hook fails, the binary isn't installed. Hooks run in the order they're added.
+func WithPreInstall(hook func(Template) error) Option {
+	return func(b *Binary) {
+		b.preinstall = append(b.preinstall, hook)
+	}
+}
+
+// WithPostInstall runs the hook after the binary is installed, with the template passed to
+// its origin, e.g. to install shell completions, chmod companion files or write configs.
+// Hooks only run when the binary is installed, not every time it's ensured.
+// If the hook fails, the binary is removed so the install is attempted again next time.
+func WithPostInstall(hook func(Template) error) Option {
+	return func(b *Binary) {
+		b.postinstall = append(b.postinstall, hook)
+	}
+}
+
+// WithTemplateVar sets a custom field available to the templates of the origin as
+// {{.Vars.key}}, e.g. WithTemplateVar("channel", "stable") for urls like
+// "https://example.com/{{.Vars.channel}}/{{.Version}}/util".
+func WithTemplateVar(key, value string) Option {
+	return func(b *Binary) {
+		if b.template.Vars == nil {
+			b.template.Vars = make(map[string]string)
+		}
+		b.template.Vars[key] = value
+	}
+}
+
 // WithVersionCmd allows customizing the command that is run to check the
 // version of the binary. The format string should contain a single `%s`
 // placeholder that will be replaced with the binary's command name.
@@ -67,3 +156,57 @@ func WithVersionCmd(format string) Option {
 		b.versioncmd = fmt.Sprintf(format, b.template.Cmd)
 	}
 }
+
+// WithForceVerify controls if the version command should be run on every [Binary.Ensure]
+// call, even if a valid install receipt exists for the binary.
+//
+// By default, once a binary has been provisioned and its version verified, a receipt is
+// recorded next to it and trusted on subsequent runs, as long as the binary hasn't been
+// modified and neither its version nor its origin changed.
+func WithForceVerify(enabled bool) Option {
+	return func(b *Binary) {
+		b.forceverify = enabled
+	}
+}
+
+// WithLogHandler sends all provisioning logs to the specified handler instead of the
+// default pretty output. Logging is process-wide, so this affects every binary.
+func WithLogHandler(handler slog.Handler) Option {
+	return func(_ *Binary) {
+		internal.SetLogHandler(handler)
+	}
+}
+
+// WithLogLevel sets the minimum level of the provisioning logs that are emitted.
+// Logging is process-wide, so this affects every binary.
+func WithLogLevel(level slog.Level) Option {
+	return func(_ *Binary) {
+		internal.SetLogLevel(level)
+	}
+}
+
+// WithVerbosity sets how much is shown while binaries are provisioned, e.g. harness.VerbosityQuiet.
+// Verbosity is process-wide, so this affects every binary.
+func WithVerbosity(verbosity internal.Verbosity) Option {
+	return func(_ *Binary) {
+		internal.SetVerbosity(verbosity)
+	}
+}
+
+// WithoutColor disables colored output.
+func WithoutColor() Option {
+	return func(_ *Binary) {
+		internal.DisableColor()
+	}
+}
+
+// WithPlainOutput disables colors along with the live status, progress reports and
+// progress bars, for ci systems that don't render terminal escape sequences; commands
+// are asked to avoid colors too via the NO_COLOR env variable.
+// Colors are also disabled when NO_COLOR is set, and escape sequences on dumb terminals.
+// Output is process-wide, so this affects every binary.
+func WithPlainOutput() Option {
+	return func(_ *Binary) {
+		internal.SetPlainOutput()
+	}
+}
@@ -1,18 +1,22 @@
 package binary
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/cheggaaa/pb/v3"
-	"github.com/fatih/color"
-
 	"github.com/aexvir/harness/internal"
 )
 
@@ -20,7 +24,8 @@ import (
 type Origin interface {
 	// Install performs the installation of a binary.
 	// The template contains information about the target environment and desired configuration.
-	Install(template Template) error
+	// Downloads and commands run during the installation must be aborted when ctx is cancelled.
+	Install(ctx context.Context, template Template) error
 }
 
 // remotebin implements [Origin] for direct binary downloads from a URL.
@@ -35,7 +40,9 @@ type remotebin struct {
 // during installation.
 // e.g. "https://github.com/foo/bar/releases/download/v{{.Version}}/bin_{{.Version}}_{{.GOOS}}_{{.GOARCH}}{{.Extension}}",
 //
-// Pass [WithChecksums] to verify the downloaded file against a known hash.
+// Pass [WithChecksum], [WithChecksums] or [WithChecksumFile] to verify the downloaded file against a known hash,
+// [WithFallbackURLs] to try other URLs if the download fails, and [WithHTTPHeader] or
+// [WithBearerTokenFromEnv] for URLs that require authentication.
 func RemoteBinaryDownload(url string, options ...OriginOption) Origin {
 	var cfg origincfg
 	for _, opt := range options {
@@ -47,75 +54,69 @@ func RemoteBinaryDownload(url string, options ...OriginOption) Origin {
 	}
 }
 
-func (r *remotebin) Install(template Template) error {
+func (r *remotebin) digest() any {
+	return struct {
+		URL    string
+		Config any
+	}{r.urlformat, r.config.digest()}
+}
+
+func (r *remotebin) Install(ctx context.Context, template Template) error {
+	config := r.config.withprimary(template, r.urlformat)
+	return config.tryurls(ctx, r.urlformat, func(urlformat string) error {
+		return r.installfrom(ctx, config, template, urlformat)
+	})
+}
+
+func (r *remotebin) installfrom(ctx context.Context, config origincfg, template Template, urlformat string) error {
 	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
 		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
 	}
 
-	url, err := template.Resolve(r.urlformat)
+	url, err := template.Resolve(urlformat)
 	if err != nil {
 		return fmt.Errorf("failed to resolve URL: %w", err)
 	}
 
-	internal.LogStep(fmt.Sprintf("downloading from %s", url))
-
-	resp, err := http.Get(url)
+	sum, err := config.checksum(ctx, template, url)
 	if err != nil {
-		return fmt.Errorf("failed to download binary: %w", err)
-	}
-	defer func() {
-		if closerr := resp.Body.Close(); closerr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to close http response body: %w", closerr))
-		}
-	}()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("received unexpected response when downloading binary: http%d", resp.StatusCode)
+		return err
 	}
 
-	data, finish := progress(resp.Body, resp.ContentLength)
-	defer finish()
-
-	var verify func() error
-	if sum, ok := r.config.checksum(template); ok {
-		verified, check, err := crcreader(data, sum)
-		if err != nil {
-			return err
-		}
-		data = verified
-		verify = check
-	}
+	internal.LogStep(fmt.Sprintf("downloading from %s", url))
 
-	out, err := os.Create(template.Cmd)
-	if err != nil {
-		return fmt.Errorf("failed to create output file %s: %w", template.Cmd, err)
+	// the binary is staged next to its final path and only moved there once it's complete
+	// and verified, so an interrupted install never leaves a broken binary behind
+	staged := filepath.Join(template.Directory, fmt.Sprintf(".%s.download", filepath.Base(template.Cmd)))
+	if err := os.Remove(staged); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove previous download %s: %w", staged, err)
 	}
 	defer func() {
-		if closerr := out.Close(); closerr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", template.Cmd, closerr))
-		}
+		_ = os.Remove(staged)
+		_ = os.Remove(downloadrecordpath(staged))
+		discardpartial(staged + ".part")
 	}()
 
-	if err := os.Chmod(template.Cmd, 0o755); err != nil {
-		return fmt.Errorf("failed to set permissions on %s: %w", template.Cmd, err)
+	if err := config.download(ctx, "binary", url, staged, sum); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(staged, template.FileMode()); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", staged, err)
 	}
 
-	if _, err := io.Copy(out, data); err != nil {
+	if err := config.verify(ctx, template, url, staged); err != nil {
 		return err
 	}
 
-	if verify != nil {
-		if err := verify(); err != nil {
-			_ = os.Remove(template.Cmd)
-			return err
-		}
+	if err := os.Rename(staged, template.Cmd); err != nil {
+		return fmt.Errorf("failed to move binary to %s: %w", template.Cmd, err)
 	}
-
 	return nil
 }
 
 // remotearchive implements Origin for downloading and extracting archived binaries.
-// It supports downloading compressed archives (tar.gz) containing multiple files
+// It supports downloading archives (zip, or tar either plain or compressed with gzip, bzip2, xz or zstd) containing multiple files
 // and selectively extracting specific binaries from them.
 type remotearchive struct {
 	urlformat string
@@ -130,6 +131,8 @@ type remotearchive struct {
 //
 // The binaries parameter maps archive paths to the desired binary names in the
 // installation directory. Only files specified in this map will be extracted.
+// Archive paths ending in "/" match every file inside that directory, which are
+// extracted under the replacement path, e.g. {"protoc/include/": "include/"}.
 // Both archive paths and binary names can contain template variables that will be resolved
 // using the [Template] values during installation.
 //
@@ -137,7 +140,22 @@ type remotearchive struct {
 // the version in the string and will extract the file under that path to a binary called simply
 // "grafana" in the root of the bin directory.
 //
-// Pass [WithChecksums] to verify the downloaded archive against a known hash.
+// Archives can also be 7z files or self-extracting windows executables with a zip or 7z payload.
+// The format is detected from the contents of the archive, falling back to its extension.
+//
+// Compressed files that aren't archives, like "tool_linux_amd64.gz", are decompressed and
+// installed under the binary name, with no need to specify binaries.
+//
+// Extracted files keep the permissions stored in the archive, falling back to 0755 for the ones
+// without any; assets are never executable. Pass [WithExecutableBinaries] to make the mapped binaries
+// executable regardless, e.g. for zip archives created on windows.
+//
+// Archives with entries using absolute paths or escaping the bin directory are rejected.
+// Symlinks are extracted only when they resolve to files extracted from the same archive.
+//
+// Pass [WithChecksum], [WithChecksums] or [WithChecksumFile] to verify the downloaded archive against a known hash,
+// [WithFallbackURLs] to try other URLs if the download fails, and [WithHTTPHeader] or
+// [WithBearerTokenFromEnv] for URLs that require authentication.
 func RemoteArchiveDownload(url string, binaries map[string]string, options ...OriginOption) Origin {
 	var cfg origincfg
 	for _, opt := range options {
@@ -150,36 +168,106 @@ func RemoteArchiveDownload(url string, binaries map[string]string, options ...Or
 	}
 }
 
-func (r *remotearchive) Install(template Template) error {
+func (r *remotearchive) digest() any {
+	return struct {
+		URL      string
+		Binaries map[string]string
+		Config   any
+	}{r.urlformat, r.binaries, r.config.digest()}
+}
+
+func (r *remotearchive) Install(ctx context.Context, template Template) error {
+	config := r.config.withprimary(template, r.urlformat)
+	return config.tryurls(ctx, r.urlformat, func(urlformat string) error {
+		return r.installfrom(ctx, config, template, urlformat)
+	})
+}
+
+func (r *remotearchive) installfrom(ctx context.Context, config origincfg, template Template, urlformat string) (err error) {
 	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
 		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
 	}
 
-	url, err := template.Resolve(r.urlformat)
+	url, err := template.Resolve(urlformat)
 	if err != nil {
 		return fmt.Errorf("failed to resolve URL: %w", err)
 	}
 
-	tmpname := filepath.Base(url)
+	archive := filepath.Join(template.Directory, filepath.Base(url))
 
-	var sum *Checksum
-	if expected, ok := r.config.checksum(template); ok {
-		sum = &expected
+	// never leave a partial or broken archive behind, as it would be picked up
+	// as a cached download on the next run; the archive itself is removed once extracted
+	defer func() {
+		if err != nil {
+			_ = os.Remove(archive)
+		}
+		_ = os.Remove(downloadrecordpath(archive))
+	}()
+
+	sum, err := config.checksum(ctx, template, url)
+	if err != nil {
+		return err
 	}
 
-	if err := download(url, filepath.Join(template.Directory, tmpname), sum); err != nil {
+	if err := config.download(ctx, "archive", url, archive, sum); err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
 
+	if err := config.verify(ctx, template, url, archive); err != nil {
+		return err
+	}
+
+	return unarchive(template, archive, r.binaries, config.executable)
+}
+
+// unarchive extracts the files of the archive specified in binaries into the bin directory,
+// the same way as [RemoteArchiveDownload]. If executable is set, the mapped binaries are made
+// executable regardless of the mode stored in the archive.
+func unarchive(template Template, archive string, binaries map[string]string, executable bool) (err error) {
 	// resolve binary mapping templates
-	mapping := make(map[string]string, len(r.binaries))
-	for path, replacement := range r.binaries {
+	mapping := make(map[string]string, len(binaries))
+	// paths the binaries are extracted to, excluding the files of mapped directories
+	mapped := map[string]bool{}
+	for path, replacement := range binaries {
 		mapping[template.MustResolve(path)] = template.MustResolve(replacement)
+		if !strings.HasSuffix(path, "/") {
+			mapped[filepath.Clean(filepath.FromSlash(template.MustResolve(replacement)))] = true
+		}
+	}
+	if len(mapping) == 0 {
+		mapped[template.Name+template.Extension] = true
+	}
+
+	// extract into a staging directory first so a failed extraction doesn't leave
+	// partial files in the bin directory
+	staging, err := os.MkdirTemp(template.Directory, ".staging-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
 	}
+	defer func() {
+		if rmerr := os.RemoveAll(staging); rmerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to remove staging directory %s: %w", staging, rmerr))
+		}
+	}()
 
-	return extract(
-		filepath.Join(template.Directory, tmpname),
-		template.Directory,
+	err = extract(
+		archive,
+		staging,
+		template.Name+template.Extension,
+		func(path string, archived os.FileMode) os.FileMode {
+			mode := archived.Perm()
+			// single compressed files and entries without permissions, like the ones of zips created on windows
+			if mode == 0 {
+				mode = template.FileMode()
+			}
+			if template.Asset {
+				return mode &^ 0o111
+			}
+			if executable && mapped[path] {
+				mode |= 0o111
+			}
+			return mode
+		},
 		func(path string) *string {
 			// if there's no file override, extract the file as is
 			if len(mapping) == 0 {
@@ -191,28 +279,96 @@ func (r *remotearchive) Install(template Template) error {
 				internal.LogDetail(fmt.Sprintf("  resolved %s to %s", path, replacement))
 				return &replacement
 			}
+
+			// or that are inside a directory present in the map
+			for prefix, replacement := range mapping {
+				if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) {
+					resolved := replacement + strings.TrimPrefix(path, prefix)
+					return &resolved
+				}
+			}
 			return nil
 		},
 	)
+	if err != nil {
+		return err
+	}
+
+	return promote(staging, template.Directory)
 }
 
 // gopkg implements Origin for installing binaries using Go's package management.
 // It provisions binaries via 'go install'.
 type gopkg struct {
-	pkg string
+	pkg    string
+	config origincfg
+}
+
+// gocfg holds the options of the go install invocation.
+type gocfg struct {
+	tags    []string
+	ldflags string
+	env     []string
 }
 
 // GoBinary creates a new Origin that installs a binary using 'go install'
 // targetting the local bin directory.
 // The pkg parameter should be a package installable using the go cli.
 // e.g. golang.org/x/tools/cmd/goimports
-func GoBinary(pkg string) Origin {
+//
+// The installed binary is renamed after the binary name when it differs from the one
+// given by go install, e.g. to install goimports as "imports".
+//
+// Pass [WithBuildTags], [WithLdflags] or [WithGoEnv] to customize the build, e.g. for tools
+// requiring build tags or cgo, or to install private modules.
+func GoBinary(pkg string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
 	return &gopkg{
-		pkg: pkg,
+		pkg:    pkg,
+		config: cfg,
+	}
+}
+
+// WithBuildTags sets the build tags [GoBinary] installs the binary with.
+func WithBuildTags(tags ...string) OriginOption {
+	return func(c *origincfg) {
+		c.golang.tags = append(c.golang.tags, tags...)
 	}
 }
 
-func (o *gopkg) Install(template Template) error {
+// WithLdflags sets the flags passed to the linker when [GoBinary] installs the binary,
+// e.g. "-s -w" or "-X main.version=1.2.3".
+func WithLdflags(flags string) OriginOption {
+	return func(c *origincfg) {
+		c.golang.ldflags = flags
+	}
+}
+
+// WithGoEnv sets an env variable for the go install run by [GoBinary], e.g. GOPRIVATE
+// and GONOSUMDB for private modules, GOFLAGS, or CGO_ENABLED for tools that need cgo.
+func WithGoEnv(key, value string) OriginOption {
+	return func(c *origincfg) {
+		c.golang.env = append(c.golang.env, key+"="+value)
+	}
+}
+
+func (o *gopkg) digest() any {
+	return struct {
+		Package string
+		Config  any
+	}{o.pkg, o.config.digest()}
+}
+
+func (o *gopkg) Install(ctx context.Context, template Template) error {
+	return goinstall(ctx, template, o.pkg, o.pkg+"@"+template.Version, o.config)
+}
+
+// goinstall installs the binary of pkg into the bin directory running go install with target,
+// either pkg at a version or pkg alone to use the version required by the current module.
+func goinstall(ctx context.Context, template Template, pkg, target string, config origincfg) error {
 	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
 		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
 	}
@@ -222,31 +378,81 @@ func (o *gopkg) Install(template Template) error {
 		return fmt.Errorf("failed to resolve dir %s: %w", template.Directory, err)
 	}
 
-	cmd := exec.Command("go", "install", o.pkg+"@"+template.Version)
-	cmd.Env = append(os.Environ(), "GOBIN="+path)
-	installcmd := fmt.Sprintf("GOBIN=%s go install %s@%s", path, o.pkg, template.Version)
+	args := []string{"install"}
+	if len(config.golang.tags) > 0 {
+		args = append(args, "-tags", strings.Join(config.golang.tags, ","))
+	}
+	if config.golang.ldflags != "" {
+		args = append(args, "-ldflags", config.golang.ldflags)
+	}
+	args = append(args, target)
+
+	// the bin directory always takes precedence
+	env := append(slices.Clone(config.golang.env), "GOBIN="+path)
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Env = append(os.Environ(), env...)
+	installcmd := fmt.Sprintf("%s go %s", strings.Join(env, " "), strings.Join(args, " "))
 	internal.LogDetail(fmt.Sprintf("running %s", installcmd))
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("unable to install executable: %w", err)
 	}
 
 	// rename if binary name is different from template
-	if currentBinaryName := filepath.Base(o.pkg); currentBinaryName != template.Name {
-		internal.LogDetail("renaming binary from " + currentBinaryName + " to " + template.Name)
-		return os.Rename(
-			fmt.Sprintf("%s/%s", path, currentBinaryName),
-			fmt.Sprintf("%s/%s", path, template.Name),
-		)
+	built := filepath.Join(path, gobinname(pkg)+template.Extension)
+	renamed := filepath.Join(path, filepath.Base(template.Cmd))
+	if built != renamed {
+		internal.LogDetail(fmt.Sprintf("renaming binary from %s to %s", filepath.Base(built), filepath.Base(renamed)))
+		if err := os.Rename(built, renamed); err != nil {
+			return fmt.Errorf("failed to rename %s to %s: %w", built, renamed, err)
+		}
 	}
 
 	return nil
 }
 
+// gobinname returns the name go install gives to the binary of pkg: the last element
+// of its path, or the one before it when the last one is a major version suffix,
+// e.g. "mycmd" for example.com/mycmd/v2.
+func gobinname(pkg string) string {
+	name := path.Base(pkg)
+	if name != pkg && ismajorversion(name) {
+		name = path.Base(path.Dir(pkg))
+	}
+	return name
+}
+
+// ismajorversion returns true for major version suffixes of module paths, from v2 on.
+func ismajorversion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, c := range elem[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// downloadattempts is the number of times a download is attempted before giving up,
+// and downloadbackoff the delay before the first retry, doubled on each attempt.
+var (
+	downloadattempts = 4
+	downloadbackoff  = time.Second
+)
+
 // download downloads a file from a URL to a local destination.
-// If the destination file already exists, the download is skipped.
+// If the destination file already exists, the download is skipped as long as it's valid,
+// see [origincfg.reusable], unless installs are forced with [WithForce].
 // When sum is non-nil, the downloaded (or cached) file is verified against it.
 // A cached file that does not match is removed and re-downloaded.
-func download(url, destination string, sum *Checksum) (err error) {
+//
+// Failed downloads are retried with exponential backoff, resuming from the data already
+// downloaded, kept in a .part file next to the destination, when the server supports ranges.
+// Partial files are only resumed for the url they were downloaded from, and only as long as
+// the file on the server didn't change since, see [partialrecord].
+func (c origincfg) download(ctx context.Context, what, url, destination string, sum *Checksum) (err error) {
 	internal.LogDetail(fmt.Sprintf("downloading %s to %s", url, destination))
 
 	start := time.Now()
@@ -256,21 +462,223 @@ func download(url, destination string, sum *Checksum) (err error) {
 	}()
 
 	if _, err := os.Stat(destination); err == nil {
-		if sum == nil {
-			return nil
-		}
-		if verr := crcfile(destination, *sum); verr == nil {
+		if !forced(ctx) && c.reusable(ctx, url, destination, sum) {
 			return nil
 		}
-		internal.LogDetail("cached file failed checksum verification, re-downloading")
 		if rmerr := os.Remove(destination); rmerr != nil {
 			return fmt.Errorf("failed to remove invalid cached file %s: %w", destination, rmerr)
 		}
 	}
 
-	resp, err := http.Get(url)
+	partial := destination + ".part"
+	backoff := downloadbackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := c.fetch(ctx, what, url, partial)
+		if err == nil {
+			break
+		}
+		if !retry || attempt == downloadattempts || ctx.Err() != nil {
+			if !retry {
+				discardpartial(partial)
+			}
+			return err
+		}
+
+		internal.LogDetail(fmt.Sprintf("download failed: %s; retrying in %s", err, backoff))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	// the hash can't be computed while streaming as downloads can be resumed
+	if sum != nil {
+		if verr := crcfile(partial, *sum); verr != nil {
+			_ = os.Remove(partial)
+			return verr
+		}
+	}
+
+	if err := os.Rename(partial, destination); err != nil {
+		return fmt.Errorf("failed to move downloaded file to %s: %w", destination, err)
+	}
+	_ = os.Remove(downloadrecordpath(partial))
+	recorddownload(url, destination)
+	return nil
+}
+
+// partialrecord is written next to partial downloads, so they're only resumed for the same
+// url, and the server is asked to send the whole file again if it changed since, via If-Range.
+type partialrecord struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validator returns the value of the If-Range header to resume the download with; only
+// strong etags are allowed there, see RFC 9110 section 13.1.5.
+func (r partialrecord) validator() string {
+	if r.ETag != "" && !strings.HasPrefix(r.ETag, "W/") {
+		return r.ETag
+	}
+	return r.LastModified
+}
+
+// resumablepartial returns the record of the partial download, or false if there's none
+// or it comes from another url, in which case the partial file can't be resumed.
+func resumablepartial(partial, url string) (partialrecord, bool) {
+	data, err := os.ReadFile(downloadrecordpath(partial))
 	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
+		return partialrecord{}, false
+	}
+
+	var record partialrecord
+	if err := json.Unmarshal(data, &record); err != nil || record.URL != url {
+		return partialrecord{}, false
+	}
+	return record, true
+}
+
+// recordpartial writes the record of the partial download of url started with the response.
+func recordpartial(partial, url string, resp *http.Response) error {
+	data, err := json.Marshal(
+		partialrecord{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")},
+	)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(downloadrecordpath(partial), data, 0o644)
+}
+
+// discardpartial removes the partial download along with its record.
+func discardpartial(partial string) {
+	_ = os.Remove(partial)
+	_ = os.Remove(downloadrecordpath(partial))
+}
+
+// downloadrecord is written next to downloaded files, so they're only reused
+// for the same url and as long as they haven't been modified.
+type downloadrecord struct {
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// downloadrecordpath returns the path of the record of the file downloaded to destination.
+func downloadrecordpath(destination string) string {
+	base := strings.TrimLeft(filepath.Base(destination), ".")
+	return filepath.Join(filepath.Dir(destination), fmt.Sprintf(".%s.download.json", base))
+}
+
+// recorddownload writes the record of the file downloaded from url to destination.
+// Records only allow reusing downloads, so failing to write one isn't fatal.
+func recorddownload(url, destination string) {
+	info, err := os.Stat(destination)
+	if err != nil {
+		return
+	}
+	sum, err := sha256file(destination)
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(downloadrecord{URL: url, Size: info.Size(), SHA256: sum})
+	if err == nil {
+		err = os.WriteFile(downloadrecordpath(destination), data, 0o644)
+	}
+	if err != nil {
+		internal.LogDetail(fmt.Sprintf("failed to record download: %s", err))
+	}
+}
+
+// reusable returns true if the file already at destination can be used instead of
+// downloading url again.
+//   - with a checksum, the file must match it
+//   - files downloaded before must come from the same url and be unmodified since
+//   - other files, like the ones placed manually, must have the size reported by the
+//     server, if it can be reached
+func (c origincfg) reusable(ctx context.Context, url, destination string, sum *Checksum) bool {
+	if sum != nil {
+		if err := crcfile(destination, *sum); err != nil {
+			internal.LogDetail("cached file failed checksum verification, re-downloading")
+			return false
+		}
+		return true
+	}
+
+	info, err := os.Stat(destination)
+	if err != nil {
+		return false
+	}
+
+	if data, err := os.ReadFile(downloadrecordpath(destination)); err == nil {
+		var record downloadrecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return false
+		}
+		if record.URL != url {
+			internal.LogDetail(fmt.Sprintf("cached file was downloaded from %s, re-downloading", record.URL))
+			return false
+		}
+		if sum, err := sha256file(destination); err != nil || record.Size != info.Size() || sum != record.SHA256 {
+			internal.LogDetail("cached file was modified, re-downloading")
+			return false
+		}
+		return true
+	}
+
+	if size, ok := c.remotesize(ctx, url); ok && size != info.Size() {
+		internal.LogDetail(fmt.Sprintf("cached file has %d bytes instead of %d, re-downloading", info.Size(), size))
+		return false
+	}
+	return true
+}
+
+// remotesize returns the size of the file at url as reported by the server,
+// or false if it can't be determined.
+func (c origincfg) remotesize(ctx context.Context, url string) (int64, bool) {
+	ctx, cancel := context.WithTimeout(ctx, probetimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, false
+	}
+	c.setheaders(req)
+
+	resp, err := c.httpclient().Do(req)
+	if err != nil {
+		return 0, false
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0, false
+	}
+	return resp.ContentLength, true
+}
+
+// fetch downloads url into the partial file, resuming from its current size when it was
+// downloaded from the same url. Returns whether the download can be retried when it fails.
+func (c origincfg) fetch(ctx context.Context, what, url, partial string) (retry bool, err error) {
+	var (
+		offset int64
+		record partialrecord
+	)
+	if info, err := os.Stat(partial); err == nil {
+		if existing, ok := resumablepartial(partial, url); ok {
+			offset, record = info.Size(), existing
+		} else {
+			internal.LogDetail(fmt.Sprintf("discarding partial download %s of another file", partial))
+			discardpartial(partial)
+		}
+	}
+
+	resp, err := c.getrange(ctx, url, offset, record.validator())
+	if err != nil {
+		return IsTransient(err), fmt.Errorf("failed to download file: %w", err)
 	}
 	defer func() {
 		if closerr := resp.Body.Close(); closerr != nil {
@@ -278,54 +686,62 @@ func download(url, destination string, sum *Checksum) (err error) {
 		}
 	}()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected response when downloading archive: http%d", resp.StatusCode)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	// bytes already downloaded, for progress reporting
+	var resumed int64
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		internal.LogDetail(fmt.Sprintf("resuming download from byte %d", offset))
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		resumed = offset
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is no longer valid, start over
+		discardpartial(partial)
+		return true, responseerror(what, url, resp)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		err := responseerror(what, url, resp)
+		return IsTransient(err), err
+	default:
+		// the whole file is sent, either from the start or because it changed since the
+		// partial download began
+		if err := recordpartial(partial, url, resp); err != nil {
+			return false, fmt.Errorf("failed to record partial download %s: %w", partial, err)
+		}
 	}
 
-	data, finish := progress(resp.Body, resp.ContentLength)
+	data, finish := progress(ctx, resp.Body, resumed, resp.ContentLength)
 	defer finish()
 
-	var verify func() error
-	if sum != nil {
-		verified, check, err := crcreader(data, *sum)
-		if err != nil {
-			return err
-		}
-		data = verified
-		verify = check
-	}
-
-	out, err := os.Create(destination)
+	out, err := os.OpenFile(partial, flags, 0o644)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", destination, err)
+		return false, fmt.Errorf("failed to create file %s: %w", partial, err)
 	}
 	defer func() {
 		if closerr := out.Close(); closerr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", destination, closerr))
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", partial, closerr))
 		}
 	}()
 
-	if _, err := io.Copy(out, data); err != nil {
-		return fmt.Errorf("failed to copy data to file %s: %w", destination, err)
+	written, err := io.Copy(out, data)
+	if err != nil {
+		return true, fmt.Errorf("failed to copy data to file %s: %w", partial, err)
 	}
-
-	if verify != nil {
-		if verr := verify(); verr != nil {
-			_ = os.Remove(destination)
-			return verr
-		}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return true, fmt.Errorf("incomplete download of %s: received %d of %d bytes", what, written, resp.ContentLength)
 	}
 
-	return nil
+	return false, nil
 }
 
-// extract extracts files from a compressed tar.gz archive.
+// extract extracts files from a zip archive or a tar archive, either plain or compressed
+// with any of the [compressions]. A single compressed file that isn't a tar archive
+// is extracted as is, under the specified name.
 // The processor function is called for each file in the archive and determines:
 // - Which files to extract (by returning non-nil)
 // - What name to give the extracted file (the returned string value)
-// Files are extracted with executable permissions (0755).
-// The source archive is removed after successful extraction.
-func extract(compressed, destination string, processor func(path string) *string) (err error) {
+// Files are extracted with the specified permissions.
+// The source archive is removed after extraction.
+func extract(compressed, destination, name string, perms permissions, processor func(path string) *string) (err error) {
 	internal.LogDetail(fmt.Sprintf("extracting %s", compressed))
 
 	start := time.Now()
@@ -347,50 +763,65 @@ func extract(compressed, destination string, processor func(path string) *string
 
 	// sniff mime header to determine file type
 	header := make([]byte, 512)
-	if _, err := file.Read(header); err != nil {
+	n, err := file.Read(header)
+	if err != nil {
 		return fmt.Errorf("failed to read file header: %w", err)
 	}
-	mime := http.DetectContentType(header)
+	header = header[:n]
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
 		return err
 	}
 
-	switch mime {
-	case "application/x-gzip":
-		return untar(file, destination, processor)
-	case "application/zip":
-		info, _ := file.Stat()
-		return unzip(file, info.Size(), destination, processor)
-	default:
-		return fmt.Errorf("unsupported format: %s", mime)
+	// only some compression formats are known by mime sniffing, so check their magic bytes first
+	for _, compression := range compressions {
+		if bytes.HasPrefix(header, compression.magic) {
+			return decompress(file, compression, destination, name, perms, processor)
+		}
+	}
+
+	if istar(header) {
+		return untar(file, destination, perms, processor)
 	}
-}
 
-// progress wraps an io.Reader to display a progress bar when running in a terminal.
-// Returns the wrapped reader and a function to finalize the progress display.
-// The progress bar shows transfer speed and completion percentage.
-func progress(reader io.Reader, size int64) (io.Reader, func()) {
-	if !internal.IsTerminalWriter(internal.Output) {
-		return reader, func() {}
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat compressed file: %w", err)
+	}
+
+	if bytes.HasPrefix(header, sevenzipmagic) {
+		return un7z(file, info.Size(), destination, perms, processor)
+	}
+
+	mime := http.DetectContentType(header)
+	switch {
+	case mime == "application/zip":
+		return unzip(file, info.Size(), destination, perms, processor)
+	// windows executables, which is how self-extracting archives are shipped
+	case bytes.HasPrefix(header, []byte("MZ")):
+		return unsfx(file, info.Size(), destination, perms, processor)
 	}
 
-	bar := pb.
-		New64(size).
-		SetWriter(internal.Output).
-		SetTemplate(
-			pb.ProgressBarTemplate(
-				color.New(color.FgHiBlack).Sprint(
-					`   ` + internal.Symbols.Detail + ` {{string . "prefix"}}{{counters . }}` +
-						` {{bar . "[" "=" ">" " " "]" }} {{percent . }}` +
-						` {{speed . "%s/s" }}{{string . "suffix"}}`,
-				),
-			),
-		).
-		SetRefreshRate(time.Second / 60).
-		SetMaxWidth(100).
-		Start()
+	// sniffing can't see through old tar archives without magic bytes, or archives served
+	// with extra bytes in front, so fall back to the extension of the file
+	lower := strings.ToLower(compressed)
+	for _, compression := range compressions {
+		for _, ext := range compression.extensions {
+			if strings.HasSuffix(lower, ext) {
+				return decompress(file, compression, destination, name, perms, processor)
+			}
+		}
+	}
 
-	return bar.NewProxyReader(reader), func() { bar.Finish() }
+	switch {
+	case strings.HasSuffix(lower, ".tar"):
+		return untar(file, destination, perms, processor)
+	case strings.HasSuffix(lower, ".zip"):
+		return unzip(file, info.Size(), destination, perms, processor)
+	case strings.HasSuffix(lower, ".7z"):
+		return un7z(file, info.Size(), destination, perms, processor)
+	default:
+		return &UnsupportedArchiveError{File: compressed, MIME: mime}
+	}
 }
 
 // OriginOption configures optional behavior for an [Origin].
@@ -399,6 +830,84 @@ type OriginOption func(*origincfg)
 // origincfg accumulates optional configuration shared across origins.
 type origincfg struct {
 	checksums map[Platform]Checksum
+	// checksum verified on platforms without an entry in checksums
+	fallback *Checksum
+	// url template of a checksums file listing the checksum of the downloaded file
+	checksumfile string
+	// signature verifications run on the downloaded file, along with their description
+	verifiers     []verifier
+	verifications []string
+	// headers sent on requests, e.g. for authentication
+	headers []headerrule
+	// host of the main url of the origin, which headers are sent to unless scoped otherwise
+	primaryhost string
+	// client used for requests instead of the process-wide one
+	client *http.Client
+
+	// options of the origins resolving the download url on their own
+	gitlab    gitlabcfg
+	hashicorp hashicorpcfg
+	golang    gocfg
+	tag       string
+	files     map[string]string
+	// repository whose latest release resolves the version "latest"
+	githubrepo string
+
+	// make the binaries extracted from archives executable regardless of their archived mode
+	executable bool
+	// link local binaries instead of copying them
+	symlink bool
+	// arguments install scripts are run with
+	scriptargs []string
+	// url templates tried in order when downloading from the main one fails
+	fallbacks []string
+	// invalid options are reported when installing, as options can't fail
+	err error
+}
+
+// digest describes the options that affect what's installed, see [origindigest].
+// Header values and the http client are left out, as they only affect how files are
+// fetched and can't be compared across runs.
+func (c origincfg) digest() any {
+	checksums := make(map[string]string, len(c.checksums))
+	for platform, sum := range c.checksums {
+		checksums[platform.OS+"/"+platform.Arch] = sum.Algorithm.String() + ":" + sum.Value
+	}
+	var fallback string
+	if c.fallback != nil {
+		fallback = c.fallback.Algorithm.String() + ":" + c.fallback.Value
+	}
+
+	headers := make([]string, 0, len(c.headers))
+	for _, header := range c.headers {
+		headers = append(headers, fmt.Sprintf("%s primary=%t hosts=%s", header.key, header.primary, strings.Join(header.hosts, ",")))
+	}
+
+	return struct {
+		Checksums     map[string]string
+		Fallback      string
+		ChecksumFile  string
+		Verifications []string
+		Headers       []string
+		GitLabURL     string
+		Mirror        string
+		Key           string
+		Tags          []string
+		LDFlags       string
+		Env           []string
+		Tag           string
+		Files         map[string]string
+		GitHubRepo    string
+		Executable    bool
+		Symlink       bool
+		ScriptArgs    []string
+		Fallbacks     []string
+	}{
+		checksums, fallback, c.checksumfile, c.verifications, headers,
+		c.gitlab.url, c.hashicorp.mirror, c.hashicorp.key,
+		c.golang.tags, c.golang.ldflags, c.golang.env,
+		c.tag, c.files, c.githubrepo, c.executable, c.symlink, c.scriptargs, c.fallbacks,
+	}
 }
 
 // WithChecksums enables integrity verification of the downloaded file
@@ -423,9 +932,239 @@ func WithChecksums(checksums map[Platform]Checksum) OriginOption {
 	}
 }
 
+// WithChecksum enables integrity verification of the downloaded file using
+// a checksum in the "algorithm:value" format, e.g. "sha256:abc...", see [ParseChecksum].
+//
+// The checksum is verified on every platform, which makes it suitable for platform
+// independent files or for origins built per platform; combined with [WithChecksums],
+// the platform specific checksums take precedence.
+// An invalid checksum makes the installation fail.
+func WithChecksum(checksum string) OriginOption {
+	return func(c *origincfg) {
+		sum, err := ParseChecksum(checksum)
+		if err != nil {
+			c.err = errors.Join(c.err, err)
+			return
+		}
+		c.fallback = &sum
+	}
+}
+
+// WithChecksumFile enables integrity verification of the downloaded file using the
+// checksums file many releases ship next to their artifacts, like checksums.txt or SHA256SUMS.
+// The URL can contain template variables that will be resolved using the [Template] values
+// during installation.
+// e.g. "https://github.com/foo/bar/releases/download/v{{.Version}}/checksums.txt"
+//
+// The file is expected in the format written by sha256sum and similar tools, with a line
+// per artifact containing its hash and name; the algorithm is inferred from the hash length.
+// The installation fails if the file can't be downloaded or has no entry for the artifact,
+// named after the last element of the download URL.
+// Checksums passed via [WithChecksum] or [WithChecksums] take precedence.
+func WithChecksumFile(url string) OriginOption {
+	return func(c *origincfg) {
+		c.checksumfile = url
+	}
+}
+
+// WithFallbackURLs sets URLs tried in order when the installation from the main URL of the
+// origin fails, e.g. when a corporate mirror is used as main URL and upstream as fallback.
+// The URLs can contain template variables that will be resolved using the [Template] values
+// during installation.
+func WithFallbackURLs(urls ...string) OriginOption {
+	return func(c *origincfg) {
+		c.fallbacks = append(c.fallbacks, urls...)
+	}
+}
+
+// WithExecutableBinaries makes the binaries mapped when extracting an archive executable,
+// regardless of the permissions stored for them in the archive, which are kept otherwise.
+// Files of mapped directories and files extracted without a mapping keep their permissions.
+func WithExecutableBinaries() OriginOption {
+	return func(c *origincfg) {
+		c.executable = true
+	}
+}
+
+// tryurls calls install with the main url and then the fallbacks, until one succeeds.
+func (c origincfg) tryurls(ctx context.Context, main string, install func(urlformat string) error) error {
+	urls := append([]string{main}, c.fallbacks...)
+
+	var errs []error
+	for i, urlformat := range urls {
+		err := install(urlformat)
+		if err == nil {
+			return nil
+		}
+		if len(urls) == 1 || ctx.Err() != nil {
+			return err
+		}
+
+		errs = append(errs, err)
+		if i < len(urls)-1 {
+			internal.LogDetail(fmt.Sprintf("installation failed: %s; trying next url", err))
+		}
+	}
+
+	return fmt.Errorf("installation failed from all %d urls: %w", len(urls), errors.Join(errs...))
+}
+
+// headerrule is a header sent on requests to the hosts, and to the primary host of the origin
+// if primary is set. The value is resolved on each request, skipping the header if it's empty.
+type headerrule struct {
+	primary bool
+	hosts   []string
+	key     string
+	value   func() string
+}
+
+// WithHTTPHeader sets a header sent on the requests made by the origin to the host of its main
+// URL, e.g. to authenticate against artifact repositories like Artifactory or Nexus.
+// Requests to other hosts, like fallback mirrors or checksum and signature files hosted
+// elsewhere, only get the header if their host is passed too, e.g. "mirror.example.com".
+func WithHTTPHeader(key, value string, hosts ...string) OriginOption {
+	return func(c *origincfg) {
+		c.headers = append(c.headers, headerrule{primary: true, hosts: hosts, key: key, value: func() string { return value }})
+	}
+}
+
+// WithBearerTokenFromEnv authenticates the requests made by the origin to the host of its main
+// URL with the bearer token in the env variable, e.g. GITHUB_TOKEN. The variable is read on
+// each request, and no token is sent while it's empty, so the same setup works locally and on ci.
+// Like with [WithHTTPHeader], other hosts only get the token if they're passed too.
+func WithBearerTokenFromEnv(env string, hosts ...string) OriginOption {
+	return func(c *origincfg) {
+		c.headers = append(
+			c.headers,
+			headerrule{
+				primary: true,
+				hosts:   hosts,
+				key:     "Authorization",
+				value: func() string {
+					if token := os.Getenv(env); token != "" {
+						return "Bearer " + token
+					}
+					return ""
+				},
+			},
+		)
+	}
+}
+
+// get performs a GET request to url, including the configured headers
+// for the host of the request.
+func (c origincfg) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.getrange(ctx, url, 0, "")
+}
+
+// snippetsize is the amount of the body of unexpected responses included in errors.
+const snippetsize = 256
+
+// responseerror reports the unexpected response to the request of what from url,
+// including the start of its body, which usually explains what went wrong.
+func responseerror(what, url string, resp *http.Response) error {
+	err := fmt.Errorf("unexpected response when downloading %s from %s: http%d", what, url, resp.StatusCode)
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, snippetsize+1))
+	truncated := len(body) > snippetsize
+	if truncated {
+		body = body[:snippetsize]
+	}
+
+	snippet := strings.Join(strings.Fields(strings.ToValidUTF8(string(body), "")), " ")
+	if snippet != "" {
+		if truncated {
+			snippet += "..."
+		}
+		err = fmt.Errorf("%w: %s", err, snippet)
+	}
+
+	return &DownloadError{URL: url, Status: resp.StatusCode, Err: err}
+}
+
+// getrange performs a GET request to url like [origincfg.get], requesting
+// the content starting at offset when it's greater than zero.
+// With a validator, the etag or last modified date of the content already downloaded,
+// the server sends the whole content instead if it changed.
+func (c origincfg) getrange(ctx context.Context, url string, offset int64, validator string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	c.setheaders(req)
+
+	resp, err := c.httpclient().Do(req)
+	if err != nil {
+		return nil, &DownloadError{URL: url, Err: err}
+	}
+	return resp, nil
+}
+
+// setheaders sets the configured headers for the host of the request.
+func (c origincfg) setheaders(req *http.Request) {
+	for _, header := range c.headers {
+		if !header.matches(c.primaryhost, req.URL) {
+			continue
+		}
+		if value := header.value(); value != "" {
+			req.Header.Set(header.key, value)
+		}
+	}
+}
+
+// matches returns true if the header is sent on requests to the url.
+// Hosts match with or without port, while the primary host must match exactly.
+func (h headerrule) matches(primaryhost string, url *neturl.URL) bool {
+	if h.primary && primaryhost != "" && url.Host == primaryhost {
+		return true
+	}
+	return slices.Contains(h.hosts, url.Host) || slices.Contains(h.hosts, url.Hostname())
+}
+
+// withprimary returns the configuration with the host of the url the template resolves to
+// as primary host, unless one is set already by origins resolving their url on their own.
+func (c origincfg) withprimary(template Template, urlformat string) origincfg {
+	if c.primaryhost != "" {
+		return c
+	}
+	if url, err := template.Resolve(urlformat); err == nil {
+		if parsed, err := neturl.Parse(url); err == nil {
+			c.primaryhost = parsed.Host
+		}
+	}
+	return c
+}
+
 // checksum returns the checksum configured for the current template's
-// platform, if any.
-func (c origincfg) checksum(t Template) (Checksum, bool) {
-	sum, ok := c.checksums[Platform{OS: t.GOOS, Arch: t.GOARCH}]
-	return sum, ok
+// platform, if any, for the file downloaded from url.
+func (c origincfg) checksum(ctx context.Context, t Template, url string) (*Checksum, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if sum, ok := c.checksums[Platform{OS: t.GOOS, Arch: t.GOARCH}]; ok {
+		return &sum, nil
+	}
+
+	if c.fallback != nil || c.checksumfile == "" {
+		return c.fallback, nil
+	}
+
+	sumsurl, err := t.Resolve(c.checksumfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve checksums file URL: %w", err)
+	}
+
+	sum, err := c.fetchchecksum(ctx, sumsurl, artifactname(url))
+	if err != nil {
+		return nil, err
+	}
+	return &sum, nil
 }
@@ -2,10 +2,12 @@ package binary
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/sha256"
 	"embed"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
@@ -14,7 +16,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,7 +37,7 @@ func TestRemoteBinaryDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util").Install(tmpl))
+			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl))
 
 			info, err := os.Stat(tmpl.Cmd)
 			require.NoError(t, err)
@@ -52,7 +56,7 @@ func TestRemoteBinaryDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteBinaryDownload(srv.URL+"/{{.Name}}").Install(tmpl))
+			require.NoError(t, RemoteBinaryDownload(srv.URL+"/{{.Name}}").Install(t.Context(), tmpl))
 			assert.FileExists(t, tmpl.Cmd)
 		},
 	)
@@ -62,9 +66,48 @@ func TestRemoteBinaryDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			err := RemoteBinaryDownload(srv.URL + "/nonexistent").Install(tmpl)
+			err := RemoteBinaryDownload(srv.URL+"/nonexistent").Install(t.Context(), tmpl)
 			require.Error(t, err)
-			assert.Contains(t, err.Error(), "unexpected response when downloading binary")
+			assert.Contains(t, err.Error(), "unexpected response when downloading binary from "+srv.URL+"/nonexistent: http404")
+			assert.Contains(t, err.Error(), "404 page not found")
+		},
+	)
+
+	t.Run("keeps previous install when download fails",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+			require.NoError(t, os.WriteFile(tmpl.Cmd, []byte("previous"), 0o755))
+
+			require.Error(t, RemoteBinaryDownload(srv.URL+"/util", WithChecksum("sha256:"+strings.Repeat("0", 64))).Install(t.Context(), tmpl))
+
+			content, err := os.ReadFile(tmpl.Cmd)
+			require.NoError(t, err)
+			assert.Equal(t, "previous", string(content))
+		},
+	)
+
+	t.Run("never installs truncated downloads",
+		func(t *testing.T) {
+			backoff := downloadbackoff
+			downloadbackoff = 0
+			t.Cleanup(func() { downloadbackoff = backoff })
+
+			// always drops the connection halfway through
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Length", "1024")
+					_, _ = w.Write(make([]byte, 512))
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.Error(t, RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl))
+			assert.NoFileExists(t, tmpl.Cmd)
 		},
 	)
 
@@ -74,7 +117,7 @@ func TestRemoteBinaryDownloadOrigin(t *testing.T) {
 			dir := filepath.Join(t.TempDir(), "nested", "bin", "dir")
 			tmpl := mktemplate(dir, "util", "1.2.3")
 
-			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util").Install(tmpl))
+			require.NoError(t, RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl))
 			assert.FileExists(t, tmpl.Cmd)
 		},
 	)
@@ -83,7 +126,7 @@ func TestRemoteBinaryDownloadOrigin(t *testing.T) {
 		func(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			err := RemoteBinaryDownload("http://example.com/{{.Invalid").Install(tmpl)
+			err := RemoteBinaryDownload("http://example.com/{{.Invalid").Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "failed to resolve URL")
 		},
@@ -95,7 +138,7 @@ func TestGoBinaryOrigin(t *testing.T) {
 		func(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "goimports", "latest")
 
-			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(tmpl)
+			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(t.Context(), tmpl)
 			require.NoError(t, err)
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "goimports"))
 		},
@@ -106,7 +149,7 @@ func TestGoBinaryOrigin(t *testing.T) {
 			// install goimports but give it a different name
 			tmpl := mktemplate(t.TempDir(), "goimp", "latest")
 
-			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(tmpl)
+			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(t.Context(), tmpl)
 			require.NoError(t, err)
 
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "goimp"))
@@ -118,7 +161,7 @@ func TestGoBinaryOrigin(t *testing.T) {
 		func(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "nonexistent", "latest")
 
-			err := GoBinary("github.com/aexvir/harness/nonexistent/cmd/tool").Install(tmpl)
+			err := GoBinary("github.com/aexvir/harness/nonexistent/cmd/tool").Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "unable to install executable")
 		},
@@ -129,20 +172,77 @@ func TestGoBinaryOrigin(t *testing.T) {
 			dir := filepath.Join(t.TempDir(), "nested", "bin")
 			tmpl := mktemplate(dir, "goimports", "latest")
 
-			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(tmpl)
+			err := GoBinary("golang.org/x/tools/cmd/goimports").Install(t.Context(), tmpl)
 			require.NoError(t, err)
 			assert.FileExists(t, filepath.Join(dir, "goimports"))
 		},
 	)
 }
 
+func TestGoBinaryBuildOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+
+	// fake go records its arguments and env, and installs a binary named after the package
+	pathdir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/go.args"
+echo "$GOPRIVATE $CGO_ENABLED" > "$(dirname "$0")/go.env"
+for last; do :; done
+name="${last%@*}"
+printf '#!/bin/sh\necho 1.0.0\n' > "$GOBIN/${name##*/}"
+chmod +x "$GOBIN/${name##*/}"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(pathdir, "go"), []byte(script), 0o755))
+	t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tmpl := mktemplate(t.TempDir(), "tool", "1.0.0")
+	origin := GoBinary("example.com/private/cmd/tool",
+		WithBuildTags("sqlite", "fts5"),
+		WithLdflags("-s -w"),
+		WithGoEnv("GOPRIVATE", "example.com/private"),
+		WithGoEnv("CGO_ENABLED", "1"),
+	)
+	require.NoError(t, origin.Install(t.Context(), tmpl))
+	assert.FileExists(t, tmpl.Cmd)
+
+	args, err := os.ReadFile(filepath.Join(pathdir, "go.args"))
+	require.NoError(t, err)
+	assert.Equal(t, "install -tags sqlite,fts5 -ldflags -s -w example.com/private/cmd/tool@1.0.0\n", string(args))
+
+	env, err := os.ReadFile(filepath.Join(pathdir, "go.env"))
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/private 1\n", string(env))
+}
+
+func TestGoBinaryName(t *testing.T) {
+	tests := map[string]string{
+		"golang.org/x/tools/cmd/goimports":                       "goimports",
+		"github.com/golangci/golangci-lint/v2/cmd/golangci-lint": "golangci-lint",
+		"github.com/bufbuild/buf/v2":                             "buf",
+		"example.com/tool/v1":                                    "v1",
+		"example.com/tool/v0":                                    "v0",
+		"example.com/tool/vnext":                                 "vnext",
+		"v2":                                                     "v2",
+	}
+
+	for pkg, want := range tests {
+		t.Run(pkg,
+			func(t *testing.T) {
+				assert.Equal(t, want, gobinname(pkg))
+			},
+		)
+	}
+}
+
 func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 	t.Run("tar.gz",
 		func(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
 
 			info, err := os.Stat(filepath.Join(tmpl.Directory, "util"))
 			require.NoError(t, err)
@@ -159,17 +259,86 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 			tmpl.ArchiveExtension = ".zip"
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.zip", map[string]string{"util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.zip", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
+		},
+	)
+
+	for _, ext := range []string{".tar.bz2", ".tar.xz", ".tar.zst", ".7z"} {
+		t.Run(strings.TrimPrefix(ext, "."),
+			func(t *testing.T) {
+				srv := setupTestServer(t)
+				tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+				tmpl.ArchiveExtension = ext
+
+				require.NoError(t, RemoteArchiveDownload(srv.URL+"/util{{.ArchiveExtension}}", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
+
+				content, err := os.ReadFile(filepath.Join(tmpl.Directory, "util"))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), "util version 1.2.3")
+			},
+		)
+	}
+
+	for _, archive := range []string{"util.zip", "util.7z"} {
+		t.Run("self-extracting "+filepath.Ext(archive),
+			func(t *testing.T) {
+				payload, err := testdata.ReadFile("testdata/" + archive)
+				require.NoError(t, err)
+
+				// executable stub followed by the archive
+				sfx := append(append([]byte("MZ"), make([]byte, 1022)...), payload...)
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write(sfx) //nolint:errcheck
+				}))
+				t.Cleanup(srv.Close)
+				tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+				require.NoError(t, RemoteArchiveDownload(srv.URL+"/util-setup.exe", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
+
+				content, err := os.ReadFile(filepath.Join(tmpl.Directory, "util"))
+				require.NoError(t, err)
+				assert.Contains(t, string(content), "util version 1.2.3")
+			},
+		)
+	}
+
+	t.Run("plain tar",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
 		},
 	)
 
+	t.Run("single gzip file is installed under the binary name",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "tool", "1.2.3")
+
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.gz", nil).Install(t.Context(), tmpl))
+
+			info, err := os.Stat(tmpl.Cmd)
+			require.NoError(t, err)
+			if runtime.GOOS != "windows" {
+				assert.NotZero(t, info.Mode().Perm()&0o111)
+			}
+
+			content, err := os.ReadFile(tmpl.Cmd)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "util version 1.2.3")
+			assert.NoFileExists(t, filepath.Join(tmpl.Directory, "util.gz"))
+		},
+	)
+
 	t.Run("nested path with mapping",
 		func(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-1.2.3/bin/util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-1.2.3/bin/util": "util"}).Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
 		},
 	)
@@ -179,7 +348,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-{{.Version}}/bin/util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-{{.Version}}/bin/util": "util"}).Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
 		},
 	)
@@ -189,7 +358,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/multi.tar.gz", map[string]string{"util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/multi.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
 
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
 			assert.NoFileExists(t, filepath.Join(tmpl.Directory, "README.md"))
@@ -202,7 +371,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/multi.tar.gz", map[string]string{}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/multi.tar.gz", map[string]string{}).Install(t.Context(), tmpl))
 
 			for _, name := range []string{"util", "README.md", "LICENSE"} {
 				assert.FileExists(t, filepath.Join(tmpl.Directory, name))
@@ -215,9 +384,28 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			srv := setupTestServer(t)
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			err := RemoteArchiveDownload(srv.URL+"/nonexistent.tar.gz", map[string]string{"util": "util"}).Install(tmpl)
+			err := RemoteArchiveDownload(srv.URL+"/nonexistent.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unexpected response when downloading archive from "+srv.URL+"/nonexistent.tar.gz: http404")
+			assert.NotContains(t, err.Error(), "unsupported format")
+			assert.NoFileExists(t, filepath.Join(tmpl.Directory, "nonexistent.tar.gz"))
+		},
+	)
+
+	t.Run("http error includes the start of long responses",
+		func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("<Error>\n  <Code>AccessDenied</Code>\n" + strings.Repeat("x", 1024) + "</Error>")) //nolint:errcheck
+			}))
+			t.Cleanup(srv.Close)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
 			require.Error(t, err)
-			assert.Contains(t, err.Error(), "unexpected response when downloading archive")
+			assert.Contains(t, err.Error(), "http403: <Error> <Code>AccessDenied</Code> xxx")
+			assert.True(t, strings.HasSuffix(err.Error(), "..."))
+			assert.NotContains(t, err.Error(), "</Error>")
 		},
 	)
 
@@ -227,7 +415,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
 			// serve a plain text file; sniffed mime type will not be a supported archive format
-			err := RemoteArchiveDownload(srv.URL+"/util", map[string]string{"util": "util"}).Install(tmpl)
+			err := RemoteArchiveDownload(srv.URL+"/util", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "unsupported format")
 		},
@@ -237,7 +425,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 		func(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			err := RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(tmpl)
+			err := RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "failed to download file")
 		},
@@ -247,7 +435,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 		func(t *testing.T) {
 			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
 
-			err := RemoteArchiveDownload("http://example.com/{{.Invalid", map[string]string{"util": "util"}).Install(tmpl)
+			err := RemoteArchiveDownload("http://example.com/{{.Invalid", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "failed to resolve URL")
 		},
@@ -262,7 +450,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			require.NoError(t, err)
 			require.NoError(t, os.WriteFile(filepath.Join(dir, "util.tar.gz"), data, 0o644))
 
-			require.NoError(t, RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(dir, "util"))
 		},
 	)
@@ -275,8 +463,27 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			// pre-place a corrupt tar.gz so the download step is skipped and extract is attempted
 			require.NoError(t, os.WriteFile(filepath.Join(dir, "util.tar.gz"), []byte("this is not a valid archive"), 0o644))
 
-			err := RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(tmpl)
+			err := RemoteArchiveDownload("http://127.0.0.1:1/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl)
 			require.Error(t, err)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "archive and staging files should have been removed")
+		},
+	)
+
+	t.Run("only extracted binaries are left in the bin dir",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/nested.tar.gz", map[string]string{"myapp-1.2.3/bin/util": "util"}).Install(t.Context(), tmpl))
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, "util", entries[0].Name())
 		},
 	)
 
@@ -286,7 +493,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			dir := filepath.Join(t.TempDir(), "deep", "nested", "dir")
 			tmpl := mktemplate(dir, "util", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"}).Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(dir, "util"))
 		},
 	)
@@ -297,7 +504,7 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 			dir := t.TempDir()
 			tmpl := mktemplate(dir, "renamed", "1.2.3")
 
-			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "renamed"}).Install(tmpl))
+			require.NoError(t, RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "renamed"}).Install(t.Context(), tmpl))
 
 			assert.FileExists(t, filepath.Join(dir, "renamed"))
 			assert.NoFileExists(t, filepath.Join(dir, "util"))
@@ -305,6 +512,360 @@ func TestRemoteArchiveDownloadOrigin(t *testing.T) {
 	)
 }
 
+func TestFallbackURLs(t *testing.T) {
+	t.Run("tries urls in order until one succeeds",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/mirror/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithFallbackURLs(srv.URL+"/missing/util.tar.gz", srv.URL+"/{{.Name}}.tar.gz"),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+		},
+	)
+
+	t.Run("tries next url on checksum mismatch",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(
+				srv.URL+"/util.zip",
+				WithFallbackURLs(srv.URL+"/util"),
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("reports errors of all urls",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/first", WithFallbackURLs(srv.URL+"/second")).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "installation failed from all 2 urls")
+			assert.Equal(t, 2, strings.Count(err.Error(), "http404"))
+		},
+	)
+}
+
+func TestDownloadRetries(t *testing.T) {
+	backoff := downloadbackoff
+	downloadbackoff = 0
+	t.Cleanup(func() { downloadbackoff = backoff })
+
+	data, err := testdata.ReadFile("testdata/util.tar.gz")
+	require.NoError(t, err)
+
+	t.Run("retries server errors",
+		func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					if requests < 3 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					_, _ = w.Write(data)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(srv.URL+"/util.tar.gz", map[string]string{"util": "util"})
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+			assert.Equal(t, 3, requests)
+		},
+	)
+
+	t.Run("resumes interrupted downloads",
+		func(t *testing.T) {
+			var ranges []string
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					if len(ranges) == 1 {
+						// send half of the file and drop the connection
+						w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+						_, _ = w.Write(data[:len(data)/2])
+						w.(http.Flusher).Flush()
+						panic(http.ErrAbortHandler)
+					}
+					http.ServeContent(w, r, "util.tar.gz", time.Time{}, bytes.NewReader(data))
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util.tar.gz")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+			assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(data)/2)}, ranges)
+		},
+	)
+
+	t.Run("discards partial downloads of other urls",
+		func(t *testing.T) {
+			var ranges []string
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					http.ServeContent(w, r, "util.tar.gz", time.Time{}, bytes.NewReader(data))
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			// left over by an interrupted download of another version
+			partial := filepath.Join(dir, "util.tar.gz.part")
+			require.NoError(t, os.WriteFile(partial, bytes.Repeat([]byte{0}, len(data)/2), 0o644))
+			require.NoError(t, os.WriteFile(downloadrecordpath(partial), []byte(`{"url":"`+srv.URL+`/v1/util.tar.gz"}`), 0o644))
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util.tar.gz")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.Equal(t, []string{""}, ranges)
+			assert.NoFileExists(t, partial)
+			assert.NoFileExists(t, downloadrecordpath(partial))
+		},
+	)
+
+	t.Run("downloads changed files again instead of resuming them",
+		func(t *testing.T) {
+			var ranges, validators []string
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ranges = append(ranges, r.Header.Get("Range"))
+					validators = append(validators, r.Header.Get("If-Range"))
+					w.Header().Set("ETag", `"v2"`)
+					http.ServeContent(w, r, "util.tar.gz", time.Time{}, bytes.NewReader(data))
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			partial := filepath.Join(dir, "util.tar.gz.part")
+			require.NoError(t, os.WriteFile(partial, bytes.Repeat([]byte{0}, len(data)/2), 0o644))
+			require.NoError(t, os.WriteFile(downloadrecordpath(partial), []byte(`{"url":"`+srv.URL+`/util.tar.gz","etag":"\"v1\""}`), 0o644))
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util.tar.gz")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.Equal(t, []string{fmt.Sprintf("bytes=%d-", len(data)/2)}, ranges)
+			assert.Equal(t, []string{`"v1"`}, validators)
+		},
+	)
+
+	t.Run("removes partial binaries of failed installs",
+		func(t *testing.T) {
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Length", "1024")
+					_, _ = w.Write([]byte("v1"))
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.0.0")
+
+			require.Error(t, RemoteBinaryDownload(srv.URL+"/v1/util").Install(t.Context(), tmpl))
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		},
+	)
+
+	t.Run("doesn't retry client errors",
+		func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					w.WriteHeader(http.StatusNotFound)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http404")
+			assert.Equal(t, 1, requests)
+		},
+	)
+
+	t.Run("stops when the context is cancelled",
+		func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+
+			var requests int
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					cancel()
+					w.WriteHeader(http.StatusBadGateway)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util", WithFallbackURLs(srv.URL+"/fallback")).Install(ctx, tmpl)
+			require.Error(t, err)
+			assert.Equal(t, 1, requests)
+		},
+	)
+
+	t.Run("gives up after the last attempt",
+		func(t *testing.T) {
+			var requests int
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					w.WriteHeader(http.StatusBadGateway)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util").Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http502")
+			assert.Equal(t, downloadattempts, requests)
+		},
+	)
+}
+
+func TestHTTPHeaders(t *testing.T) {
+	// server only serves the binary to authenticated requests with the custom header
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Custom") != "value" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			data, _ := testdata.ReadFile("testdata/util")
+			_, _ = w.Write(data)
+		}),
+	)
+	t.Cleanup(srv.Close)
+
+	t.Run("sends headers and bearer token from env",
+		func(t *testing.T) {
+			t.Setenv("HARNESS_TEST_TOKEN", "token")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(
+				srv.URL+"/util",
+				WithHTTPHeader("X-Custom", "value"),
+				WithBearerTokenFromEnv("HARNESS_TEST_TOKEN"),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("skips bearer token when env is empty",
+		func(t *testing.T) {
+			t.Setenv("HARNESS_TEST_TOKEN", "")
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithHTTPHeader("X-Custom", "value"),
+				WithBearerTokenFromEnv("HARNESS_TEST_TOKEN"),
+			)
+			err := origin.Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http401")
+		},
+	)
+
+	t.Run("scopes headers to the host of the main url",
+		func(t *testing.T) {
+			t.Setenv("HARNESS_TEST_TOKEN", "token")
+
+			primary := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+				}),
+			)
+			t.Cleanup(primary.Close)
+
+			var authorization, custom atomic.Value
+			mirror := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					authorization.Store(r.Header.Get("Authorization"))
+					custom.Store(r.Header.Get("X-Custom"))
+					data, _ := testdata.ReadFile("testdata/util")
+					_, _ = w.Write(data)
+				}),
+			)
+			t.Cleanup(mirror.Close)
+
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+			origin := RemoteBinaryDownload(
+				primary.URL+"/util",
+				WithFallbackURLs(mirror.URL+"/util"),
+				WithHTTPHeader("X-Custom", "value"),
+				WithBearerTokenFromEnv("HARNESS_TEST_TOKEN"),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.Empty(t, authorization.Load())
+			assert.Empty(t, custom.Load())
+
+			// other hosts get them only when allowed explicitly
+			tmpl = mktemplate(t.TempDir(), "util", "1.2.3")
+			host := strings.TrimPrefix(mirror.URL, "http://")
+			origin = RemoteBinaryDownload(
+				primary.URL+"/util",
+				WithFallbackURLs(mirror.URL+"/util"),
+				WithHTTPHeader("X-Custom", "value", host),
+				WithBearerTokenFromEnv("HARNESS_TEST_TOKEN", host),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.Equal(t, "Bearer token", authorization.Load())
+			assert.Equal(t, "value", custom.Load())
+		},
+	)
+}
+
 func TestChecksumVerification(t *testing.T) {
 	here := Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
 
@@ -320,7 +881,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			require.NoError(t, origin.Install(tmpl))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
 			assert.FileExists(t, tmpl.Cmd)
 		},
 	)
@@ -337,7 +898,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			err := origin.Install(tmpl)
+			err := origin.Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "checksum mismatch")
 			assert.NoFileExists(t, tmpl.Cmd)
@@ -356,7 +917,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			require.NoError(t, origin.Install(tmpl))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
 		},
 	)
 
@@ -373,7 +934,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			require.NoError(t, origin.Install(tmpl))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
 			assert.FileExists(t, tmpl.Cmd)
 		},
 	)
@@ -391,7 +952,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			require.NoError(t, origin.Install(tmpl))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(tmpl.Directory, "util"))
 		},
 	)
@@ -410,7 +971,7 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			err := origin.Install(tmpl)
+			err := origin.Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "checksum mismatch")
 			assert.NoFileExists(t, filepath.Join(dir, "util.tar.gz"))
@@ -435,11 +996,64 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			require.NoError(t, origin.Install(tmpl))
+			require.NoError(t, origin.Install(t.Context(), tmpl))
 			assert.FileExists(t, filepath.Join(dir, "util"))
 		},
 	)
 
+	t.Run("verifies checksum on every platform",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util.tar.gz")),
+			)
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+
+			origin = RemoteBinaryDownload(
+				srv.URL+"/util",
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util.tar.gz")),
+			)
+			err := origin.Install(t.Context(), mktemplate(t.TempDir(), "util", "1.2.3"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+		},
+	)
+
+	t.Run("platform checksum takes precedence",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			origin := RemoteBinaryDownload(
+				srv.URL+"/util",
+				WithChecksum("sha256:"+strings.Repeat("0", 64)),
+				WithChecksums(map[Platform]Checksum{
+					here: {Algorithm: crypto.SHA256, Value: sha256hex(t, "testdata/util")},
+				}),
+			)
+
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+		},
+	)
+
+	t.Run("invalid checksum fails installation",
+		func(t *testing.T) {
+			srv := setupTestServer(t)
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util", WithChecksum("md5:deadbeef")).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unsupported algorithm")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
 	t.Run("unsupported hash algorithm errors clearly",
 		func(t *testing.T) {
 			srv := setupTestServer(t)
@@ -452,13 +1066,115 @@ func TestChecksumVerification(t *testing.T) {
 				}),
 			)
 
-			err := origin.Install(tmpl)
+			err := origin.Install(t.Context(), tmpl)
 			require.Error(t, err)
 			assert.Contains(t, err.Error(), "not available")
 		},
 	)
 }
 
+func TestChecksumFileVerification(t *testing.T) {
+	serve := func(t *testing.T, checksums string) *httptest.Server {
+		t.Helper()
+		sub, err := fs.Sub(testdata, "testdata")
+		require.NoError(t, err)
+
+		mux := http.NewServeMux()
+		mux.Handle("/", http.FileServer(http.FS(sub)))
+		mux.HandleFunc("/v1.2.3/checksums.txt", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, checksums)
+		})
+
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	t.Run("verifies artifact listed in checksums file",
+		func(t *testing.T) {
+			srv := serve(t, fmt.Sprintf(
+				"%s  util.zip\n%s *util.tar.gz\n",
+				sha256hex(t, "testdata/util.zip"),
+				sha256hex(t, "testdata/util.tar.gz"),
+			))
+			dir := t.TempDir()
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := RemoteArchiveDownload(
+				srv.URL+"/util.tar.gz",
+				map[string]string{"util": "util"},
+				WithChecksumFile(srv.URL+"/v{{.Version}}/checksums.txt"),
+			)
+
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join(dir, "util"))
+		},
+	)
+
+	t.Run("fails on checksum mismatch",
+		func(t *testing.T) {
+			srv := serve(t, sha256hex(t, "testdata/util.zip")+"  util\n")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util", WithChecksumFile(srv.URL+"/v{{.Version}}/checksums.txt")).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails when artifact is not listed",
+		func(t *testing.T) {
+			srv := serve(t, sha256hex(t, "testdata/util.zip")+"  util.zip\n")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util", WithChecksumFile(srv.URL+"/v{{.Version}}/checksums.txt")).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no checksum found for util")
+			assert.NoFileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("fails when checksums file can't be downloaded",
+		func(t *testing.T) {
+			srv := serve(t, "")
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := RemoteBinaryDownload(srv.URL+"/util", WithChecksumFile(srv.URL+"/missing.txt")).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http404")
+		},
+	)
+}
+
+func TestParseChecksum(t *testing.T) {
+	t.Run("parses supported algorithms",
+		func(t *testing.T) {
+			sum, err := ParseChecksum("sha256:" + strings.Repeat("ab", 32))
+			require.NoError(t, err)
+			assert.Equal(t, Checksum{Algorithm: crypto.SHA256, Value: strings.Repeat("ab", 32)}, sum)
+
+			sum, err = ParseChecksum("SHA512:" + strings.Repeat("AB", 64))
+			require.NoError(t, err)
+			assert.Equal(t, crypto.SHA512, sum.Algorithm)
+		},
+	)
+
+	t.Run("returns error for invalid checksums",
+		func(t *testing.T) {
+			for _, checksum := range []string{
+				strings.Repeat("ab", 32),
+				"md5:" + strings.Repeat("ab", 16),
+				"sha256:deadbeef",
+				"sha256:" + strings.Repeat("zz", 32),
+			} {
+				_, err := ParseChecksum(checksum)
+				assert.Error(t, err, checksum)
+			}
+		},
+	)
+}
+
 func sha256hex(t *testing.T, path string) string {
 	t.Helper()
 
@@ -478,7 +1194,7 @@ func TestProgressDisablesOnNonTerminalOutput(t *testing.T) {
 			SetOutput(io.Discard)
 
 			src := bytes.NewBufferString("payload")
-			got, finish := progress(src, int64(src.Len()))
+			got, finish := progress(t.Context(), src, 0, int64(src.Len()))
 			defer finish()
 
 			assert.True(t, got == src, "expected progress to be disabled for non-terminal output")
@@ -491,7 +1207,7 @@ func TestProgressDisablesOnNonTerminalOutput(t *testing.T) {
 			SetOutput(buf)
 
 			src := bytes.NewBufferString("payload")
-			got, finish := progress(src, int64(src.Len()))
+			got, finish := progress(t.Context(), src, 0, int64(src.Len()))
 			defer finish()
 
 			assert.True(t, got == src, "expected progress to be disabled for non-terminal output")
@@ -499,6 +1215,63 @@ func TestProgressDisablesOnNonTerminalOutput(t *testing.T) {
 	)
 }
 
+func TestProgressReporting(t *testing.T) {
+	t.Run("reports downloaded bytes",
+		func(t *testing.T) {
+			withTempDir(t)
+			srv := setupTestServer(t)
+
+			var done, total int64
+			var calls int
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"),
+				WithVersionCmd(SkipVersionCheck),
+				WithProgress(func(d, t int64) {
+					done, total = d, t
+					calls++
+				}),
+			)
+			require.NoError(t, bin.Install(t.Context()))
+
+			info, err := os.Stat(bin.BinPath())
+			require.NoError(t, err)
+			assert.Positive(t, calls)
+			assert.Equal(t, info.Size(), done)
+			assert.Equal(t, info.Size(), total)
+		},
+	)
+
+	t.Run("counts resumed bytes",
+		func(t *testing.T) {
+			var reported []int64
+			ctx := withprogress(t.Context(), func(done, total int64) {
+				reported = append(reported, done)
+				assert.EqualValues(t, 10, total)
+			})
+
+			reader, finish := progress(ctx, strings.NewReader("world"), 5, 5)
+			defer finish()
+			_, err := io.ReadAll(reader)
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(5), reported[0])
+			assert.Equal(t, int64(10), reported[len(reported)-1])
+		},
+	)
+
+	t.Run("can be disabled",
+		func(t *testing.T) {
+			bin := New("util", "1.2.3", new(fakeorigin), WithoutProgress())
+			ctx := withprogress(t.Context(), bin.progress)
+
+			// reporting to a no-op function instead of showing the progress bar
+			src := strings.NewReader("payload")
+			reader, finish := progress(ctx, src, 0, src.Size())
+			defer finish()
+			assert.IsType(t, &progressreader{}, reader)
+		},
+	)
+}
+
 func setupTestServer(t *testing.T) *httptest.Server {
 	t.Helper()
 	sub, err := fs.Sub(testdata, "testdata")
@@ -524,3 +1297,90 @@ func mktemplate(dir, name, version string) Template {
 		ArchiveExtension: ".tar.gz",
 	}
 }
+
+func TestDownloadReuse(t *testing.T) {
+	data, err := testdata.ReadFile("testdata/util.tar.gz")
+	require.NoError(t, err)
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloads.Add(1)
+		}
+		http.ServeContent(w, r, "util.tar.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+
+	url := srv.URL + "/v1.2.3/util.tar.gz"
+
+	t.Run("reuses recorded downloads from the same url",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			downloads.Store(0)
+
+			archive := filepath.Join(dir, "util.tar.gz")
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			assert.FileExists(t, downloadrecordpath(archive))
+
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			assert.EqualValues(t, 1, downloads.Load())
+		},
+	)
+
+	t.Run("downloads again files recorded from other urls",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			downloads.Store(0)
+
+			archive := filepath.Join(dir, "util.tar.gz")
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", srv.URL+"/v1.0.0/util.tar.gz", archive, nil))
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			assert.EqualValues(t, 2, downloads.Load())
+		},
+	)
+
+	t.Run("downloads again modified files",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			downloads.Store(0)
+
+			archive := filepath.Join(dir, "util.tar.gz")
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			require.NoError(t, os.WriteFile(archive, data[:len(data)/2], 0o644))
+
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			assert.EqualValues(t, 2, downloads.Load())
+
+			content, err := os.ReadFile(archive)
+			require.NoError(t, err)
+			assert.Equal(t, data, content)
+		},
+	)
+
+	t.Run("downloads again truncated files placed manually",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			downloads.Store(0)
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "util.tar.gz"), data[:len(data)/2], 0o644))
+
+			require.NoError(t, RemoteArchiveDownload(url, map[string]string{"util": "util"}).Install(t.Context(), tmpl))
+			assert.EqualValues(t, 1, downloads.Load())
+			assert.FileExists(t, filepath.Join(dir, "util"))
+			assert.NoFileExists(t, downloadrecordpath(filepath.Join(dir, "util.tar.gz")))
+		},
+	)
+
+	t.Run("forced downloads bypass previous downloads",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			downloads.Store(0)
+
+			archive := filepath.Join(dir, "util.tar.gz")
+			require.NoError(t, origincfg{}.download(t.Context(), "archive", url, archive, nil))
+			require.NoError(t, origincfg{}.download(withforce(t.Context()), "archive", url, archive, nil))
+			assert.EqualValues(t, 2, downloads.Load())
+		},
+	)
+}
@@ -0,0 +1,102 @@
+package binary
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// platform describes the variant of the host platform that GOOS and GOARCH don't capture.
+type platform struct {
+	// arm version, e.g. "7", only when GOARCH is arm
+	goarm string
+	// "musl" or "gnu", only on linux
+	libc string
+	// process translated by rosetta on apple silicon
+	rosetta bool
+}
+
+// hostplatform detects the variant of the host platform once per process.
+var hostplatform = sync.OnceValue(
+	func() platform {
+		return platform{
+			goarm:   detectgoarm(runtime.GOARCH),
+			libc:    detectlibc(runtime.GOOS, "/"),
+			rosetta: detectrosetta(runtime.GOOS, runtime.GOARCH),
+		}
+	},
+)
+
+// detectgoarm returns the arm version, from the GOARM env variable if set, like go does,
+// or from the cpu architecture reported by the kernel.
+func detectgoarm(goarch string) string {
+	if goarch != "arm" {
+		return ""
+	}
+
+	if goarm := os.Getenv("GOARM"); goarm != "" {
+		// GOARM can also specify the floating point mode, e.g. "7,softfloat"
+		goarm, _, _ = strings.Cut(goarm, ",")
+		return goarm
+	}
+
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer file.Close() //nolint:errcheck
+
+	return cpuinfoarm(file)
+}
+
+// cpuinfoarm returns the arm version from the contents of /proc/cpuinfo,
+// capped to 7 as 64 bit cpus run 32 bit binaries built for armv7.
+func cpuinfoarm(cpuinfo io.Reader) string {
+	scanner := bufio.NewScanner(cpuinfo)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "CPU architecture" {
+			continue
+		}
+
+		switch version := strings.TrimSpace(value); version {
+		case "5", "6", "7":
+			return version
+		case "":
+			return ""
+		default:
+			return "7"
+		}
+	}
+	return ""
+}
+
+// detectlibc returns the flavor of the C library on linux, "musl" if the musl dynamic
+// loader is present under root, like on alpine, and "gnu" otherwise.
+func detectlibc(goos, root string) string {
+	if goos != "linux" {
+		return ""
+	}
+
+	if loaders, _ := filepath.Glob(filepath.Join(root, "lib", "ld-musl-*.so.1")); len(loaders) > 0 {
+		return "musl"
+	}
+	return "gnu"
+}
+
+// detectrosetta returns true if the process is an amd64 binary translated by rosetta
+// on apple silicon.
+func detectrosetta(goos, goarch string) bool {
+	if goos != "darwin" || goarch != "amd64" {
+		return false
+	}
+
+	out, err := exec.Command("sysctl", "-n", "sysctl.proc_translated").Output()
+	return err == nil && string(bytes.TrimSpace(out)) == "1"
+}
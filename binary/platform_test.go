@@ -0,0 +1,89 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectGOARM(t *testing.T) {
+	t.Run("only for arm",
+		func(t *testing.T) {
+			t.Setenv("GOARM", "6")
+			assert.Empty(t, detectgoarm("arm64"))
+		},
+	)
+
+	t.Run("from env variable",
+		func(t *testing.T) {
+			t.Setenv("GOARM", "7,softfloat")
+			assert.Equal(t, "7", detectgoarm("arm"))
+		},
+	)
+
+	t.Run("from cpuinfo",
+		func(t *testing.T) {
+			tests := map[string]struct {
+				cpuinfo string
+				want    string
+			}{
+				"armv6":           {cpuinfo: "processor\t: 0\nmodel name\t: ARMv6-compatible processor rev 7 (v6l)\nCPU architecture: 6\n", want: "6"},
+				"armv7":           {cpuinfo: "processor\t: 0\nCPU architecture: 7\nCPU variant\t: 0x0\n", want: "7"},
+				"64 bit cpu":      {cpuinfo: "processor\t: 0\nCPU architecture: 8\n", want: "7"},
+				"no architecture": {cpuinfo: "processor\t: 0\n", want: ""},
+			}
+
+			for name, test := range tests {
+				t.Run(name,
+					func(t *testing.T) {
+						assert.Equal(t, test.want, cpuinfoarm(strings.NewReader(test.cpuinfo)))
+					},
+				)
+			}
+		},
+	)
+}
+
+func TestDetectLibc(t *testing.T) {
+	t.Run("glibc",
+		func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "lib"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "lib", "ld-linux-x86-64.so.2"), nil, 0o755))
+
+			assert.Equal(t, "gnu", detectlibc("linux", root))
+		},
+	)
+
+	t.Run("musl",
+		func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "lib"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(root, "lib", "ld-musl-x86_64.so.1"), nil, 0o755))
+
+			assert.Equal(t, "musl", detectlibc("linux", root))
+		},
+	)
+
+	t.Run("only on linux",
+		func(t *testing.T) {
+			assert.Empty(t, detectlibc("darwin", t.TempDir()))
+		},
+	)
+}
+
+func TestDetectRosetta(t *testing.T) {
+	assert.False(t, detectrosetta("linux", "amd64"))
+	assert.False(t, detectrosetta("darwin", "arm64"))
+}
+
+func TestCachePlatform(t *testing.T) {
+	assert.Equal(t, "linux_amd64", cacheplatform(Template{GOOS: "linux", GOARCH: "amd64", Libc: "gnu"}))
+	assert.Equal(t, "linux_amd64_musl", cacheplatform(Template{GOOS: "linux", GOARCH: "amd64", Libc: "musl"}))
+	assert.Equal(t, "linux_armv6", cacheplatform(Template{GOOS: "linux", GOARCH: "arm", GOARM: "6", Libc: "gnu"}))
+	assert.Equal(t, "darwin_arm64", cacheplatform(Template{GOOS: "darwin", GOARCH: "arm64"}))
+}
@@ -0,0 +1,100 @@
+package binary
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/fatih/color"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// ProgressFunc is called while the files of a binary are downloaded, with the bytes
+// downloaded so far and the total size of the file, which is -1 when it's unknown.
+type ProgressFunc func(done, total int64)
+
+// WithProgress reports the progress of the downloads of the binary to fn
+// instead of showing a progress bar.
+func WithProgress(fn ProgressFunc) Option {
+	return func(b *Binary) {
+		b.progress = fn
+	}
+}
+
+// WithoutProgress disables the progress bar shown while downloading the binary.
+//
+// By default the progress bar is only shown when the output is a terminal
+// and the verbosity is verbose or higher.
+func WithoutProgress() Option {
+	return func(b *Binary) {
+		b.progress = func(int64, int64) {}
+	}
+}
+
+type progresskey struct{}
+
+// withprogress returns a context making downloads report their progress to fn.
+func withprogress(ctx context.Context, fn ProgressFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progresskey{}, fn)
+}
+
+// progress wraps an io.Reader to report the progress of a download of size bytes,
+// resumed after the given amount of bytes.
+// Returns the wrapped reader and a function to finalize the progress reporting.
+//
+// Progress is reported to the [ProgressFunc] of the context, or displayed as a progress
+// bar, showing transfer speed and completion percentage, when running in a terminal.
+func progress(ctx context.Context, reader io.Reader, resumed, size int64) (io.Reader, func()) {
+	if fn, ok := ctx.Value(progresskey{}).(ProgressFunc); ok {
+		total := int64(-1)
+		if size >= 0 {
+			total = resumed + size
+		}
+		fn(resumed, total)
+		return &progressreader{reader: reader, done: resumed, total: total, report: fn}, func() {}
+	}
+
+	if !internal.IsTerminalWriter(internal.Output) || internal.CurrentVerbosity() < internal.VerbosityVerbose {
+		return reader, func() {}
+	}
+
+	bar := pb.
+		New64(size).
+		SetWriter(internal.Output).
+		SetTemplate(
+			pb.ProgressBarTemplate(
+				color.New(color.FgHiBlack).Sprint(
+					`   ` + internal.Symbols.Detail + ` {{string . "prefix"}}{{counters . }}` +
+						` {{bar . "[" "=" ">" " " "]" }} {{percent . }}` +
+						` {{speed . "%s/s" }}{{string . "suffix"}}`,
+				),
+			),
+		).
+		SetRefreshRate(time.Second / 60).
+		SetMaxWidth(100).
+		Start()
+
+	return bar.NewProxyReader(reader), func() { bar.Finish() }
+}
+
+// progressreader reports the bytes read through it to a [ProgressFunc].
+type progressreader struct {
+	reader io.Reader
+	done   int64
+	total  int64
+	report ProgressFunc
+}
+
+func (r *progressreader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.done += int64(n)
+		r.report(r.done, r.total)
+	}
+	return n, err
+}
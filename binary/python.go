@@ -0,0 +1,85 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// pythonpkg implements [Origin] for executables of python packages.
+type pythonpkg struct {
+	pkg string
+	bin string
+}
+
+// PythonPackage creates a new Origin that installs a python package into an isolated
+// virtualenv, exposing one of its executables as the binary, e.g. PythonPackage("pre-commit", "pre-commit").
+//
+// The virtualenv is created inside the bin directory and the executable is linked into it.
+// uv is used when found in PATH, falling back to the venv module and pip of python3 otherwise.
+func PythonPackage(pkg, bin string) Origin {
+	return &pythonpkg{
+		pkg: pkg,
+		bin: bin,
+	}
+}
+
+func (p *pythonpkg) digest() any {
+	return struct{ Package, Bin string }{p.pkg, p.bin}
+}
+
+func (p *pythonpkg) Install(ctx context.Context, template Template) error {
+	venv, err := filepath.Abs(filepath.Join(template.Directory, ".venv", template.Name))
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir %s: %w", template.Directory, err)
+	}
+
+	// start from a clean virtualenv so upgrades don't leave stale dependencies behind
+	if err := os.RemoveAll(venv); err != nil {
+		return fmt.Errorf("failed to remove previous virtualenv %s: %w", venv, err)
+	}
+
+	scripts := filepath.Join(venv, "bin")
+	executable := filepath.Join(scripts, p.bin)
+	if runtime.GOOS == "windows" {
+		scripts = filepath.Join(venv, "Scripts")
+		executable = filepath.Join(scripts, p.bin+".exe")
+	}
+
+	requirement := fmt.Sprintf("%s==%s", p.pkg, template.Version)
+	if template.Version == "latest" {
+		requirement = p.pkg
+	}
+
+	internal.LogStep(fmt.Sprintf("installing %s", requirement))
+	if _, err := exec.LookPath("uv"); err == nil {
+		if err := runcommand(ctx, "uv", "venv", "--quiet", venv); err != nil {
+			return fmt.Errorf("unable to create virtualenv: %w", err)
+		}
+		if err := runcommand(ctx, "uv", "pip", "install", "--quiet", "--python", venv, requirement); err != nil {
+			return fmt.Errorf("unable to install package: %w", err)
+		}
+	} else {
+		python := "python3"
+		if runtime.GOOS == "windows" {
+			python = "python"
+		}
+		if err := runcommand(ctx, python, "-m", "venv", venv); err != nil {
+			return fmt.Errorf("unable to create virtualenv: %w", err)
+		}
+		if err := runcommand(ctx, filepath.Join(scripts, "python"), "-m", "pip", "install", "--quiet", requirement); err != nil {
+			return fmt.Errorf("unable to install package: %w", err)
+		}
+	}
+
+	if _, err := os.Stat(executable); err != nil {
+		return fmt.Errorf("package %s has no executable %s: %w", p.pkg, p.bin, err)
+	}
+
+	return relink(executable, template.Cmd)
+}
@@ -0,0 +1,93 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPythonPackageOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake python tooling is made of shell scripts")
+	}
+
+	// tool is the fake executable installed by the fake package managers
+	const tool = `printf '#!/bin/sh\necho 1.35.1\n' > "$venv/bin/yamllint"; chmod +x "$venv/bin/yamllint"`
+
+	fakes := map[string]string{
+		// uv venv --quiet <venv> / uv pip install --quiet --python <venv> <requirement>
+		"uv": `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/uv.args"
+if [ "$1" = "venv" ]; then mkdir -p "$3/bin"; exit 0; fi
+venv="$5"
+[ "$6" = "yamllint==1.35.1" ] || exit 1
+` + tool + "\n",
+		// python3 -m venv <venv>, creating a python that installs with pip
+		"python3": `#!/bin/sh
+mkdir -p "$3/bin"
+cat > "$3/bin/python" <<'SCRIPT'
+#!/bin/sh
+venv="$(dirname "$(dirname "$0")")"
+[ "$5" = "yamllint==1.35.1" ] || exit 1
+` + tool + `
+SCRIPT
+chmod +x "$3/bin/python"
+`,
+	}
+
+	setup := func(t *testing.T, clis ...string) string {
+		t.Helper()
+		dir := t.TempDir()
+		for _, cli := range clis {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, cli), []byte(fakes[cli]), 0o755))
+		}
+		t.Setenv("PATH", dir+string(os.PathListSeparator)+"/usr/bin"+string(os.PathListSeparator)+"/bin")
+		return dir
+	}
+
+	t.Run("installs with uv",
+		func(t *testing.T) {
+			pathdir := setup(t, "uv", "python3")
+			withTempDir(t)
+
+			bin := New("yamllint", "1.35.1", PythonPackage("yamllint", "yamllint"))
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			target, err := os.Readlink(bin.BinPath())
+			require.NoError(t, err)
+			expected, err := filepath.Abs(filepath.Join("bin", ".venv", "yamllint", "bin", "yamllint"))
+			require.NoError(t, err)
+			assert.Equal(t, expected, target)
+
+			args, err := os.ReadFile(filepath.Join(pathdir, "uv.args"))
+			require.NoError(t, err)
+			assert.Contains(t, string(args), "pip install --quiet --python "+filepath.Dir(filepath.Dir(expected))+" yamllint==1.35.1")
+		},
+	)
+
+	t.Run("falls back to venv and pip",
+		func(t *testing.T) {
+			setup(t, "python3")
+			withTempDir(t)
+
+			bin := New("yamllint", "1.35.1", PythonPackage("yamllint", "yamllint"))
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.FileExists(t, bin.BinPath())
+		},
+	)
+
+	t.Run("fails when package can't be installed",
+		func(t *testing.T) {
+			setup(t, "uv")
+			withTempDir(t)
+
+			err := New("yamllint", "0.0.1", PythonPackage("yamllint", "yamllint")).Ensure(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unable to install package")
+		},
+	)
+}
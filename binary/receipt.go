@@ -0,0 +1,170 @@
+package binary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// receipt is recorded next to a binary after it has been provisioned and verified.
+// As long as the receipt matches the binary specification and the binary on disk
+// hasn't been modified, the binary is trusted without running its version command.
+type receipt struct {
+	Tool    string    `json:"tool"`
+	Version string    `json:"version"`
+	Origin  string    `json:"origin"`
+	ModTime time.Time `json:"mtime"`
+	// kind of origin the binary was provisioned from, for listing
+	Source string `json:"source,omitempty"`
+	// file name of the binary in the bin directory, including its extension
+	File string `json:"file,omitempty"`
+	// sha256 of the binary, empty for assets that are directories
+	Checksum string `json:"sha256,omitempty"`
+	// when the binary on disk was installed, kept while its contents don't change
+	Installed time.Time `json:"installed"`
+}
+
+// readReceipt reads the receipt recorded for the binary.
+func (b *Binary) readReceipt() (receipt, error) {
+	data, err := os.ReadFile(b.receiptPath())
+	if err != nil {
+		return receipt{}, err
+	}
+
+	var rcpt receipt
+	if err := json.Unmarshal(data, &rcpt); err != nil {
+		return receipt{}, fmt.Errorf("invalid receipt %s: %w", b.receiptPath(), err)
+	}
+	return rcpt, nil
+}
+
+// receiptPath returns the path of the receipt file for the binary.
+func (b *Binary) receiptPath() string {
+	return filepath.Join(b.template.Directory, fmt.Sprintf(".%s.receipt.json", b.template.Name))
+}
+
+// hasValidReceipt returns true if there's a receipt for the binary matching its
+// name, version and origin, recorded for the binary currently on disk.
+func (b *Binary) hasValidReceipt() bool {
+	rcpt, err := b.readReceipt()
+	if err != nil {
+		return false
+	}
+
+	info, err := os.Stat(b.template.Cmd)
+	if err != nil {
+		return false
+	}
+
+	return rcpt.Tool == b.template.Name &&
+		rcpt.Version == b.version &&
+		rcpt.Origin == origindigest(b.origin) &&
+		rcpt.ModTime.Equal(info.ModTime())
+}
+
+// writeReceipt records a receipt for the binary currently on disk.
+func (b *Binary) writeReceipt() error {
+	info, err := os.Stat(b.template.Cmd)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", b.template.Cmd, err)
+	}
+
+	var checksum string
+	if info.Mode().IsRegular() {
+		if checksum, err = sha256file(b.template.Cmd); err != nil {
+			return err
+		}
+	}
+
+	// binaries verified again keep the time they were installed at
+	installed := time.Now()
+	if previous, err := b.readReceipt(); err == nil && previous.Checksum == checksum && !previous.Installed.IsZero() {
+		installed = previous.Installed
+	}
+
+	data, err := json.Marshal(
+		receipt{
+			Tool:      b.template.Name,
+			Version:   b.version,
+			Origin:    origindigest(b.origin),
+			ModTime:   info.ModTime(),
+			Source:    originkind(b.origin),
+			File:      filepath.Base(b.template.Cmd),
+			Checksum:  checksum,
+			Installed: installed,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to encode receipt: %w", err)
+	}
+
+	if err := os.WriteFile(b.receiptPath(), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write receipt %s: %w", b.receiptPath(), err)
+	}
+
+	return nil
+}
+
+// touchReceipt updates the modification time of the receipt, which [List] reports as the
+// time the binary was last used.
+func (b *Binary) touchReceipt() {
+	now := time.Now()
+	if err := os.Chtimes(b.receiptPath(), now, now); err != nil {
+		internal.LogDetail(fmt.Sprintf("failed to update install receipt: %s", err))
+	}
+}
+
+// originkind returns the kind of the origin, e.g. "remotebin" for [RemoteBinaryDownload].
+func originkind(origin Origin) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", origin), "*binary.")
+}
+
+// digester is implemented by the origins of this package, describing their configuration
+// with values that are stable across runs, unlike pointers or funcs, so it can be digested.
+type digester interface {
+	digest() any
+}
+
+// origindigest returns a digest of the origin configuration, so changes in the
+// origin, like a different url or package, invalidate existing receipts.
+// Origins defined elsewhere are digested as printed, which is only stable across runs
+// as long as they don't hold pointers or funcs.
+func origindigest(origin Origin) string {
+	described := fmt.Sprintf("%+v", origin)
+	if d, ok := origin.(digester); ok {
+		if data, err := json.Marshal(d.digest()); err == nil {
+			described = string(data)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T %s", origin, described)))
+	return hex.EncodeToString(sum[:])
+}
+
+// sha256file returns the hex encoded sha256 of the file at path.
+func sha256file(path string) (sum string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closerr := file.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", path, closerr))
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
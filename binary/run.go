@@ -0,0 +1,33 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aexvir/harness"
+)
+
+// Command ensures the binary is installed and returns a runner for it, like [harness.Cmd]
+// with the path of the binary, so it can be started, stopped or run more than once.
+func (b *Binary) Command(ctx context.Context, opts ...harness.RunnerOpt) (*harness.TaskRunner, error) {
+	if b.asset {
+		return nil, fmt.Errorf("%s is an asset and can't be run", b.template.Name)
+	}
+
+	if err := b.Ensure(ctx); err != nil {
+		return nil, fmt.Errorf("failed to provision %s: %w", b.template.Name, err)
+	}
+
+	return harness.Cmd(ctx, b.BinPath(), opts...)
+}
+
+// Run ensures the binary is installed and runs it, like [harness.Run] with the path
+// of the binary, e.g. bin.Run(ctx, harness.WithArgs("lint", "./...")).
+func (b *Binary) Run(ctx context.Context, opts ...harness.RunnerOpt) error {
+	runner, err := b.Command(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	return runner.Exec()
+}
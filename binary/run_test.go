@@ -0,0 +1,68 @@
+package binary
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binary is a shell script")
+	}
+
+	srv := setupTestServer(t)
+
+	t.Run("ensures the binary before running it",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			var out bytes.Buffer
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			require.NoError(t, bin.Run(t.Context(), harness.WithStdOut(&out)))
+
+			assert.FileExists(t, bin.BinPath())
+			assert.Equal(t, "util version 1.2.3\n", out.String())
+		},
+	)
+
+	t.Run("returns a runner for the binary",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/util"))
+			runner, err := bin.Command(t.Context(), harness.WithArgs("--version"))
+			require.NoError(t, err)
+
+			path, err := filepath.Abs(bin.BinPath())
+			require.NoError(t, err)
+			assert.Equal(t, path, runner.Executable)
+		},
+	)
+
+	t.Run("fails when the binary can't be provisioned",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			err := New("util", "1.2.3", RemoteBinaryDownload(srv.URL+"/missing")).Run(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to provision util")
+		},
+	)
+
+	t.Run("refuses to run assets",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			err := NewAsset("include", "1.2.3", new(fakeorigin)).Run(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "can't be run")
+		},
+	)
+}
@@ -0,0 +1,130 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// scriptorigin implements [Origin] with a plain function.
+type scriptorigin struct {
+	install func(ctx context.Context, template Template) error
+}
+
+// FromScript creates a new Origin that installs the binary by calling the install function,
+// for one-off tools that don't justify implementing an [Origin] type of their own.
+// The function receives the [Template] of the binary and must place it at template.Cmd.
+func FromScript(install func(ctx context.Context, template Template) error) Origin {
+	return &scriptorigin{
+		install: install,
+	}
+}
+
+func (s *scriptorigin) digest() any {
+	// the install func can't be compared across runs, only the kind of origin is digested
+	return struct{}{}
+}
+
+func (s *scriptorigin) Install(ctx context.Context, template Template) error {
+	if err := os.MkdirAll(template.Directory, 0o755); err != nil {
+		return fmt.Errorf("failed to create destination folder %s: %w", template.Directory, err)
+	}
+
+	if err := s.install(ctx, template); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(template.Cmd); err != nil {
+		return fmt.Errorf("install script didn't install %s: %w", template.Cmd, err)
+	}
+	return nil
+}
+
+// installscript implements [Origin] for upstream install scripts.
+type installscript struct {
+	urlformat string
+	config    origincfg
+}
+
+// InstallScriptURL creates a new Origin that installs the binary by running the install.sh
+// script many tools publish, with sh. The URL can contain template variables that will be
+// resolved using the [Template] values during installation.
+// e.g. "https://raw.githubusercontent.com/foo/bar/v{{.Version}}/install.sh"
+//
+// Scripts usually take the version and the directory to install into as arguments, which are
+// passed via [WithScriptArgs]; the script must place the binary at template.Cmd.
+// Pass [WithChecksum] to verify the script against a known hash before running it.
+func InstallScriptURL(url string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &installscript{
+		urlformat: url,
+		config:    cfg,
+	}
+}
+
+// WithScriptArgs sets the arguments the script of [InstallScriptURL] is run with.
+// Arguments can contain template variables that will be resolved using the [Template] values,
+// e.g. WithScriptArgs("-b", "{{.Directory}}", "v{{.Version}}").
+func WithScriptArgs(args ...string) OriginOption {
+	return func(c *origincfg) {
+		c.scriptargs = args
+	}
+}
+
+func (i *installscript) digest() any {
+	return struct {
+		URL    string
+		Config any
+	}{i.urlformat, i.config.digest()}
+}
+
+func (i *installscript) Install(ctx context.Context, template Template) error {
+	url, err := template.Resolve(i.urlformat)
+	if err != nil {
+		return fmt.Errorf("failed to resolve URL: %w", err)
+	}
+
+	args := make([]string, 0, len(i.config.scriptargs)+1)
+	for _, arg := range i.config.scriptargs {
+		resolved, err := template.Resolve(arg)
+		if err != nil {
+			return fmt.Errorf("failed to resolve script argument %s: %w", arg, err)
+		}
+		args = append(args, resolved)
+	}
+
+	return FromScript(
+		func(ctx context.Context, template Template) error {
+			internal.LogStep(fmt.Sprintf("downloading install script from %s", url))
+			script, err := i.config.fetchtemp(ctx, url)
+			if err != nil {
+				return fmt.Errorf("failed to download install script: %w", err)
+			}
+			defer os.Remove(script)
+
+			sum, err := i.config.checksum(ctx, template, url)
+			if err != nil {
+				return err
+			}
+			if sum != nil {
+				if err := crcfile(script, *sum); err != nil {
+					return err
+				}
+			}
+
+			if err := i.config.verify(ctx, template, url, script); err != nil {
+				return err
+			}
+
+			if err := runcommand(ctx, "sh", append([]string{script}, args...)...); err != nil {
+				return fmt.Errorf("install script failed: %w", err)
+			}
+			return nil
+		},
+	).Install(ctx, template)
+}
@@ -0,0 +1,122 @@
+package binary
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromScriptOrigin(t *testing.T) {
+	t.Run("installs binary with function",
+		func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "bin")
+			tmpl := mktemplate(dir, "util", "1.2.3")
+
+			origin := FromScript(func(_ context.Context, template Template) error {
+				return os.WriteFile(template.Cmd, []byte(template.Version), 0o755)
+			})
+			require.NoError(t, origin.Install(t.Context(), tmpl))
+			assert.FileExists(t, tmpl.Cmd)
+		},
+	)
+
+	t.Run("returns function error",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := FromScript(func(context.Context, Template) error { return errors.New("boom") }).Install(t.Context(), tmpl)
+			require.EqualError(t, err, "boom")
+		},
+	)
+
+	t.Run("fails when binary isn't installed",
+		func(t *testing.T) {
+			tmpl := mktemplate(t.TempDir(), "util", "1.2.3")
+
+			err := FromScript(func(context.Context, Template) error { return nil }).Install(t.Context(), tmpl)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "install script didn't install")
+		},
+	)
+}
+
+func TestInstallScriptURLOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("install scripts require sh")
+	}
+
+	// script installs a binary printing the version into the directory passed with -b
+	script := `#!/bin/sh
+[ "$1" = "-b" ] || exit 1
+printf '#!/bin/sh\necho %s\n' "$3" > "$2/util"
+chmod +x "$2/util"
+`
+	sum := sha256.Sum256([]byte(script))
+
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1.2.3/install.sh" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = io.WriteString(w, script)
+		}),
+	)
+	t.Cleanup(srv.Close)
+
+	t.Run("runs script with arguments",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New(
+				"util", "1.2.3",
+				InstallScriptURL(
+					srv.URL+"/v{{.Version}}/install.sh",
+					WithScriptArgs("-b", "{{.Directory}}", "v{{.Version}}"),
+					WithChecksum("sha256:"+hex.EncodeToString(sum[:])),
+				),
+			)
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			// the version is verified with the installed binary
+			require.NoError(t, New("util", "1.2.3", InstallScriptURL(srv.URL+"/v{{.Version}}/install.sh"), WithForceVerify(true)).Ensure(t.Context()))
+		},
+	)
+
+	t.Run("doesn't run script on checksum mismatch",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			origin := InstallScriptURL(
+				srv.URL+"/v{{.Version}}/install.sh",
+				WithScriptArgs("-b", "{{.Directory}}", "v{{.Version}}"),
+				WithChecksum("sha256:"+sha256hex(t, "testdata/util")),
+			)
+			err := New("util", "1.2.3", origin).Ensure(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "checksum mismatch")
+			assert.NoFileExists(t, filepath.Join("bin", "util"))
+		},
+	)
+
+	t.Run("fails when script fails",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			err := New("util", "1.2.3", InstallScriptURL(srv.URL+"/v{{.Version}}/install.sh")).Ensure(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "install script failed")
+		},
+	)
+}
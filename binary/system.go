@@ -0,0 +1,34 @@
+package binary
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// system implements [Origin] for binaries provided by the system, found in PATH.
+type system struct{}
+
+// System creates a new Origin for binaries that aren't provisioned but expected to be
+// installed on the system, like docker or node, which can't sensibly be downloaded.
+// The binary is resolved from PATH, which is also the path returned by [Binary.BinPath],
+// and its version is verified on every [Binary.Ensure] call.
+// Nothing is ever installed; if the binary is missing or its version doesn't match, Ensure
+// fails explaining what's needed. Use "latest" or [WithVersionCmd] with [SkipVersionCheck]
+// to accept any version.
+func System() Origin {
+	return &system{}
+}
+
+func (s *system) digest() any {
+	return struct{}{}
+}
+
+func (s *system) Install(ctx context.Context, template Template) error {
+	path, err := exec.LookPath(template.Name)
+	if err != nil {
+		return fmt.Errorf("%s was not found in PATH; install version %s of %s to continue", template.Name, template.Version, template.Name)
+	}
+
+	return fmt.Errorf("%w: %s found at %s is not version %s; install version %s of %s to continue", ErrVersionMismatch, template.Name, path, template.Version, template.Version, template.Name)
+}
@@ -0,0 +1,58 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake system binary is a shell script")
+	}
+
+	pathdir := t.TempDir()
+	script := "#!/bin/sh\necho 'systool version 1.2.3'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(pathdir, "systool"), []byte(script), 0o755))
+	t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Run("uses binary from PATH",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("systool", "1.2.3", System())
+			assert.Equal(t, filepath.Join(pathdir, "systool"), bin.BinPath())
+			require.NoError(t, bin.Ensure(t.Context()))
+
+			// nothing is recorded in the bin directory
+			assert.NoDirExists(t, "bin")
+		},
+	)
+
+	t.Run("fails on version mismatch",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			err := New("systool", "2.0.0", System()).Ensure(t.Context())
+			require.ErrorIs(t, err, ErrVersionMismatch)
+			assert.Contains(t, err.Error(), "is not version 2.0.0")
+		},
+	)
+
+	t.Run("fails when binary is missing",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("missingtool", "1.0.0", System())
+			assert.Equal(t, "missingtool", bin.BinPath())
+
+			err := bin.Ensure(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "missingtool was not found in PATH")
+		},
+	)
+}
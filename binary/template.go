@@ -1,8 +1,11 @@
 package binary
 
 import (
+	"os"
 	"strings"
 	"text/template"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Template contains fields used to resolve specific metadata about the binary.
@@ -12,6 +15,13 @@ type Template struct {
 	GOOS string
 	// GOARCH is the architecture target (e.g., "amd64", "arm64")
 	GOARCH string
+	// GOARM is the arm version (e.g., "6", "7") when GOARCH is "arm"
+	GOARM string
+	// Libc is the C library flavor on linux, "musl" (e.g., on alpine) or "gnu"
+	Libc string
+	// Rosetta is true when running as an amd64 process translated by rosetta on apple silicon,
+	// where arm64 binaries can be used instead
+	Rosetta bool
 
 	// Directory where the binary is located
 	Directory string
@@ -26,12 +36,48 @@ type Template struct {
 	Extension string
 	// ArchiveExtension is the archive extension for the archive containing the binary.
 	ArchiveExtension string
+	// Asset is true when provisioning a non-executable file, like a data file or a
+	// directory with headers, instead of an executable binary.
+	Asset bool
+	// Vars are custom fields set with [WithTemplateVar], e.g. {{.Vars.channel}}
+	Vars map[string]string
+}
+
+// templatefuncs are the functions available when resolving templates; like in sprig, the
+// string being transformed is the last argument, so they can be used in pipelines,
+// e.g. {{.GOOS | replace "darwin" "macos" | title}}.
+var templatefuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"title": func(s string) string {
+		first, size := utf8.DecodeRuneInString(s)
+		if size == 0 {
+			return s
+		}
+		return string(unicode.ToUpper(first)) + s[size:]
+	},
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+}
+
+// FileMode returns the permissions that provisioned files should have.
+// Binaries are made executable, while assets are only readable and writable.
+func (t Template) FileMode() os.FileMode {
+	if t.Asset {
+		return 0o644
+	}
+	return 0o755
 }
 
 // Resolve executes the provided format string as a template with the Template's fields.
 // It returns the resolved string and any error that occurred during template parsing or execution.
+//
+// Besides the fields, templates can use the lower, upper, title, replace, trimPrefix and
+// trimSuffix functions, e.g. {{upper .GOOS}} or {{.Version | trimPrefix "v"}}.
+// Referencing custom fields that weren't set is an error.
 func (t Template) Resolve(format string) (string, error) {
-	tmpl, err := template.New("bin").Parse(format)
+	tmpl, err := template.New("bin").Funcs(templatefuncs).Option("missingkey=error").Parse(format)
 	if err != nil {
 		return "", err
 	}
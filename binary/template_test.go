@@ -16,6 +16,7 @@ func TestTemplateResolve(t *testing.T) {
 		Cmd:              "bin/util",
 		Version:          "1.2.3",
 		ArchiveExtension: ".tar.gz",
+		Vars:             map[string]string{"channel": "stable"},
 	}
 
 	tests := map[string]struct {
@@ -51,6 +52,26 @@ func TestTemplateResolve(t *testing.T) {
 			format:  "{{.NonExistent}}",
 			wantErr: true,
 		},
+		"custom field": {
+			format:       "https://example.com/{{.Vars.channel}}/{{.Version}}",
+			wantResolved: "https://example.com/stable/1.2.3",
+		},
+		"unknown custom field": {
+			format:  "{{.Vars.nightly}}",
+			wantErr: true,
+		},
+		"case functions": {
+			format:       "{{upper .GOOS}}-{{title .GOOS}}-{{lower \"AMD64\"}}",
+			wantResolved: "LINUX-Linux-amd64",
+		},
+		"functions in pipelines": {
+			format:       "{{.GOARCH | replace \"amd64\" \"x86_64\"}}_{{\"v1.2.3\" | trimPrefix \"v\"}}_{{.ArchiveExtension | trimSuffix \".gz\"}}",
+			wantResolved: "x86_64_1.2.3_.tar",
+		},
+		"title of empty string": {
+			format:       "{{title \"\"}}",
+			wantResolved: "",
+		},
 	}
 
 	for name, test := range tests {
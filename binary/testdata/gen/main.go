@@ -8,8 +8,11 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"time"
@@ -67,6 +70,31 @@ func main() {
 	}
 	fmt.Println("created multi.tar.gz")
 
+	// 6. util.tar.gz uncompressed and recompressed with other formats; needs the bzip2, xz and zstd clis
+	for ext, cli := range map[string][]string{
+		".tar":     {"cat"},
+		".tar.bz2": {"bzip2", "-c"},
+		".tar.xz":  {"xz", "-c"},
+		".tar.zst": {"zstd", "-c", "-q"},
+	} {
+		if err := recompress(filepath.Join(dir, "util.tar.gz"), filepath.Join(dir, "util"+ext), cli...); err != nil {
+			fatal(err)
+		}
+		fmt.Println("created util" + ext)
+	}
+
+	// 7. util compressed as a single file, without tar
+	if err := createGz(filepath.Join(dir, "util.gz"), binaryContent); err != nil {
+		fatal(err)
+	}
+	fmt.Println("created util.gz")
+
+	// 8. 7z with util at root
+	if err := create7z(filepath.Join(dir, "util.7z"), "util", binaryContent); err != nil {
+		fatal(err)
+	}
+	fmt.Println("created util.7z")
+
 	fmt.Println("done")
 }
 
@@ -119,6 +147,46 @@ func createTarGz(path string, files map[string]string) error {
 	return nil
 }
 
+func createGz(path, content string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+
+	_, err = gw.Write([]byte(content))
+	return err
+}
+
+// recompress decompresses the tar.gz archive and compresses it again with the cli.
+func recompress(targz, path string, cli ...string) error {
+	in, err := os.Open(targz)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cmd := exec.Command(cli[0], cli[1:]...)
+	cmd.Stdin = gr
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 func createZip(path string, files map[string]string) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -150,6 +218,47 @@ func createZip(path string, files map[string]string) error {
 	return nil
 }
 
+// create7z writes a 7z archive with a single file stored with the copy method.
+// There's no 7z writer for go, so the archive is encoded by hand; every number
+// written fits in a single byte, which is how 7z encodes numbers below 0x80.
+func create7z(path, name, content string) error {
+	var fname []byte
+	for _, r := range name + "\x00" {
+		fname = append(fname, byte(r), 0)
+	}
+
+	attributes := make([]byte, 4)
+	// unix permissions live in the high 16 bits, flagged by 0x8000
+	binary.LittleEndian.PutUint32(attributes, 0x8000|(0o100755<<16))
+
+	header := []byte{
+		0x01,                                             // header
+		0x04,                                             // main streams info
+		0x06, 0x00, 0x01, 0x09, byte(len(content)), 0x00, // pack info: position, streams, sizes
+		0x07, 0x0b, 0x01, 0x00, // unpack info: one folder, not external
+		0x01, 0x01, 0x00, // one coder with a one byte id: copy
+		0x0c, byte(len(content)), 0x00, // unpack sizes
+		0x00,       // end of streams info
+		0x05, 0x01, // files info: one file
+		0x11, byte(len(fname) + 1), 0x00, // names, not external
+	}
+	header = append(header, fname...)
+	header = append(header, 0x15, 0x06, 0x01, 0x00) // attributes, all defined, not external
+	header = append(header, attributes...)
+	header = append(header, 0x00, 0x00) // end of files info and header
+
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start[0:], uint64(len(content)))
+	binary.LittleEndian.PutUint64(start[8:], uint64(len(header)))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(header))
+
+	signature := []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0x00, 0x04}
+	signature = binary.LittleEndian.AppendUint32(signature, crc32.ChecksumIEEE(start))
+
+	archive := append(append(append(signature, start...), content...), header...)
+	return os.WriteFile(path, archive, 0o644)
+}
+
 func fatal(err error) {
 	fmt.Fprintf(os.Stderr, "error: %v\n", err)
 	os.Exit(1)
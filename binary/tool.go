@@ -0,0 +1,178 @@
+package binary
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gotool implements [Origin] for tools declared in the go.mod file of the project.
+type gotool struct {
+	name   string
+	config origincfg
+}
+
+// GoTool creates a new Origin that installs a tool declared with a tool directive in the
+// go.mod file of the project, e.g. "tool gotest.tools/gotestsum", using 'go install'
+// targetting the local bin directory. The tool is built at the version of the module
+// providing it in the go.mod file, so tool versions are managed like any other dependency,
+// e.g. added with 'go get -tool' and updated by dependabot.
+//
+// The name matches the name of the binary of the tool, e.g. "gotestsum", or its full
+// package path. Pass an empty version to [New] to use the version from the go.mod file.
+//
+// The same options as [GoBinary] are supported.
+func GoTool(name string, options ...OriginOption) Origin {
+	var cfg origincfg
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &gotool{
+		name:   name,
+		config: cfg,
+	}
+}
+
+func (o *gotool) digest() any {
+	return struct {
+		Name   string
+		Config any
+	}{o.name, o.config.digest()}
+}
+
+func (o *gotool) Install(ctx context.Context, template Template) error {
+	tool, err := o.resolve()
+	if err != nil {
+		return err
+	}
+
+	// without a version, go install builds the package at the version required by the module
+	return goinstall(ctx, template, tool.pkg, tool.pkg, o.config)
+}
+
+// resolve looks up the tool in the go.mod file of the project.
+func (o *gotool) resolve() (gomodtool, error) {
+	gomod, err := findgomod()
+	if err != nil {
+		return gomodtool{}, err
+	}
+
+	tools, err := readgomodtools(gomod)
+	if err != nil {
+		return gomodtool{}, err
+	}
+
+	for _, tool := range tools {
+		if tool.pkg == o.name || gobinname(tool.pkg) == o.name {
+			return tool, nil
+		}
+	}
+	return gomodtool{}, fmt.Errorf("tool %s not declared in %s", o.name, gomod)
+}
+
+// gomodtool is a tool declared in a go.mod file.
+type gomodtool struct {
+	pkg string
+	// version of the module providing the tool, empty for tools of the module itself
+	version string
+}
+
+// findgomod returns the path of the go.mod file of the module in the working directory,
+// looking for it in the parent directories like the go cli does.
+func findgomod() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	for {
+		path := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod file not found")
+		}
+		dir = parent
+	}
+}
+
+// readgomodtools returns the tools declared in the go.mod file at path, along with the
+// version of the required module providing each of them.
+func readgomodtools(path string) (tools []gomodtool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		if closerr := file.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", path, closerr))
+		}
+	}()
+
+	requires := map[string]string{}
+	var pkgs []string
+
+	// directive of the block being read, e.g. "require" for require ( ... )
+	var block string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		directive := block
+		switch {
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		case block == "":
+			directive, fields = fields[0], fields[1:]
+		}
+
+		for i, field := range fields {
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				fields[i] = unquoted
+			}
+		}
+
+		switch {
+		case directive == "require" && len(fields) >= 2:
+			requires[fields[0]] = fields[1]
+		case directive == "tool" && len(fields) >= 1:
+			pkgs = append(pkgs, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	for _, pkg := range pkgs {
+		tool := gomodtool{pkg: pkg}
+
+		// the module providing the tool is the required one with the longest matching path
+		var provider string
+		for module, version := range requires {
+			if (pkg == module || strings.HasPrefix(pkg, module+"/")) && len(module) > len(provider) {
+				provider, tool.version = module, version
+			}
+		}
+
+		tools = append(tools, tool)
+	}
+
+	return tools, nil
+}
@@ -0,0 +1,107 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const toolgomod = `module example.com/project
+
+go 1.24
+
+tool (
+	github.com/golangci/golangci-lint/v2/cmd/golangci-lint
+	gotest.tools/gotestsum // test runner
+	example.com/project/cmd/gen
+)
+
+tool "golang.org/x/tools/cmd/stringer"
+
+require github.com/golangci/golangci-lint/v2 v2.1.6
+
+require (
+	golang.org/x/tools v0.33.0 // indirect
+	golang.org/x/tools/cmd/stringer v0.1.0 // indirect
+	gotest.tools/gotestsum v1.12.2 // indirect
+)
+`
+
+func TestReadGoModTools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go.mod")
+	require.NoError(t, os.WriteFile(path, []byte(toolgomod), 0o644))
+
+	tools, err := readgomodtools(path)
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]gomodtool{
+			{pkg: "github.com/golangci/golangci-lint/v2/cmd/golangci-lint", version: "v2.1.6"},
+			{pkg: "gotest.tools/gotestsum", version: "v1.12.2"},
+			{pkg: "example.com/project/cmd/gen"},
+			{pkg: "golang.org/x/tools/cmd/stringer", version: "v0.1.0"},
+		},
+		tools,
+	)
+}
+
+func TestGoToolOrigin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+
+	// fake go records its arguments and installs a binary named after the package
+	pathdir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/go.args"
+for last; do :; done
+printf '#!/bin/sh\necho "v1.12.2"\n' > "$GOBIN/${last##*/}"
+chmod +x "$GOBIN/${last##*/}"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(pathdir, "go"), []byte(script), 0o755))
+	t.Setenv("PATH", pathdir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	t.Run("installs tool at the version in go.mod",
+		func(t *testing.T) {
+			dir := withTempDir(t)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(toolgomod), 0o644))
+
+			bin := New("gotestsum", "", GoTool("gotestsum"))
+			assert.Equal(t, "v1.12.2", bin.version)
+
+			require.NoError(t, bin.Ensure(t.Context()))
+			assert.FileExists(t, bin.BinPath())
+
+			args, err := os.ReadFile(filepath.Join(pathdir, "go.args"))
+			require.NoError(t, err)
+			assert.Equal(t, "install gotest.tools/gotestsum\n", string(args))
+		},
+	)
+
+	t.Run("finds go.mod in parent directories",
+		func(t *testing.T) {
+			dir := withTempDir(t)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(toolgomod), 0o644))
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+			require.NoError(t, os.Chdir(filepath.Join(dir, "sub")))
+
+			tmpl := mktemplate("bin", "lint", "")
+			require.NoError(t, GoTool("github.com/golangci/golangci-lint/v2/cmd/golangci-lint").Install(t.Context(), tmpl))
+			assert.FileExists(t, filepath.Join("bin", "lint"))
+		},
+	)
+
+	t.Run("fails for undeclared tools",
+		func(t *testing.T) {
+			dir := withTempDir(t)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(toolgomod), 0o644))
+
+			err := GoTool("goimports").Install(t.Context(), mktemplate("bin", "goimports", ""))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "tool goimports not declared")
+		},
+	)
+}
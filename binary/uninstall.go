@@ -0,0 +1,92 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// Uninstall removes the binary from the bin directory, along with its install receipt and
+// the files some origins keep next to it, like the virtualenvs of [PythonPackage] or the
+// node modules of [NpmPackage]. Other files extracted from the same archive are kept.
+// Binaries provided by the [System] can't be uninstalled.
+func (b *Binary) Uninstall(ctx context.Context) error {
+	if b.system {
+		return fmt.Errorf("%s is provided by the system and can't be uninstalled", b.template.Name)
+	}
+
+	return b.withlock(ctx, func() error {
+		internal.LogStep(fmt.Sprintf("uninstalling %s", b.template.Name))
+
+		paths := []string{
+			b.template.Cmd,
+			b.receiptPath(),
+			b.latestPath(),
+			filepath.Join(b.template.Directory, ".npm", b.template.Name),
+			filepath.Join(b.template.Directory, ".venv", b.template.Name),
+			filepath.Join(b.template.Directory, ".cargo", b.template.Name),
+		}
+
+		var errs []error
+		for _, path := range paths {
+			if err := os.RemoveAll(path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Clean removes the bin directory along with every binary provisioned into it, so stale
+// versions don't accumulate. If dir is empty, the default bin directory is used, ./bin unless
+// overridden in the harness.yaml config file. Binaries are provisioned again when ensured.
+func Clean(dir string) error {
+	if dir == "" {
+		dir = defaultbindir()
+	}
+
+	if err := removable(dir); err != nil {
+		return err
+	}
+
+	internal.LogStep(fmt.Sprintf("removing %s", dir))
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove bin directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// removable guards against configs pointing the bin directory at a directory that must never
+// be removed: the filesystem root, the home directory of the user, or any directory containing
+// them or the working directory, like the project itself.
+func removable(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir %s: %w", dir, err)
+	}
+
+	if filepath.Dir(abs) == abs {
+		return fmt.Errorf("refusing to remove %s, which is the filesystem root", dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil && within(abs, home) {
+		return fmt.Errorf("refusing to remove %s, which contains the home directory", dir)
+	}
+	if cwd, err := os.Getwd(); err == nil && within(abs, cwd) {
+		return fmt.Errorf("refusing to remove %s, which contains the working directory", dir)
+	}
+	return nil
+}
+
+// within returns true if path is parent or any directory inside it.
+func within(parent, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
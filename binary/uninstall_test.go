@@ -0,0 +1,92 @@
+package binary
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUninstall(t *testing.T) {
+	t.Run("removes the binary and its metadata",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			bin := New("util", "1.0.0", new(fakeorigin))
+			require.NoError(t, bin.Install(t.Context()))
+			require.NoError(t, os.MkdirAll(filepath.Join("bin", ".venv", "util"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join("bin", "other"), nil, 0o755))
+			assert.FileExists(t, bin.receiptPath())
+
+			require.NoError(t, bin.Uninstall(t.Context()))
+			assert.NoFileExists(t, bin.BinPath())
+			assert.NoFileExists(t, bin.receiptPath())
+			assert.NoDirExists(t, filepath.Join("bin", ".venv", "util"))
+			assert.FileExists(t, filepath.Join("bin", "other"))
+		},
+	)
+
+	t.Run("succeeds when not installed",
+		func(t *testing.T) {
+			withTempDir(t)
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin)).Uninstall(t.Context()))
+		},
+	)
+
+	t.Run("refuses system binaries",
+		func(t *testing.T) {
+			withTempDir(t)
+			err := New("go", "", System()).Uninstall(t.Context())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "provided by the system")
+		},
+	)
+}
+
+func TestClean(t *testing.T) {
+	t.Run("removes the bin directory",
+		func(t *testing.T) {
+			withTempDir(t)
+			require.NoError(t, New("util", "1.0.0", new(fakeorigin)).Install(t.Context()))
+
+			require.NoError(t, Clean(""))
+			assert.NoDirExists(t, "bin")
+		},
+	)
+
+	t.Run("refuses to remove the working directory",
+		func(t *testing.T) {
+			dir := withTempDir(t)
+			require.Error(t, Clean("."))
+			require.Error(t, Clean(filepath.Dir(dir)))
+			assert.DirExists(t, dir)
+		},
+	)
+
+	t.Run("refuses to remove the home directory",
+		func(t *testing.T) {
+			withTempDir(t)
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("USERPROFILE", home)
+
+			err := Clean(home)
+			require.ErrorContains(t, err, "contains the home directory")
+			require.Error(t, Clean(filepath.Dir(home)))
+			assert.DirExists(t, home)
+		},
+	)
+
+	t.Run("refuses to remove the filesystem root",
+		func(t *testing.T) {
+			withTempDir(t)
+
+			// checked through the guard alone, as a regression would wipe the filesystem
+			root, err := filepath.Abs(string(filepath.Separator))
+			require.NoError(t, err)
+			require.ErrorContains(t, removable(root), "filesystem root")
+		},
+	)
+}
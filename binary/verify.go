@@ -0,0 +1,77 @@
+package binary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// verifier checks the authenticity of a file downloaded from url before it's installed.
+type verifier func(ctx context.Context, config origincfg, template Template, url, file string) error
+
+// verify runs all the configured verifiers on the file downloaded from url.
+func (c origincfg) verify(ctx context.Context, template Template, url, file string) error {
+	for _, verify := range c.verifiers {
+		if err := verify(ctx, c, template, url, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchtemp downloads url into a temporary file, returning its path.
+// The caller is responsible for removing the file.
+func (c origincfg) fetchtemp(ctx context.Context, url string) (path string, err error) {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() {
+		if closerr := resp.Body.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close http response body: %w", closerr))
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", responseerror("file", url, resp)
+	}
+
+	out, err := os.CreateTemp("", "harness-verify-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if closerr := out.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", out.Name(), closerr))
+		}
+		if err != nil {
+			_ = os.Remove(out.Name())
+		}
+	}()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	return out.Name(), nil
+}
+
+// runcommand runs a command, including its output in the returned error.
+func runcommand(ctx context.Context, name string, args ...string) error {
+	internal.LogDetail(fmt.Sprintf("running %s %s", name, strings.Join(args, " ")))
+
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if output := strings.TrimSpace(string(out)); output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// WithVersionRegexp extracts the version from the output of the version command with the
+// regular expression, using its first capture group if it has one, and compares it with the
+// expected version as semver, e.g. `version (\S+)`.
+//
+// By default the expected version is looked up in the output, so "1.2" is found in the
+// output of version "1.21.0".
+func WithVersionRegexp(pattern string) Option {
+	return func(b *Binary) {
+		expr, err := regexp.Compile(pattern)
+		if err != nil {
+			b.err = fmt.Errorf("invalid version regexp %q: %w", pattern, err)
+			return
+		}
+		b.versionexpr = expr
+	}
+}
+
+// WithMajorMinorVersionMatch only compares the major and minor versions of the binary,
+// so any patch release of the expected version is accepted, e.g. 1.21.3 for 1.21.0.
+// The version is extracted from the output of the version command like with [WithVersionRegexp],
+// with a pattern matching the first semver-like version if none is set.
+func WithMajorMinorVersionMatch() Option {
+	return func(b *Binary) {
+		b.versionprecision = 2
+	}
+}
+
+// WithVersionJSONPath reads the version from the json output of the version command at the
+// path, with keys separated by dots, e.g. "clientVersion.gitVersion" for kubectl, and compares
+// it with the expected version as semver, like [WithVersionRegexp].
+// The version command usually needs to be set too, e.g. WithVersionCmd("%s version -o json").
+func WithVersionJSONPath(path string) Option {
+	return func(b *Binary) {
+		b.versionjson = strings.Split(path, ".")
+	}
+}
+
+// semverexpr matches the first semver-like version in the output of version commands.
+var semverexpr = regexp.MustCompile(`v?\d+(?:\.\d+)*(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?`)
+
+// matchesversion returns true if the output of the version command reports the expected version.
+func (b *Binary) matchesversion(output []byte) bool {
+	precision := b.versionprecision
+	if precision == 0 {
+		precision = 3
+	}
+
+	if b.versionjson != nil {
+		found, err := jsonversion(output, b.versionjson)
+		if err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to read version from output: %s", err))
+			return false
+		}
+		return semverequal(found, b.version, precision)
+	}
+
+	if b.versionexpr == nil && b.versionprecision == 0 {
+		return bytes.Contains(output, []byte(strings.TrimPrefix(b.version, "v")))
+	}
+
+	expr := b.versionexpr
+	if expr == nil {
+		expr = semverexpr
+	}
+
+	match := expr.FindSubmatch(output)
+	if match == nil {
+		return false
+	}
+	found := match[0]
+	if len(match) > 1 {
+		found = match[1]
+	}
+
+	return semverequal(string(found), b.version, precision)
+}
+
+// jsonversion reads the version at the path of the json document in the output,
+// ignoring anything printed before it, like warnings.
+func jsonversion(output []byte, path []string) (string, error) {
+	start := bytes.IndexAny(output, "{[")
+	if start < 0 {
+		return "", errors.New("no json found")
+	}
+
+	var value any
+	if err := json.NewDecoder(bytes.NewReader(output[start:])).Decode(&value); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+
+	for i, key := range path {
+		switch node := value.(type) {
+		case map[string]any:
+			value = node[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", fmt.Errorf("no %s in output", strings.Join(path[:i+1], "."))
+			}
+			value = node[index]
+		default:
+			return "", fmt.Errorf("no %s in output", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	switch version := value.(type) {
+	case string:
+		return version, nil
+	case float64:
+		return strconv.FormatFloat(version, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("no version at %s in output", strings.Join(path, "."))
+	}
+}
+
+// semver is a parsed semantic version.
+type semver struct {
+	// major, minor and patch, missing ones being zero
+	core       [3]int
+	prerelease string
+}
+
+// parsesemver parses versions like v1.2.3, 1.2 or 1.2.3-rc.1+build, ignoring build metadata.
+func parsesemver(version string) (semver, error) {
+	var parsed semver
+
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, parsed.prerelease, _ = strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	if len(parts) > len(parsed.core) {
+		return semver{}, fmt.Errorf("invalid version %q", version)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return semver{}, fmt.Errorf("invalid version %q: %w", version, err)
+		}
+		parsed.core[i] = number
+	}
+
+	return parsed, nil
+}
+
+// semverequal returns true if both versions are equal up to the precision,
+// 3 comparing the whole version and 2 comparing major and minor only.
+// Versions that aren't semver are compared as they are.
+func semverequal(a, b string, precision int) bool {
+	first, err := parsesemver(a)
+	if err != nil {
+		return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+	}
+	second, err := parsesemver(b)
+	if err != nil {
+		return false
+	}
+
+	if !slices.Equal(first.core[:precision], second.core[:precision]) {
+		return false
+	}
+	return precision < 3 || first.prerelease == second.prerelease
+}
@@ -0,0 +1,57 @@
+package binary
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		output  string
+		options []Option
+		want    bool
+	}{
+		{name: "substring by default", version: "1.2", output: "util version 1.21.0", want: true},
+		{name: "regexp rejects prefix matches", version: "1.2", output: "util version 1.21.0", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: false},
+		{name: "regexp matches equal versions", version: "v1.21.0", output: "util version 1.21.0", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: true},
+		{name: "regexp without groups", version: "1.21.0", output: "util 1.21.0 (abcdef)", options: []Option{WithVersionRegexp(`\d+\.\d+\.\d+`)}, want: true},
+		{name: "missing components are zero", version: "1.21", output: "util version 1.21.0", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: true},
+		{name: "prereleases must match", version: "1.21.0", output: "util version 1.21.0-rc.1", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: false},
+		{name: "build metadata is ignored", version: "1.21.0", output: "util version 1.21.0+abcdef", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: true},
+		{name: "no match", version: "1.21.0", output: "util", options: []Option{WithVersionRegexp(`version (\S+)`)}, want: false},
+		{name: "non semver versions are compared as they are", version: "2024-05-01", output: "util build 2024-05-01", options: []Option{WithVersionRegexp(`build (\S+)`)}, want: true},
+		{name: "major minor accepts patch releases", version: "1.21.0", output: "go version go1.21.3 linux/amd64", options: []Option{WithMajorMinorVersionMatch()}, want: true},
+		{name: "major minor rejects minor releases", version: "1.21.0", output: "go version go1.22.0 linux/amd64", options: []Option{WithMajorMinorVersionMatch()}, want: false},
+		{name: "json path", version: "v1.33.1", output: `{"clientVersion":{"gitVersion":"v1.33.1","major":"1"}}`, options: []Option{WithVersionJSONPath("clientVersion.gitVersion")}, want: true},
+		{name: "json path rejects other versions", version: "1.33.0", output: `{"clientVersion":{"gitVersion":"v1.33.1"}}`, options: []Option{WithVersionJSONPath("clientVersion.gitVersion")}, want: false},
+		{name: "json path skips warnings", version: "2.1.6", output: "WARN outdated config\n{\"version\":\"2.1.6\",\"commit\":\"abc\"}\n", options: []Option{WithVersionJSONPath("version")}, want: true},
+		{name: "json path with indices", version: "3.1", output: `{"components":[{"version":3.1}]}`, options: []Option{WithVersionJSONPath("components.0.version")}, want: true},
+		{name: "json path missing", version: "2.1.6", output: `{"commit":"abc"}`, options: []Option{WithVersionJSONPath("version")}, want: false},
+		{name: "json path on text output", version: "2.1.6", output: "version 2.1.6", options: []Option{WithVersionJSONPath("version")}, want: false},
+		{name: "major minor with regexp", version: "v2.1", output: "tool 2.1.9 (go1.24.0)", options: []Option{WithVersionRegexp(`tool (\S+)`), WithMajorMinorVersionMatch()}, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name,
+			func(t *testing.T) {
+				bin := New("util", test.version, new(fakeorigin), test.options...)
+				require.NoError(t, bin.err)
+				assert.Equal(t, test.want, bin.matchesversion([]byte(test.output)))
+			},
+		)
+	}
+}
+
+func TestInvalidVersionRegexp(t *testing.T) {
+	withTempDir(t)
+
+	origin := new(fakeorigin)
+	err := New("util", "1.0.0", origin, WithVersionRegexp(`version (`)).Ensure(t.Context())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid version regexp")
+	assert.False(t, origin.installed)
+}
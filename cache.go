@@ -0,0 +1,179 @@
+package harness
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// CacheDir is the directory where the state of cached tasks is stored.
+var CacheDir = filepath.Join(".harness", "cache")
+
+// CacheKey computes a fingerprint of the inputs of a task; the task is considered
+// up to date as long as the fingerprint doesn't change.
+type CacheKey func(ctx context.Context) (string, error)
+
+// CacheKeyFromFiles computes the fingerprint from the paths and contents of all files in
+// the current directory matching any of the patterns.
+// Patterns use forward slashes and support "**" to match any amount of directories,
+// e.g. "**/*.go" or "go.sum"; the .git and .harness directories are never considered.
+func CacheKeyFromFiles(patterns ...string) CacheKey {
+	return func(ctx context.Context) (string, error) {
+		var files []string
+
+		err := filepath.WalkDir(".", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if entry.IsDir() {
+				if name == ".git" || name == ".harness" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			slashed := filepath.ToSlash(name)
+			for _, pattern := range patterns {
+				if globmatch(pattern, slashed) {
+					files = append(files, name)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to list cache inputs: %w", err)
+		}
+
+		slices.Sort(files)
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%q\n", patterns) //nolint:errcheck
+
+		for _, name := range files {
+			if err := hashfile(hash, name); err != nil {
+				return "", err
+			}
+		}
+
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+}
+
+// Cached skips running the task when the fingerprint of its inputs, computed using the key,
+// didn't change since the last time the task succeeded.
+// The fingerprint is stored under [CacheDir], identified by the place where Cached is called
+// from along with the task name; see [Named].
+func Cached(task Task, key CacheKey) Task {
+	_, file, line, _ := runtime.Caller(1)
+	callsite := fmt.Sprintf("%s:%d", file, line)
+
+	return func(ctx context.Context) error {
+		name := "task"
+		state := currenttask(ctx)
+		if state != nil {
+			name = state.displayname()
+		}
+
+		fingerprint, err := key(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to compute cache key: %w", err)
+		}
+
+		entry := filepath.Join(CacheDir, cacheentry(callsite, name))
+
+		if stored, err := os.ReadFile(entry); err == nil && string(stored) == fingerprint {
+			internal.LogStep(fmt.Sprintf("%s is up to date, skipping", name))
+			if state != nil {
+				state.setcached()
+			}
+			return nil
+		}
+
+		if err := task(ctx); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(CacheDir, 0o755); err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to create cache dir: %s", err))
+			return nil
+		}
+		if err := os.WriteFile(entry, []byte(fingerprint), 0o644); err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to record cache entry: %s", err))
+		}
+
+		return nil
+	}
+}
+
+// cacheentry returns the name of the file storing the fingerprint of a task.
+func cacheentry(callsite, name string) string {
+	hash := sha256.Sum256([]byte(callsite + "\n" + name))
+	return hex.EncodeToString(hash[:16])
+}
+
+// hashfile writes the name and content of the file to the hash.
+func hashfile(hash io.Writer, name string) (err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open cache input %s: %w", name, err)
+	}
+	defer func() {
+		if closerr := f.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", name, closerr))
+		}
+	}()
+
+	fmt.Fprintf(hash, "%s\n", filepath.ToSlash(name)) //nolint:errcheck
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to read cache input %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// globmatch reports whether the slash separated name matches the pattern, where
+// "**" segments match any amount of directories.
+func globmatch(pattern, name string) bool {
+	return matchsegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchsegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchsegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,124 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"go.sum", "go.sum", true},
+		{"go.sum", "sub/go.sum", false},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/sub/main.go", true},
+		{"**/*.go", "pkg/sub/main.txt", false},
+		{"pkg/**", "pkg/sub/main.go", true},
+		{"pkg/**/*.go", "pkg/main.go", true},
+		{"pkg/**/*.go", "other/main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name,
+			func(t *testing.T) {
+				assert.Equal(t, tt.want, globmatch(tt.pattern, tt.name))
+			},
+		)
+	}
+}
+
+func TestCached(t *testing.T) {
+	setup := func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		require.NoError(t, os.MkdirAll("pkg", 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join("pkg", "main.go"), []byte("package main"), 0o644))
+		require.NoError(t, os.WriteFile("readme.md", []byte("readme"), 0o644))
+	}
+
+	t.Run("key changes only when matching files change",
+		func(t *testing.T) {
+			setup(t)
+			key := CacheKeyFromFiles("**/*.go")
+
+			first, err := key(t.Context())
+			require.NoError(t, err)
+
+			require.NoError(t, os.WriteFile("readme.md", []byte("changed"), 0o644))
+			second, err := key(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, first, second)
+
+			require.NoError(t, os.WriteFile(filepath.Join("pkg", "main.go"), []byte("package changed"), 0o644))
+			third, err := key(t.Context())
+			require.NoError(t, err)
+			assert.NotEqual(t, first, third)
+		},
+	)
+
+	t.Run("skips task when inputs didn't change",
+		func(t *testing.T) {
+			setup(t)
+
+			var runs int
+			task := Cached(
+				func(_ context.Context) error { runs++; return nil },
+				CacheKeyFromFiles("**/*.go"),
+			)
+
+			h := New()
+
+			require.NoError(t, h.Execute(t.Context(), task))
+			results, err := h.ExecuteWithResults(t.Context(), task)
+			require.NoError(t, err)
+			assert.Equal(t, 1, runs)
+			assert.True(t, results[0].Cached)
+
+			require.NoError(t, os.WriteFile(filepath.Join("pkg", "main.go"), []byte("package changed"), 0o644))
+			require.NoError(t, h.Execute(t.Context(), task))
+			assert.Equal(t, 2, runs)
+		},
+	)
+
+	t.Run("doesn't cache failures",
+		func(t *testing.T) {
+			setup(t)
+
+			var runs int
+			task := Cached(
+				func(_ context.Context) error { runs++; return errors.New("boom") },
+				CacheKeyFromFiles("**/*.go"),
+			)
+
+			require.Error(t, task(t.Context()))
+			require.Error(t, task(t.Context()))
+			assert.Equal(t, 2, runs)
+		},
+	)
+
+	t.Run("tasks with the same inputs are cached separately",
+		func(t *testing.T) {
+			setup(t)
+
+			var lint, test int
+			key := CacheKeyFromFiles("**/*.go")
+			linttask := Cached(func(_ context.Context) error { lint++; return nil }, key)
+			testtask := Cached(func(_ context.Context) error { test++; return nil }, key)
+
+			require.NoError(t, linttask(t.Context()))
+			require.NoError(t, testtask(t.Context()))
+			assert.Equal(t, 1, lint)
+			assert.Equal(t, 1, test)
+		},
+	)
+}
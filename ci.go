@@ -0,0 +1,115 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// sections delimits the output of each task in the job log of a ci system, so it can be collapsed.
+type sections interface {
+	open(id, title string)
+	close(id string)
+}
+
+// githubsections uses github actions workflow commands to group log lines.
+// https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions#grouping-log-lines
+type githubsections struct{}
+
+func (githubsections) open(_, title string) {
+	fmt.Fprintf(internal.Output, "::group::%s\n", title) //nolint:errcheck
+}
+
+func (githubsections) close(_ string) {
+	fmt.Fprintln(internal.Output, "::endgroup::") //nolint:errcheck
+}
+
+// gitlabsections uses gitlab ci section markers to make log lines collapsible.
+// https://docs.gitlab.com/ci/jobs/job_logs/#custom-collapsible-sections
+type gitlabsections struct{}
+
+func (gitlabsections) open(id, title string) {
+	fmt.Fprintf(internal.Output, "\x1b[0Ksection_start:%d:%s\r\x1b[0K%s\n", time.Now().Unix(), id, title) //nolint:errcheck
+}
+
+func (gitlabsections) close(id string) {
+	fmt.Fprintf(internal.Output, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), id) //nolint:errcheck
+}
+
+// isgithubactions reports if the current environment is github actions.
+func isgithubactions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// isgitlabci reports if the current environment is gitlab ci.
+func isgitlabci() bool {
+	return os.Getenv("GITLAB_CI") == "true"
+}
+
+// sectionid builds an identifier for the section of a task, only made of characters
+// accepted by all ci systems.
+func sectionid(idx int, name string) string {
+	id := strings.Map(
+		func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		},
+		name,
+	)
+	return fmt.Sprintf("task_%d_%s", idx+1, id)
+}
+
+// writestepsummary appends a markdown table with the results of the tasks to the
+// github actions step summary file.
+func writestepsummary(results []TaskResult) (err error) {
+	summary := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summary == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(summary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if closerr := f.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("fatal: couldn't close file %s: %s", summary, closerr))
+		}
+	}()
+
+	_, err = f.WriteString(stepsummary(results))
+	return err
+}
+
+// stepsummary renders the results of the tasks as a markdown table.
+func stepsummary(results []TaskResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("## Task summary\n\n")
+	sb.WriteString("| Task | Status | Duration | Error |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+
+	escape := strings.NewReplacer("|", `\|`, "\n", "<br>")
+
+	for _, result := range results {
+		duration, errmsg := result.Duration.Round(time.Millisecond).String(), ""
+		if result.Skipped {
+			duration = ""
+		}
+		if result.Err != nil {
+			errmsg = result.Err.Error()
+		}
+
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", escape.Replace(result.Name), result.status(), duration, escape.Replace(errmsg))
+	}
+
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,175 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/internal"
+)
+
+func TestGitHubActions(t *testing.T) {
+	t.Run("groups the output of every task",
+		func(t *testing.T) {
+			buf := captureoutput(t)
+			h := New(WithGitHubActions(true))
+
+			err := h.Execute(t.Context(),
+				Named("lint", func(_ context.Context) error { return nil }),
+				func(ctx context.Context) error {
+					return Run(ctx, "sh", WithArgs("testdata/util.sh", "success"))
+				},
+			)
+
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), "::group::lint\n::endgroup::\n")
+			assert.Contains(t, buf.String(), "::group::task 2\n")
+			assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("::endgroup::")))
+		},
+	)
+
+	t.Run("groups output when detected",
+		func(t *testing.T) {
+			t.Setenv("GITLAB_CI", "")
+			t.Setenv("GITHUB_ACTIONS", "true")
+			buf := captureoutput(t)
+			h := New()
+
+			err := h.Execute(t.Context(), Named("lint", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), "::group::lint\n::endgroup::\n")
+		},
+	)
+
+	t.Run("doesn't group output outside github actions",
+		func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", "")
+			buf := captureoutput(t)
+			h := New()
+
+			err := h.Execute(t.Context(), Named("lint", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), "::group::")
+		},
+	)
+
+	t.Run("can be disabled",
+		func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", "true")
+			summary := filepath.Join(t.TempDir(), "summary.md")
+			t.Setenv("GITHUB_STEP_SUMMARY", summary)
+			buf := captureoutput(t)
+			h := New(WithGitHubStepSummary(true), WithGitHubActions(false))
+
+			err := h.Execute(t.Context(), Named("lint", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), "::group::")
+			assert.NoFileExists(t, summary)
+		},
+	)
+
+	t.Run("writes step summary",
+		func(t *testing.T) {
+			summary := filepath.Join(t.TempDir(), "summary.md")
+			t.Setenv("GITHUB_STEP_SUMMARY", summary)
+
+			h := New(WithGitHubStepSummary(true))
+
+			err := h.Execute(t.Context(),
+				Named("lint", func(_ context.Context) error { return nil }),
+				Named("test", func(_ context.Context) error { return errors.New("boom | bang") }),
+			)
+			require.Error(t, err)
+
+			content, err := os.ReadFile(summary)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), "| Task | Status | Duration | Error |")
+			assert.Regexp(t, `\| lint \| passed \| \S+ \|  \|`, string(content))
+			assert.Regexp(t, `\| test \| failed \| \S+ \| boom \\\| bang \|`, string(content))
+		},
+	)
+}
+
+func TestStepSummary(t *testing.T) {
+	summary := stepsummary([]TaskResult{
+		{Name: "lint", Duration: 1500 * time.Millisecond},
+		{Name: "test", Duration: time.Second, Err: errors.New("line one\nline two")},
+		{Name: "task 3", Skipped: true},
+	})
+
+	assert.Equal(t,
+		"## Task summary\n\n"+
+			"| Task | Status | Duration | Error |\n"+
+			"| --- | --- | --- | --- |\n"+
+			"| lint | passed | 1.5s |  |\n"+
+			"| test | failed | 1s | line one<br>line two |\n"+
+			"| task 3 | skipped |  |  |\n\n",
+		summary,
+	)
+}
+
+func TestSectionID(t *testing.T) {
+	assert.Equal(t, "task_1_lint", sectionid(0, "lint"))
+	assert.Equal(t, "task_3_go_test__integration_", sectionid(2, "go test (integration)"))
+}
+
+func TestGitLabCI(t *testing.T) {
+	t.Run("wraps tasks in sections when detected",
+		func(t *testing.T) {
+			t.Setenv("GITLAB_CI", "true")
+			buf := captureoutput(t)
+			h := New()
+
+			err := h.Execute(t.Context(), Named("go test", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.Regexp(t, `\x1b\[0Ksection_start:\d+:task_1_go_test\r\x1b\[0Kgo test\n`, buf.String())
+			assert.Regexp(t, `\x1b\[0Ksection_end:\d+:task_1_go_test\r\x1b\[0K\n`, buf.String())
+		},
+	)
+
+	t.Run("can be disabled",
+		func(t *testing.T) {
+			t.Setenv("GITLAB_CI", "true")
+			buf := captureoutput(t)
+			h := New(WithGitLabCI(false))
+
+			err := h.Execute(t.Context(), Named("lint", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), "section_start")
+		},
+	)
+
+	t.Run("not used outside gitlab",
+		func(t *testing.T) {
+			t.Setenv("GITLAB_CI", "")
+			buf := captureoutput(t)
+			h := New()
+
+			err := h.Execute(t.Context(), Named("lint", func(_ context.Context) error { return nil }))
+
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), "section_start")
+		},
+	)
+}
+
+// captureoutput redirects harness logs to a buffer for the duration of the test.
+func captureoutput(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	prev := internal.Output
+	internal.Output = &buf
+	t.Cleanup(func() { internal.Output = prev })
+	return &buf
+}
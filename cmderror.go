@@ -0,0 +1,97 @@
+package harness
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// stderrtail is how many bytes of the end of stderr are kept for [CmdError].
+const stderrtail = 4 << 10
+
+// CmdError is the error returned when running a command fails, carrying the details
+// of the command so callers can inspect them via errors.As.
+type CmdError struct {
+	// Executable and Args of the command that failed, with secrets masked.
+	Executable string
+	Args       []string
+	// ExitCode of the command, or -1 if it didn't exit on its own, like when it failed
+	// to start or was terminated by a signal.
+	ExitCode int
+	// Stderr holds the last lines the command wrote to stderr, along with stdout when both
+	// are written to the same place, like with [WithCombinedOutput] or when output is quiet.
+	// It's only captured when stderr isn't written straight to a file or terminal, like
+	// when using [WithStdErr] with a buffer or capturing the output of tasks.
+	Stderr string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *CmdError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Executable, e.Err)
+}
+
+func (e *CmdError) Unwrap() error {
+	return e.Err
+}
+
+// fail wraps the error in a [CmdError] with the details of the command.
+func (r *TaskRunner) fail(err error) error {
+	args := make([]string, len(r.Arguments))
+	for idx, arg := range r.Arguments {
+		args[idx] = internal.Mask(arg, r.secrets...)
+	}
+
+	cmderr := CmdError{
+		Executable: r.Executable,
+		Args:       args,
+		ExitCode:   r.ExitCode(),
+		Err:        err,
+	}
+
+	if r.stderr != nil {
+		cmderr.Stderr = internal.Mask(r.stderr.String(), r.secrets...)
+	}
+
+	return &cmderr
+}
+
+// capturestderr keeps the tail of the command stderr around for reporting failures,
+// making sure commands writing stdout and stderr to the same writer keep doing so.
+// Files are left alone, so commands keep writing to them directly rather than through
+// a pipe, which would make waiting for them depend on any processes they leave behind.
+func (r *TaskRunner) capturestderr() {
+	if r.stderr != nil {
+		return
+	}
+	if _, ok := r.cmd.Stderr.(*os.File); ok || internal.IsTerminalWriter(r.cmd.Stderr) {
+		return
+	}
+
+	r.stderr = internal.NewTailWriter(stderrtail)
+
+	if r.cmd.Stderr == nil {
+		r.cmd.Stderr = r.stderr
+		return
+	}
+
+	writer := io.MultiWriter(r.cmd.Stderr, r.stderr)
+	if samewriter(r.cmd.Stdout, r.cmd.Stderr) {
+		r.cmd.Stdout = writer
+	}
+	r.cmd.Stderr = writer
+}
+
+// samewriter reports if both writers are the same, without panicking on
+// writers that can't be compared.
+func samewriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+
+	return a == b
+}
@@ -0,0 +1,61 @@
+package commons
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aexvir/harness"
+)
+
+// changeset configures formatting tasks to operate only on the files changed in git,
+// instead of the whole codebase.
+type changeset struct {
+	base   string
+	staged bool
+}
+
+// enabled returns true if the task should only consider changed files.
+func (c changeset) enabled() bool {
+	return c.base != "" || c.staged
+}
+
+// gofiles returns the go files that have been changed, relative to the working directory.
+// Deleted files are ignored, while untracked files are included unless only staged
+// changes are considered.
+func (c changeset) gofiles(ctx context.Context) ([]string, error) {
+	args := []string{"diff", "--name-only", "--relative", "--diff-filter=ACMR"}
+	if c.staged {
+		args = append(args, "--cached")
+	}
+	if c.base != "" {
+		args = append(args, c.base)
+	}
+
+	changed, err := harness.Output(ctx, "git", harness.WithArgs(args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+
+	if !c.staged {
+		untracked, err := harness.Output(ctx, "git", harness.WithArgs("ls-files", "--others", "--exclude-standard"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list untracked files: %w", err)
+		}
+		changed += "\n" + untracked
+	}
+
+	return filtergofiles(changed), nil
+}
+
+// filtergofiles returns the go files out of a newline separated list of paths.
+func filtergofiles(paths string) []string {
+	var files []string
+	for _, path := range strings.Split(paths, "\n") {
+		path = strings.TrimSpace(path)
+		if strings.HasSuffix(path, ".go") {
+			files = append(files, path)
+		}
+	}
+	return files
+}
@@ -0,0 +1,16 @@
+package commons
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterGoFiles(t *testing.T) {
+	assert.Equal(t,
+		[]string{"main.go", "pkg/util.go"},
+		filtergofiles("main.go\nreadme.md\npkg/util.go\ngo.mod\n\n"),
+	)
+
+	assert.Empty(t, filtergofiles(""))
+}
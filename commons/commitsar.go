@@ -34,7 +34,7 @@ func Commitsar(opts ...CommitsarOpt) harness.Task {
 			binary.WithVersionCmd("%s version"),
 		)
 
-		if err := cmsr.Ensure(); err != nil {
+		if err := cmsr.Ensure(ctx); err != nil {
 			return fmt.Errorf("failed to provision commitsar binary: %w", err)
 		}
 
@@ -0,0 +1,74 @@
+package commons
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
+)
+
+// Doctor checks the state of a list of binaries without changing anything, see [binary.Binary.Diagnose],
+// and prints a report of the ones that are missing, don't match their version, were modified
+// since they were installed, can't be installed or are shadowed by other executables in PATH.
+// Fails if any binary isn't healthy.
+func Doctor(binaries ...*binary.Binary) harness.Task {
+	return func(ctx context.Context) error {
+		harness.LogStep(fmt.Sprintf("checking %d binaries", len(binaries)))
+
+		var unhealthy int
+		for _, bin := range binaries {
+			diagnosis := bin.Diagnose(ctx)
+			if !diagnosis.Healthy() {
+				unhealthy++
+			}
+			printdiagnosis(diagnosis)
+		}
+
+		if unhealthy > 0 {
+			return fmt.Errorf("%d of %d binaries need attention", unhealthy, len(binaries))
+		}
+		return nil
+	}
+}
+
+// printdiagnosis prints the state of the binary followed by its problems.
+func printdiagnosis(d binary.Diagnosis) {
+	summary := fmt.Sprintf("%s %s %s", d.Name, d.Version, color.HiBlackString(d.Path))
+	if d.Healthy() {
+		internal.LogSuccess(summary)
+	} else {
+		internal.LogError(summary)
+	}
+
+	for _, problem := range diagnosisproblems(d) {
+		internal.LogErrorItem(problem)
+	}
+	if d.Shadowed != "" {
+		internal.LogMessage(color.FgYellow, fmt.Sprintf("   %s shadowed by %s in PATH", harness.Symbols.Detail, d.Shadowed))
+	}
+}
+
+// diagnosisproblems describes the problems found with the binary.
+func diagnosisproblems(d binary.Diagnosis) []string {
+	var problems []string
+	if !d.Installed {
+		problems = append(problems, "not installed")
+	}
+	if d.VersionMismatch {
+		problems = append(problems, fmt.Sprintf("installed version doesn't match %s", d.Version))
+	}
+	if d.ChecksumDrift {
+		problems = append(problems, "modified since it was installed")
+	}
+	if d.Unwritable != nil {
+		problems = append(problems, fmt.Sprintf("can't be installed: %s", d.Unwritable))
+	}
+	if d.Unreachable != nil {
+		problems = append(problems, fmt.Sprintf("origin unreachable: %s", d.Unreachable))
+	}
+	return problems
+}
@@ -0,0 +1,33 @@
+package commons
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aexvir/harness/binary"
+)
+
+func TestDiagnosisProblems(t *testing.T) {
+	assert.Empty(t, diagnosisproblems(binary.Diagnosis{Installed: true, Shadowed: "/usr/bin/util"}))
+
+	assert.Equal(t,
+		[]string{
+			"not installed",
+			"can't be installed: bin isn't writable",
+			"origin unreachable: http404",
+		},
+		diagnosisproblems(
+			binary.Diagnosis{
+				Unwritable:  errors.New("bin isn't writable"),
+				Unreachable: errors.New("http404"),
+			},
+		),
+	)
+
+	assert.Equal(t,
+		[]string{"installed version doesn't match 1.2.3", "modified since it was installed"},
+		diagnosisproblems(binary.Diagnosis{Installed: true, Version: "1.2.3", VersionMismatch: true, ChecksumDrift: true}),
+	)
+}
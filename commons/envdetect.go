@@ -64,4 +64,14 @@ func IsCIEnv() bool {
 	return os.Getenv("CI") != ""
 }
 
+// IsGitHubActions returns true if the current environment is github actions.
+func IsGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// IsGitLabCI returns true if the current environment is gitlab ci.
+func IsGitLabCI() bool {
+	return os.Getenv("GITLAB_CI") == "true"
+}
+
 func noop(ctx context.Context) error { return nil }
@@ -7,13 +7,57 @@ import (
 )
 
 // GoFmt runs gofmt and formats code in place.
-func GoFmt() harness.Task {
+func GoFmt(opts ...GoFmtOpt) harness.Task {
+	var conf gofmtconf
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
 	return func(ctx context.Context) error {
+		args := []string{"-w", "-s"}
+
+		if conf.changes.enabled() {
+			files, err := conf.changes.gofiles(ctx)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				harness.LogStep("no changed go files to format")
+				return nil
+			}
+			args = append(args, files...)
+		} else {
+			args = append(args, ".")
+		}
+
 		return harness.Run(
 			ctx,
 			"gofmt",
-			harness.WithArgs("-w", "-s", "."),
+			harness.WithArgs(args...),
 			harness.WithErrMsg("failed to format code"),
 		)
 	}
 }
+
+type gofmtconf struct {
+	changes changeset
+}
+
+type GoFmtOpt func(c *gofmtconf)
+
+// WithGoFmtChangedSince limits formatting to the go files that changed compared
+// to the specified git ref, including uncommitted changes; e.g. "origin/main".
+func WithGoFmtChangedSince(ref string) GoFmtOpt {
+	return func(c *gofmtconf) {
+		c.changes.base = ref
+	}
+}
+
+// WithGoFmtStagedOnly limits formatting to the go files currently staged in git.
+// This is mostly useful when running as part of a pre-commit hook.
+func WithGoFmtStagedOnly() GoFmtOpt {
+	return func(c *gofmtconf) {
+		c.changes.staged = true
+	}
+}
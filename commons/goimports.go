@@ -28,16 +28,33 @@ func GoImports(localpkg string, opts ...GoImportsOpt) harness.Task {
 			),
 		)
 
-		if err := imp.Ensure(); err != nil {
+		if err := imp.Ensure(ctx); err != nil {
 			return fmt.Errorf("failed to provision goimports: %w", err)
 		}
 
-		return harness.Run(ctx, imp.BinPath(), harness.WithArgs("-w", "-local", localpkg, "."))
+		args := []string{"-w", "-local", localpkg}
+
+		if conf.changes.enabled() {
+			files, err := conf.changes.gofiles(ctx)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				harness.LogStep("no changed go files to format")
+				return nil
+			}
+			args = append(args, files...)
+		} else {
+			args = append(args, ".")
+		}
+
+		return harness.Run(ctx, imp.BinPath(), harness.WithArgs(args...))
 	}
 }
 
 type goimportsconf struct {
 	version string
+	changes changeset
 }
 
 type GoImportsOpt func(c *goimportsconf)
@@ -49,3 +66,19 @@ func WithGoImportsVersion(version string) GoImportsOpt {
 		c.version = version
 	}
 }
+
+// WithGoImportsChangedSince limits formatting to the go files that changed compared
+// to the specified git ref, including uncommitted changes; e.g. "origin/main".
+func WithGoImportsChangedSince(ref string) GoImportsOpt {
+	return func(c *goimportsconf) {
+		c.changes.base = ref
+	}
+}
+
+// WithGoImportsStagedOnly limits formatting to the go files currently staged in git.
+// This is mostly useful when running as part of a pre-commit hook.
+func WithGoImportsStagedOnly() GoImportsOpt {
+	return func(c *goimportsconf) {
+		c.changes.staged = true
+	}
+}
@@ -8,10 +8,9 @@ import (
 	"os"
 	"strings"
 
-	"github.com/fatih/color"
-
 	"github.com/aexvir/harness"
 	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
 )
 
 // GolangCILint aggregates multiple linters that analyze go code.
@@ -39,7 +38,7 @@ func GolangCILint(opts ...GolangCILintOpt) harness.Task {
 			),
 		)
 
-		if err := gci.Ensure(); err != nil {
+		if err := gci.Ensure(ctx); err != nil {
 			return fmt.Errorf("failed to provision golangci-lint binary: %w", err)
 		}
 
@@ -53,7 +52,8 @@ func GolangCILint(opts ...GolangCILintOpt) harness.Task {
 
 		if conf.codeclimate {
 			ccformat := []string{"--output.code-climate.path", conf.codeclimatefile}
-			if strings.HasPrefix(conf.version, "1.") {
+			// the version can be overridden in the config file, so it's read from the binary
+			if strings.HasPrefix(gci.Version(), "1.") {
 				ccformat = []string{"--out-format", fmt.Sprintf("code-climate:%s", conf.codeclimatefile)}
 			}
 
@@ -65,11 +65,11 @@ func GolangCILint(opts ...GolangCILintOpt) harness.Task {
 
 					var issues []linterissue
 					if jsonerr := json.NewDecoder(bytes.NewBuffer(output)).Decode(&issues); jsonerr != nil {
-						color.Red("failed to parse codeclimate output")
+						internal.LogError("failed to parse codeclimate output")
 					}
 
 					for _, issue := range issues {
-						color.Red("  %s %s:%d        %s", harness.Symbols.Dot, issue.Location.Path, issue.Location.Lines.Begin, issue.Description)
+						internal.LogErrorItem(fmt.Sprintf("%s:%d        %s", issue.Location.Path, issue.Location.Lines.Begin, issue.Description))
 					}
 				}
 			}()
@@ -0,0 +1,293 @@
+package commons
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
+)
+
+// GoLicenses checks the licenses of the dependencies of the project, failing when any
+// of them is forbidden.
+//
+// By default licenses go-licenses classifies as forbidden, like AGPL, and unknown licenses
+// make the task fail. With an allow list, only the allowed licenses are accepted instead,
+// and licenses in the deny list are always rejected.
+// A csv report, as generated by go-licenses, or a spdx report can be written too.
+//
+// https://github.com/google/go-licenses
+// https://spdx.org/licenses
+func GoLicenses(opts ...GoLicensesOpt) harness.Task {
+	conf := golicensesconf{
+		version:  "latest",
+		target:   "./...",
+		csvfile:  "licenses.csv",
+		spdxfile: "licenses.spdx.json",
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return func(ctx context.Context) error {
+		gl := binary.New(
+			"go-licenses",
+			conf.version,
+			binary.GoBinary("github.com/google/go-licenses/v2"),
+			binary.WithVersionCmd(binary.SkipVersionCheck),
+		)
+
+		if err := gl.Ensure(ctx); err != nil {
+			return fmt.Errorf("failed to provision go-licenses: %w", err)
+		}
+
+		// the report is only needed for the deny list and the report files
+		if len(conf.denied) > 0 || conf.csv || conf.spdx {
+			report, err := harness.Output(ctx, gl.BinPath(), harness.WithArgs(golicensesargs("report", conf)...))
+			if err != nil {
+				return err
+			}
+
+			dependencies, err := golicensesreport(report)
+			if err != nil {
+				return err
+			}
+
+			if conf.csv {
+				if err := os.WriteFile(conf.csvfile, []byte(report+"\n"), 0o644); err != nil {
+					return fmt.Errorf("failed to write licenses csv report: %w", err)
+				}
+			}
+			if conf.spdx {
+				if err := writespdx(conf.spdxfile, dependencies); err != nil {
+					return err
+				}
+			}
+
+			if denied := deniedlicenses(dependencies, conf.denied); len(denied) > 0 {
+				for _, dep := range denied {
+					internal.LogErrorItem(fmt.Sprintf("%s        %s", dep.pkg, dep.license))
+				}
+				return fmt.Errorf("%d dependencies use denied licenses", len(denied))
+			}
+		}
+
+		return harness.Run(
+			ctx,
+			gl.BinPath(),
+			harness.WithArgs(golicensesargs("check", conf)...),
+			harness.WithErrMsg("dependencies with forbidden licenses found"),
+		)
+	}
+}
+
+// golicensesargs builds the arguments for the go-licenses command, either check or report.
+func golicensesargs(command string, conf golicensesconf) []string {
+	args := []string{command, conf.target}
+	for _, prefix := range conf.ignored {
+		args = append(args, "--ignore", prefix)
+	}
+
+	if command != "check" {
+		return args
+	}
+
+	if len(conf.allowed) > 0 {
+		return append(args, "--allowed_licenses", strings.Join(conf.allowed, ","))
+	}
+	return append(args, "--disallowed_types", "forbidden,unknown")
+}
+
+// licenseddependency is a row of the csv report of go-licenses.
+type licenseddependency struct {
+	pkg     string
+	url     string
+	license string
+}
+
+// golicensesreport parses the csv report of go-licenses, with the package,
+// the url of its license and the license on each row.
+func golicensesreport(report string) ([]licenseddependency, error) {
+	reader := csv.NewReader(strings.NewReader(report))
+	reader.FieldsPerRecord = 3
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go-licenses report: %w", err)
+	}
+
+	dependencies := make([]licenseddependency, 0, len(records))
+	for _, record := range records {
+		dependencies = append(dependencies, licenseddependency{pkg: record[0], url: record[1], license: record[2]})
+	}
+	return dependencies, nil
+}
+
+// deniedlicenses returns the dependencies using any of the denied licenses.
+func deniedlicenses(dependencies []licenseddependency, denied []string) []licenseddependency {
+	var found []licenseddependency
+	for _, dep := range dependencies {
+		if slices.ContainsFunc(denied, func(license string) bool { return strings.EqualFold(license, dep.license) }) {
+			found = append(found, dep)
+		}
+	}
+	return found
+}
+
+// writespdx writes a spdx 2.3 document in json format listing the dependencies and their licenses.
+// https://spdx.github.io/spdx-spec/v2.3
+func writespdx(filename string, dependencies []licenseddependency) error {
+	type spdxpackage struct {
+		Name             string `json:"name"`
+		SPDXID           string `json:"SPDXID"`
+		DownloadLocation string `json:"downloadLocation"`
+		FilesAnalyzed    bool   `json:"filesAnalyzed"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		CopyrightText    string `json:"copyrightText"`
+	}
+
+	packages := make([]spdxpackage, 0, len(dependencies))
+	for idx, dep := range dependencies {
+		license := dep.license
+		if license == "" || strings.EqualFold(license, "unknown") {
+			license = "NOASSERTION"
+		}
+		location := dep.url
+		if location == "" || strings.EqualFold(location, "unknown") {
+			location = "NOASSERTION"
+		}
+		packages = append(packages,
+			spdxpackage{
+				Name:             dep.pkg,
+				SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", idx+1),
+				DownloadLocation: location,
+				LicenseConcluded: license,
+				LicenseDeclared:  license,
+				CopyrightText:    "NOASSERTION",
+			},
+		)
+	}
+
+	// the namespace must be unique for each version of the document
+	packagesjson, err := json.Marshal(packages)
+	if err != nil {
+		return fmt.Errorf("failed to encode spdx packages: %w", err)
+	}
+	digest := sha256.Sum256(packagesjson)
+
+	document := map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              "dependency-licenses",
+		"documentNamespace": "https://spdx.org/spdxdocs/dependency-licenses-" + hex.EncodeToString(digest[:8]),
+		"creationInfo": map[string]any{
+			"created":  time.Now().UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: harness"},
+		},
+		"packages": packages,
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode spdx report: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write spdx report: %w", err)
+	}
+	return nil
+}
+
+type golicensesconf struct {
+	version string
+	target  string
+	allowed []string
+	denied  []string
+	ignored []string
+
+	csv      bool
+	csvfile  string
+	spdx     bool
+	spdxfile string
+}
+
+type GoLicensesOpt func(c *golicensesconf)
+
+// WithGoLicensesVersion allows specifying the go-licenses version
+// that should be used when running this task.
+func WithGoLicensesVersion(version string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.version = version
+	}
+}
+
+// WithGoLicensesAllowed only accepts dependencies using the specified licenses,
+// as spdx identifiers, e.g. "MIT", "Apache-2.0" or "BSD-3-Clause".
+func WithGoLicensesAllowed(licenses ...string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.allowed = append(c.allowed, licenses...)
+	}
+}
+
+// WithGoLicensesDenied rejects dependencies using the specified licenses,
+// as spdx identifiers, e.g. "GPL-3.0".
+func WithGoLicensesDenied(licenses ...string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.denied = append(c.denied, licenses...)
+	}
+}
+
+// WithGoLicensesIgnored skips the packages with the specified path prefixes,
+// e.g. the module of the project itself if it has no license.
+func WithGoLicensesIgnored(prefixes ...string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.ignored = append(c.ignored, prefixes...)
+	}
+}
+
+// WithGoLicensesTarget specifies the packages whose dependencies are checked, ./... by default.
+func WithGoLicensesTarget(target string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.target = target
+	}
+}
+
+// WithGoLicensesCSV controls if a csv report file should be generated.
+func WithGoLicensesCSV(enabled bool) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.csv = enabled
+	}
+}
+
+// WithGoLicensesCSVOutput specifies the filename for the csv report.
+func WithGoLicensesCSVOutput(filename string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.csvfile = filename
+	}
+}
+
+// WithGoLicensesSPDX controls if a spdx report file should be generated.
+// https://spdx.dev
+func WithGoLicensesSPDX(enabled bool) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.spdx = enabled
+	}
+}
+
+// WithGoLicensesSPDXOutput specifies the filename for the spdx report.
+func WithGoLicensesSPDXOutput(filename string) GoLicensesOpt {
+	return func(c *golicensesconf) {
+		c.spdxfile = filename
+	}
+}
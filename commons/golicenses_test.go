@@ -0,0 +1,92 @@
+package commons
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoLicensesArgs(t *testing.T) {
+	defaults := golicensesconf{target: "./..."}
+
+	assert.Equal(t,
+		[]string{"check", "./...", "--disallowed_types", "forbidden,unknown"},
+		golicensesargs("check", defaults),
+	)
+
+	conf := defaults
+	for _, opt := range []GoLicensesOpt{
+		WithGoLicensesAllowed("MIT", "Apache-2.0"),
+		WithGoLicensesIgnored("github.com/acme/project"),
+		WithGoLicensesTarget("./cmd/..."),
+	} {
+		opt(&conf)
+	}
+
+	assert.Equal(t,
+		[]string{"check", "./cmd/...", "--ignore", "github.com/acme/project", "--allowed_licenses", "MIT,Apache-2.0"},
+		golicensesargs("check", conf),
+	)
+	assert.Equal(t,
+		[]string{"report", "./cmd/...", "--ignore", "github.com/acme/project"},
+		golicensesargs("report", conf),
+	)
+}
+
+const golicensesoutput = `github.com/fatih/color,https://github.com/fatih/color/blob/v1.18.0/LICENSE.md,MIT
+github.com/acme/copyleft,https://github.com/acme/copyleft/blob/v1.0.0/LICENSE,GPL-3.0
+github.com/acme/private,Unknown,Unknown`
+
+func TestGoLicensesReport(t *testing.T) {
+	dependencies, err := golicensesreport(golicensesoutput)
+	require.NoError(t, err)
+	require.Len(t, dependencies, 3)
+	assert.Equal(t,
+		licenseddependency{pkg: "github.com/fatih/color", url: "https://github.com/fatih/color/blob/v1.18.0/LICENSE.md", license: "MIT"},
+		dependencies[0],
+	)
+
+	denied := deniedlicenses(dependencies, []string{"gpl-3.0", "AGPL-3.0"})
+	require.Len(t, denied, 1)
+	assert.Equal(t, "github.com/acme/copyleft", denied[0].pkg)
+
+	assert.Empty(t, deniedlicenses(dependencies, nil))
+
+	_, err = golicensesreport("github.com/fatih/color,MIT")
+	require.Error(t, err)
+}
+
+func TestWriteSPDX(t *testing.T) {
+	dependencies, err := golicensesreport(golicensesoutput)
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "licenses.spdx.json")
+	require.NoError(t, writespdx(filename, dependencies))
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	var document struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Namespace   string `json:"documentNamespace"`
+		Packages    []struct {
+			Name             string `json:"name"`
+			SPDXID           string `json:"SPDXID"`
+			DownloadLocation string `json:"downloadLocation"`
+			LicenseConcluded string `json:"licenseConcluded"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(data, &document))
+
+	assert.Equal(t, "SPDX-2.3", document.SPDXVersion)
+	assert.NotEmpty(t, document.Namespace)
+	require.Len(t, document.Packages, 3)
+	assert.Equal(t, "SPDXRef-Package-1", document.Packages[0].SPDXID)
+	assert.Equal(t, "MIT", document.Packages[0].LicenseConcluded)
+	assert.Equal(t, "NOASSERTION", document.Packages[2].DownloadLocation)
+	assert.Equal(t, "NOASSERTION", document.Packages[2].LicenseConcluded)
+}
@@ -0,0 +1,161 @@
+package commons
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/binary"
+)
+
+// Gosec inspects go code for security problems, like hardcoded credentials,
+// sql injections or unsafe file permissions.
+//
+// The task fails when there are issues with a severity and confidence equal or higher
+// than the configured thresholds, low by default, so every issue is reported.
+//
+// https://github.com/securego/gosec
+func Gosec(opts ...GosecOpt) harness.Task {
+	conf := newgosecconf(opts...)
+
+	return func(ctx context.Context) error {
+		args, err := gosecargs(conf)
+		if err != nil {
+			return err
+		}
+
+		gs := binary.New(
+			"gosec",
+			strings.TrimPrefix(conf.version, "v"),
+			binary.RemoteArchiveDownload(
+				"https://github.com/securego/gosec/releases/download/v{{.Version}}/gosec_{{.Version}}_{{.GOOS}}_{{.GOARCH}}.tar.gz",
+				map[string]string{"gosec{{.Extension}}": "gosec"},
+				binary.WithLatestGitHubRelease("securego/gosec"),
+			),
+		)
+
+		if err := gs.Ensure(ctx); err != nil {
+			return fmt.Errorf("failed to provision gosec binary: %w", err)
+		}
+
+		return harness.Run(
+			ctx,
+			gs.BinPath(),
+			harness.WithArgs(args...),
+			harness.WithErrMsg("gosec found security issues"),
+		)
+	}
+}
+
+// newgosecconf returns the configuration of [Gosec] with the options applied over the defaults.
+func newgosecconf(opts ...GosecOpt) gosecconf {
+	conf := gosecconf{
+		version:    "latest",
+		severity:   "low",
+		confidence: "low",
+		target:     "./...",
+		sariffile:  "gosec.sarif",
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return conf
+}
+
+// gosecthresholds are the severity and confidence levels gosec accepts.
+var gosecthresholds = []string{"low", "medium", "high"}
+
+// gosecargs returns the arguments gosec is run with for the configuration.
+func gosecargs(conf gosecconf) ([]string, error) {
+	severity := strings.ToLower(conf.severity)
+	if !slices.Contains(gosecthresholds, severity) {
+		return nil, fmt.Errorf("invalid gosec severity threshold %q", conf.severity)
+	}
+	confidence := strings.ToLower(conf.confidence)
+	if !slices.Contains(gosecthresholds, confidence) {
+		return nil, fmt.Errorf("invalid gosec confidence threshold %q", conf.confidence)
+	}
+
+	args := []string{"-severity", severity, "-confidence", confidence}
+	if len(conf.excluded) > 0 {
+		args = append(args, "-exclude", strings.Join(conf.excluded, ","))
+	}
+	if conf.sarif {
+		// the report goes to the file, while issues are still shown as text
+		args = append(args, "-fmt", "sarif", "-out", conf.sariffile, "-stdout", "-verbose", "text")
+	}
+
+	return append(args, conf.target), nil
+}
+
+type gosecconf struct {
+	version    string
+	excluded   []string
+	severity   string
+	confidence string
+	target     string
+
+	sarif     bool
+	sariffile string
+}
+
+type GosecOpt func(c *gosecconf)
+
+// WithGosecVersion allows specifying the gosec version
+// that should be used when running this task.
+func WithGosecVersion(version string) GosecOpt {
+	return func(c *gosecconf) {
+		c.version = version
+	}
+}
+
+// WithGosecExcludedRules disables the rules with the specified ids, e.g. "G104".
+// https://github.com/securego/gosec#available-rules
+func WithGosecExcludedRules(rules ...string) GosecOpt {
+	return func(c *gosecconf) {
+		c.excluded = append(c.excluded, rules...)
+	}
+}
+
+// WithGosecSeverityThreshold specifies the minimum severity of the issues that are
+// reported and make the task fail; one of low, medium or high.
+func WithGosecSeverityThreshold(severity string) GosecOpt {
+	return func(c *gosecconf) {
+		c.severity = severity
+	}
+}
+
+// WithGosecConfidenceThreshold specifies the minimum confidence of the issues that are
+// reported and make the task fail; one of low, medium or high.
+func WithGosecConfidenceThreshold(confidence string) GosecOpt {
+	return func(c *gosecconf) {
+		c.confidence = confidence
+	}
+}
+
+// WithGosecTarget specifies the packages to inspect, ./... by default.
+func WithGosecTarget(target string) GosecOpt {
+	return func(c *gosecconf) {
+		c.target = target
+	}
+}
+
+// WithGosecSARIF controls if a sarif report file should be generated,
+// e.g. to upload it to github code scanning.
+// https://sarifweb.azurewebsites.net
+func WithGosecSARIF(enabled bool) GosecOpt {
+	return func(c *gosecconf) {
+		c.sarif = enabled
+	}
+}
+
+// WithGosecSARIFOutput specifies the filename for the sarif report.
+func WithGosecSARIFOutput(filename string) GosecOpt {
+	return func(c *gosecconf) {
+		c.sariffile = filename
+	}
+}
@@ -0,0 +1,51 @@
+package commons
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGosecArgs(t *testing.T) {
+	t.Run("defaults",
+		func(t *testing.T) {
+			args, err := gosecargs(newgosecconf())
+			require.NoError(t, err)
+			assert.Equal(t, []string{"-severity", "low", "-confidence", "low", "./..."}, args)
+		},
+	)
+
+	t.Run("all options",
+		func(t *testing.T) {
+			args, err := gosecargs(newgosecconf(
+				WithGosecExcludedRules("G104", "G304"),
+				WithGosecSeverityThreshold("MEDIUM"),
+				WithGosecConfidenceThreshold("high"),
+				WithGosecTarget("./cmd/..."),
+				WithGosecSARIF(true),
+				WithGosecSARIFOutput("reports/gosec.sarif"),
+			))
+			require.NoError(t, err)
+			assert.Equal(t,
+				[]string{
+					"-severity", "medium", "-confidence", "high",
+					"-exclude", "G104,G304",
+					"-fmt", "sarif", "-out", "reports/gosec.sarif", "-stdout", "-verbose", "text",
+					"./cmd/...",
+				},
+				args,
+			)
+		},
+	)
+
+	t.Run("invalid thresholds",
+		func(t *testing.T) {
+			_, err := gosecargs(newgosecconf(WithGosecSeverityThreshold("critical")))
+			require.ErrorContains(t, err, "invalid gosec severity threshold")
+
+			_, err = gosecargs(newgosecconf(WithGosecConfidenceThreshold("certain")))
+			require.ErrorContains(t, err, "invalid gosec confidence threshold")
+		},
+	)
+}
@@ -9,11 +9,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/fatih/color"
 
 	"github.com/aexvir/harness"
 	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
 )
 
 // GoTest runs go test recursively.
@@ -22,6 +25,7 @@ import (
 func GoTest(opts ...TestOpt) harness.Task {
 	conf := testconf{
 		race:          true,
+		coverfile:     "coverage.out",
 		coberturafile: "test-coverage.xml",
 		junitfile:     "test-results.xml",
 		filedumpfile:  "test-output.txt",
@@ -32,13 +36,24 @@ func GoTest(opts ...TestOpt) harness.Task {
 	}
 
 	return func(ctx context.Context) error {
+		if conf.artifactsdir != "" {
+			if conf.cleanartifacts {
+				if err := os.RemoveAll(conf.artifactsdir); err != nil {
+					return fmt.Errorf("failed to clean artifacts dir %s: %w", conf.artifactsdir, err)
+				}
+			}
+			if err := os.MkdirAll(conf.artifactsdir, 0o755); err != nil {
+				return fmt.Errorf("failed to create artifacts dir %s: %w", conf.artifactsdir, err)
+			}
+		}
+
 		target := "./..."
 
 		if conf.target != nil {
 			target = fmt.Sprintf("./%s/...", *conf.target)
 		}
 
-		args := []string{"test", "-cover", target}
+		args := []string{"test", "-cover"}
 		var env []string
 
 		if conf.race {
@@ -51,7 +66,7 @@ func GoTest(opts ...TestOpt) harness.Task {
 			env = append(env, "TEST_TARGET=integration")
 		}
 
-		output := io.Writer(os.Stdout)
+		output := internal.Stdout
 
 		if conf.cifriendlyout || conf.junit {
 			args = append(args, "-json")
@@ -63,23 +78,23 @@ func GoTest(opts ...TestOpt) harness.Task {
 				jsonoutput := iobuf.Bytes()
 				if conf.cifriendlyout {
 					if err := gotestfmt(ctx, jsonoutput); err != nil {
-						color.Red("failed to format test output: %s", err.Error())
+						internal.LogError(fmt.Sprintf("failed to format test output: %s", err.Error()))
 					}
 				}
 
 				if conf.junit {
-					if err := computeJunit(ctx, jsonoutput, conf.junitfile); err != nil {
-						color.Red("failed to compute junit output: %s", err.Error())
+					if err := computeJunit(ctx, jsonoutput, conf.artifact(conf.junitfile)); err != nil {
+						internal.LogError(fmt.Sprintf("failed to compute junit output: %s", err.Error()))
 					}
 				}
 
 				if tests, passed, skipped, failed, err := computeTestSummaryFromJSON(jsonoutput); err != nil {
-					color.Red("failed to compute test summary: %s", err.Error())
+					internal.LogError(fmt.Sprintf("failed to compute test summary: %s", err.Error()))
 				} else {
 					line := fmt.Sprintf("%d tests run, %d passed, %d skipped, %d failed", tests, passed, skipped, failed)
-					fmt.Println(line)
+					internal.LogStep(line)
 					if err := writeGitHubStepSummary(line); err != nil {
-						color.Red("failed to write github step summary: %s", err.Error())
+						internal.LogError(fmt.Sprintf("failed to write github step summary: %s", err.Error()))
 					}
 				}
 			}()
@@ -91,30 +106,40 @@ func GoTest(opts ...TestOpt) harness.Task {
 
 			defer func() {
 				textoutput := iobuf.Bytes()
-				if err := os.WriteFile(conf.filedumpfile, textoutput, 0o644); err != nil {
-					color.Red("failed to write dump file: %s", err.Error())
+				if err := os.WriteFile(conf.artifact(conf.filedumpfile), textoutput, 0o644); err != nil {
+					internal.LogError(fmt.Sprintf("failed to write dump file: %s", err.Error()))
 				}
-				fmt.Println(string(textoutput))
+				internal.LogMessage(color.Reset, strings.TrimRight(string(textoutput), "\n"))
 			}()
 		}
 
+		var gocoverfile string
+
 		if conf.cobertura {
-			gocoverfile := "coverage.out"
-			args = append(args, "-coverprofile", gocoverfile)
+			gocoverfile = conf.artifact(conf.coverfile)
 
 			if conf.courtneycoverage {
 				if err := computeCourtneyCoverage(ctx, gocoverfile); err != nil {
-					color.Red("failed to apply coverage exclusions using courtney: %s", err.Error())
+					internal.LogError(fmt.Sprintf("failed to apply coverage exclusions using courtney: %s", err.Error()))
 				}
 			}
 
 			defer func() {
-				if err := computeCobertura(ctx, gocoverfile, conf.coberturafile); err != nil {
-					color.Red("failed to compute cobertura output: %s", err)
+				if err := computeCobertura(ctx, gocoverfile, conf.artifact(conf.coberturafile)); err != nil {
+					internal.LogError(fmt.Sprintf("failed to compute cobertura output: %s", err))
 				}
 			}()
 		}
 
+		if conf.parallel > 1 {
+			return gotestpackages(ctx, conf.parallel, target, args, env, output, gocoverfile)
+		}
+
+		if gocoverfile != "" {
+			args = append(args, "-coverprofile", gocoverfile)
+		}
+		args = append(args, target)
+
 		return harness.Run(ctx, "go",
 			harness.WithArgs(args...),
 			harness.WithEnv(env...),
@@ -130,13 +155,13 @@ func GoIntegrationTest(opts ...TestOpt) harness.Task {
 }
 
 func gotestfmt(ctx context.Context, testout []byte) error {
-	fmt.Println("gotestfmt")
+	internal.LogDetail("formatting test output with gotestfmt")
 	gtf := binary.New(
 		"gotestfmt",
 		"latest",
 		binary.GoBinary("github.com/gotesttools/gotestfmt/v2/cmd/gotestfmt"),
 	)
-	if err := gtf.Ensure(); err != nil {
+	if err := gtf.Ensure(ctx); err != nil {
 		return err
 	}
 
@@ -161,7 +186,7 @@ func computeJunit(ctx context.Context, testout []byte, junitfile string) error {
 		"latest",
 		binary.GoBinary("gotest.tools/gotestsum"),
 	)
-	if err := gts.Ensure(); err != nil {
+	if err := gts.Ensure(ctx); err != nil {
 		return err
 	}
 
@@ -186,29 +211,32 @@ func computeCobertura(ctx context.Context, coverfile, coberturafile string) erro
 		"latest",
 		binary.GoBinary("github.com/boumenot/gocover-cobertura"),
 	)
-	if err := cbrt.Ensure(); err != nil {
+	if err := cbrt.Ensure(ctx); err != nil {
 		return err
 	}
 
-	coverout, err := os.ReadFile(coverfile)
+	coverout, err := os.Open(coverfile)
 	if err != nil {
 		return fmt.Errorf("error reading go coverage output: %w", err)
 	}
+	defer coverout.Close() //nolint:errcheck
 
-	buf := new(bytes.Buffer)
-
+	cobertura, err := os.Create(coberturafile)
+	if err != nil {
+		return fmt.Errorf("failed to create cobertura file: %w", err)
+	}
 	defer func() {
-		err := os.WriteFile(coberturafile, buf.Bytes(), 0o644)
-		if err != nil {
-			color.Red("failed to write cobertura file: %s", err.Error())
+		if err := cobertura.Close(); err != nil {
+			internal.LogError(fmt.Sprintf("failed to write cobertura file: %s", err.Error()))
 		}
 	}()
 
+	// both files are passed to the converter directly, so the coverage is streamed through it
 	return harness.Run(
 		ctx,
 		cbrt.BinPath(),
-		harness.WithStdIn(bytes.NewReader(coverout)),
-		harness.WithStdOut(buf),
+		harness.WithStdIn(coverout),
+		harness.WithStdOut(cobertura),
 	)
 }
 
@@ -222,7 +250,7 @@ func computeCourtneyCoverage(ctx context.Context, coverfile string) error {
 		binary.GoBinary("github.com/dave/courtney"),
 	)
 
-	if err := ctny.Ensure(); err != nil {
+	if err := ctny.Ensure(ctx); err != nil {
 		return err
 	}
 
@@ -313,6 +341,21 @@ type testconf struct {
 	junitfile     string
 	cobertura     bool
 	coberturafile string
+	coverfile     string
+
+	artifactsdir   string
+	cleanartifacts bool
+
+	parallel int
+}
+
+// artifact returns the path where the named output file should be written.
+// Relative filenames are placed inside the artifacts dir when one is configured.
+func (c testconf) artifact(filename string) string {
+	if c.artifactsdir == "" || filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(c.artifactsdir, filename)
 }
 
 type TestOpt func(c *testconf)
@@ -395,3 +438,34 @@ func WithTestJunitOutput(filename string) TestOpt {
 		c.junitfile = filename
 	}
 }
+
+// WithTestArtifactsDir specifies a directory where all the files generated by the test task
+// are written; coverage profile, cobertura and junit reports and output dumps.
+// The directory is created if it doesn't exist.
+// Filenames set via the specific output options are resolved relative to this directory,
+// unless they are absolute paths.
+func WithTestArtifactsDir(dir string) TestOpt {
+	return func(c *testconf) {
+		c.artifactsdir = dir
+	}
+}
+
+// WithTestArtifactsDirCleanup controls if the artifacts directory should be removed
+// before running the tests, so no stale files from previous runs are left behind.
+// Only has effect when used together with [WithTestArtifactsDir].
+func WithTestArtifactsDirCleanup(enabled bool) TestOpt {
+	return func(c *testconf) {
+		c.cleanartifacts = enabled
+	}
+}
+
+// WithTestParallelPackages runs every package matched by the test target as a separate
+// go test invocation, running up to the specified amount of them at the same time.
+// Output of each package is streamed prefixed with the package name, and reports like
+// junit or cobertura are computed from the merged results of all packages.
+// Values lower than 2 disable this behavior, running a single go test invocation.
+func WithTestParallelPackages(workers int) TestOpt {
+	return func(c *testconf) {
+		c.parallel = workers
+	}
+}
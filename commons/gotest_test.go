@@ -22,3 +22,29 @@ func TestComputeTestSummaryFromJSON(t *testing.T) {
 	assert.Equal(t, 1, skipped)
 	assert.Equal(t, 1, failed)
 }
+
+func TestTestConfArtifact(t *testing.T) {
+	t.Run("without artifacts dir",
+		func(t *testing.T) {
+			conf := testconf{}
+			assert.Equal(t, "test-results.xml", conf.artifact("test-results.xml"))
+		},
+	)
+
+	t.Run("with artifacts dir",
+		func(t *testing.T) {
+			conf := testconf{artifactsdir: "artifacts"}
+			assert.Equal(t, filepath.Join("artifacts", "test-results.xml"), conf.artifact("test-results.xml"))
+		},
+	)
+
+	t.Run("absolute paths are kept",
+		func(t *testing.T) {
+			abs, err := filepath.Abs("test-results.xml")
+			require.NoError(t, err)
+
+			conf := testconf{artifactsdir: "artifacts"}
+			assert.Equal(t, abs, conf.artifact(abs))
+		},
+	)
+}
@@ -0,0 +1,200 @@
+package commons
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/internal"
+)
+
+// gotestpackages runs go test for every package matching target in a separate invocation,
+// running up to workers invocations concurrently.
+// When output is the default command output, the output of each package is streamed prefixed
+// with its name; otherwise it's buffered and written to output package by package once all are
+// done, so consumers like the json parsers get the unmodified go test output, while the
+// diagnostics go test writes to stderr, like compilation errors, follow the output of each package.
+// Once the context is done no more packages are scheduled.
+// If coverfile is set, the coverage profiles of all packages are merged into it.
+func gotestpackages(ctx context.Context, workers int, target string, args, env []string, output io.Writer, coverfile string) error {
+	packages, err := listpackages(ctx, target, env)
+	if err != nil {
+		return err
+	}
+
+	harness.LogStep(fmt.Sprintf("testing %d packages using %d workers", len(packages), workers))
+
+	coverdir, err := os.MkdirTemp("", "harness-coverage-")
+	if err != nil {
+		return fmt.Errorf("failed to create coverage dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(coverdir) }()
+
+	streaming := output == internal.Stdout
+	shared := internal.NewSyncWriter(output)
+
+	buffers := make([]*bytes.Buffer, len(packages))
+	diagnostics := make([]*bytes.Buffer, len(packages))
+	profiles := make([]string, len(packages))
+
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+
+	sem := make(chan struct{}, workers)
+
+	for idx, pkg := range packages {
+		if !acquire(ctx, sem) {
+			mtx.Lock()
+			errs = append(errs, fmt.Errorf("stopped before testing %d packages: %w", len(packages)-idx, ctx.Err()))
+			mtx.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			pkgargs := append([]string{}, args...)
+			if coverfile != "" {
+				profiles[idx] = filepath.Join(coverdir, fmt.Sprintf("%d.out", idx))
+				pkgargs = append(pkgargs, "-coverprofile", profiles[idx])
+			}
+			pkgargs = append(pkgargs, pkg)
+
+			opts := []harness.RunnerOpt{
+				harness.WithArgs(pkgargs...),
+				harness.WithEnv(env...),
+			}
+
+			if streaming {
+				prefixed := internal.NewPrefixWriter(shared, fmt.Sprintf("[%s] ", pkg))
+				defer func() { _ = prefixed.Flush() }()
+				opts = append(opts, harness.WithCombinedOutput(prefixed))
+			} else {
+				buffers[idx], diagnostics[idx] = new(bytes.Buffer), new(bytes.Buffer)
+				opts = append(opts, harness.WithStdOut(buffers[idx]), harness.WithStdErr(diagnostics[idx]))
+			}
+
+			if err := harness.Run(ctx, "go", opts...); err != nil {
+				mtx.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", pkg, err))
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if !streaming {
+		quiet := internal.CurrentVerbosity() == internal.VerbosityQuiet
+
+		for idx, buf := range buffers {
+			if buf == nil {
+				continue
+			}
+			if _, err := output.Write(buf.Bytes()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to write test output: %w", err))
+			}
+			if !quiet {
+				_, _ = internal.Stderr.Write(diagnostics[idx].Bytes())
+			}
+		}
+	}
+
+	if coverfile != "" {
+		if err := mergecoverprofiles(coverfile, profiles); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// acquire takes a slot of the semaphore, waiting for one to be free; reports false
+// without taking any if the context is done first.
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// listpackages returns the import paths of all packages matching target.
+func listpackages(ctx context.Context, target string, env []string) ([]string, error) {
+	listing, err := harness.Output(ctx, "go",
+		harness.WithArgs("list", target),
+		harness.WithEnv(env...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	return strings.Fields(listing), nil
+}
+
+// mergecoverprofiles combines multiple go coverage profiles into a single file.
+// All profiles are expected to use the same cover mode; only the mode line of the
+// first profile is kept. Missing profiles, like the ones of packages without tests,
+// are ignored.
+func mergecoverprofiles(destination string, profiles []string) (err error) {
+	out, err := os.Create(destination)
+	if err != nil {
+		return fmt.Errorf("failed to create coverage file %s: %w", destination, err)
+	}
+	defer func() {
+		if closerr := out.Close(); closerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close file %s: %w", destination, closerr))
+		}
+	}()
+
+	var mode bool
+
+	for _, profile := range profiles {
+		if profile == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(profile)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return fmt.Errorf("failed to read coverage file %s: %w", profile, err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "mode:") {
+				if mode {
+					continue
+				}
+				mode = true
+			}
+
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return fmt.Errorf("failed to write coverage file %s: %w", destination, err)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read coverage file %s: %w", profile, err)
+		}
+	}
+
+	return nil
+}
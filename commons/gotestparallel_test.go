@@ -0,0 +1,43 @@
+package commons
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeCoverProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	uno := filepath.Join(dir, "uno.out")
+	require.NoError(t, os.WriteFile(uno, []byte("mode: atomic\nexample.com/uno/a.go:1.1,2.2 1 1\n"), 0o644))
+
+	dos := filepath.Join(dir, "dos.out")
+	require.NoError(t, os.WriteFile(dos, []byte("mode: atomic\nexample.com/dos/b.go:3.1,4.2 2 0\n"), 0o644))
+
+	merged := filepath.Join(dir, "coverage.out")
+	require.NoError(t, mergecoverprofiles(merged, []string{uno, "", filepath.Join(dir, "missing.out"), dos}))
+
+	data, err := os.ReadFile(merged)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"mode: atomic\nexample.com/uno/a.go:1.1,2.2 1 1\nexample.com/dos/b.go:3.1,4.2 2 0\n",
+		string(data),
+	)
+}
+
+func TestAcquire(t *testing.T) {
+	sem := make(chan struct{}, 1)
+
+	assert.True(t, acquire(t.Context(), sem))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	assert.False(t, acquire(ctx, sem))
+	assert.Len(t, sem, 1)
+}
@@ -0,0 +1,242 @@
+package commons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/aexvir/harness"
+)
+
+// Playwright runs an end-to-end browser test suite using playwright.
+//
+// The browsers needed by the suite are installed first; playwright caches them, so this
+// is fast when they're already present. If a server command is configured, the app under
+// test is started in the background, the suite is run once it responds on the configured
+// url, and it's stopped after the suite finishes.
+// Traces of failed tests and the html report are written inside the artifacts directory.
+//
+// The project in the configured directory is expected to have @playwright/test as dependency.
+//
+// https://playwright.dev
+func Playwright(opts ...PlaywrightOpt) harness.Task {
+	conf := playwrightconf{
+		dir:            ".",
+		artifactsdir:   "playwright",
+		junitfile:      "results.xml",
+		serverstart:    60 * time.Second,
+		servershutdown: 10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return func(ctx context.Context) (err error) {
+		artifacts, err := filepath.Abs(conf.artifactsdir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve artifacts dir %s: %w", conf.artifactsdir, err)
+		}
+
+		installargs := append([]string{"playwright", "install"}, conf.browsers...)
+		if conf.systemdeps {
+			installargs = append(installargs, "--with-deps")
+		}
+
+		var env []string
+		if conf.browserspath != "" {
+			browserspath, err := filepath.Abs(conf.browserspath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve browsers path %s: %w", conf.browserspath, err)
+			}
+			env = append(env, "PLAYWRIGHT_BROWSERS_PATH="+browserspath)
+		}
+
+		err = harness.Run(ctx, "npx",
+			harness.WithArgs(installargs...),
+			harness.WithDir(conf.dir),
+			harness.WithEnv(env...),
+			harness.WithErrMsg("failed to install playwright browsers"),
+		)
+		if err != nil {
+			return err
+		}
+
+		if conf.server != nil {
+			server, err := harness.Cmd(ctx, conf.server[0],
+				harness.WithArgs(conf.server[1:]...),
+				harness.WithDir(conf.dir),
+				harness.WithGracefulStop(conf.servershutdown),
+			)
+			if err != nil {
+				return err
+			}
+
+			if err := server.Start(); err != nil {
+				return fmt.Errorf("failed to start app under test: %w", err)
+			}
+			defer func() {
+				if stoperr := server.Stop(); stoperr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to stop app under test: %w", stoperr))
+				}
+			}()
+
+			if conf.serverurl != "" {
+				if err := waitforurl(ctx, conf.serverurl, conf.serverstart); err != nil {
+					return err
+				}
+			}
+		}
+
+		testargs := []string{
+			"playwright", "test",
+			"--output", filepath.Join(artifacts, "test-results"),
+			"--trace", "retain-on-failure",
+		}
+
+		reporters := "list,html"
+		env = append(env,
+			"PLAYWRIGHT_HTML_OPEN=never",
+			"PLAYWRIGHT_HTML_OUTPUT_DIR="+filepath.Join(artifacts, "report"),
+			"PLAYWRIGHT_HTML_REPORT="+filepath.Join(artifacts, "report"),
+		)
+		if conf.junit {
+			reporters += ",junit"
+			env = append(env, "PLAYWRIGHT_JUNIT_OUTPUT_FILE="+filepath.Join(artifacts, conf.junitfile))
+		}
+		testargs = append(testargs, "--reporter", reporters)
+		testargs = append(testargs, conf.args...)
+
+		return harness.Run(ctx, "npx",
+			harness.WithArgs(testargs...),
+			harness.WithDir(conf.dir),
+			harness.WithEnv(env...),
+			harness.WithErrMsg("end-to-end tests failed"),
+		)
+	}
+}
+
+// waitforurl polls the url until it responds with a non server error status or
+// the timeout is reached.
+func waitforurl(ctx context.Context, url string, timeout time.Duration) error {
+	harness.LogStep(fmt.Sprintf("waiting for %s to be ready", url))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("invalid url %s: %w", url, err)
+		}
+
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode < http.StatusInternalServerError {
+				return nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%s wasn't ready after %s: %w", url, timeout, ctx.Err())
+		}
+	}
+}
+
+type playwrightconf struct {
+	dir          string
+	args         []string
+	browsers     []string
+	browserspath string
+	systemdeps   bool
+
+	server         []string
+	serverurl      string
+	serverstart    time.Duration
+	servershutdown time.Duration
+
+	artifactsdir string
+	junit        bool
+	junitfile    string
+}
+
+type PlaywrightOpt func(c *playwrightconf)
+
+// WithPlaywrightDir specifies the directory of the project containing the playwright
+// configuration and dependencies.
+func WithPlaywrightDir(dir string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.dir = dir
+	}
+}
+
+// WithPlaywrightArgs allows passing additional arguments to playwright test, e.g. "--project", "chromium".
+func WithPlaywrightArgs(args ...string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.args = append(c.args, args...)
+	}
+}
+
+// WithPlaywrightBrowsers limits the browsers that are installed; by default all browsers
+// required by the playwright configuration are installed.
+func WithPlaywrightBrowsers(browsers ...string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.browsers = append(c.browsers, browsers...)
+	}
+}
+
+// WithPlaywrightBrowsersPath specifies where browsers are cached; useful for keeping them
+// in a directory that is cached between ci runs.
+func WithPlaywrightBrowsersPath(path string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.browserspath = path
+	}
+}
+
+// WithPlaywrightSystemDeps controls if the system dependencies of the browsers should be
+// installed too. This usually requires elevated privileges, so it's mostly useful on ci.
+func WithPlaywrightSystemDeps(enabled bool) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.systemdeps = enabled
+	}
+}
+
+// WithPlaywrightServer specifies the command that starts the app under test.
+// The command is run in the background from the playwright directory and stopped
+// after the test suite finishes.
+func WithPlaywrightServer(command string, args ...string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.server = append([]string{command}, args...)
+	}
+}
+
+// WithPlaywrightServerURL specifies the url that is polled to know when the app under
+// test is ready, along with how long to wait for it at most.
+func WithPlaywrightServerURL(url string, timeout time.Duration) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.serverurl = url
+		c.serverstart = timeout
+	}
+}
+
+// WithPlaywrightArtifactsDir specifies the directory where traces and reports are written.
+func WithPlaywrightArtifactsDir(dir string) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.artifactsdir = dir
+	}
+}
+
+// WithPlaywrightJunit controls if a junit report file should be generated inside the artifacts dir.
+func WithPlaywrightJunit(enabled bool) PlaywrightOpt {
+	return func(c *playwrightconf) {
+		c.junit = enabled
+	}
+}
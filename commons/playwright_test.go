@@ -0,0 +1,41 @@
+package commons
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForURL(t *testing.T) {
+	t.Run("waits until server is ready",
+		func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					if calls.Add(1) < 3 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				}),
+			)
+			t.Cleanup(srv.Close)
+
+			require.NoError(t, waitforurl(t.Context(), srv.URL, 5*time.Second))
+			assert.Equal(t, int32(3), calls.Load())
+		},
+	)
+
+	t.Run("times out",
+		func(t *testing.T) {
+			err := waitforurl(t.Context(), "http://127.0.0.1:1", time.Second)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "wasn't ready after 1s")
+		},
+	)
+}
@@ -1,31 +1,37 @@
 package commons
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
 
 	"github.com/aexvir/harness"
 	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
 )
 
 // Provision a list of binaries.
 // Generates and executes a list of tasks where [Binary.Ensure] is called on each binary
 // collecting and returning any errors encountered.
+// Failures that may go away when retried, like network errors, are reported as such,
+// see [binary.IsTransient]; the returned error wraps every failure.
 func Provision(binaries ...*binary.Binary) harness.Task {
 	return func(ctx context.Context) (err error) {
-		var errs []string
+		var errs []error
 		start := time.Now()
 		defer func() {
 			elapsed := time.Since(start).Round(time.Millisecond)
 			if err != nil {
-				color.Red(" %s %s\n\n", harness.Symbols.Error, elapsed)
-				return
+				internal.LogError(elapsed.String())
+			} else {
+				internal.LogSuccess(elapsed.String())
 			}
-			color.Green(" %s %s\n\n", harness.Symbols.Success, elapsed)
+			internal.LogBlank()
 		}()
 
 		names := make([]string, 0, len(binaries))
@@ -35,18 +41,107 @@ func Provision(binaries ...*binary.Binary) harness.Task {
 		harness.LogStep(fmt.Sprintf("provisioning %d binaries: %s", len(binaries), strings.Join(names, ", ")))
 
 		for _, bin := range binaries {
-			if err := bin.Ensure(); err != nil {
-				errs = append(errs, fmt.Sprintf("failed to provision %s: %s", bin.Name(), err))
+			if err := bin.Ensure(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to provision %s: %w", bin.Name(), err))
 			}
 		}
 
 		if len(errs) > 0 {
-			for _, errmsg := range errs {
-				color.Red(" %s %s", harness.Symbols.Dot, errmsg)
+			for _, err := range errs {
+				if binary.IsTransient(err) {
+					internal.LogErrorItem(err.Error() + " (transient, retrying may help)")
+					continue
+				}
+				internal.LogErrorItem(err.Error())
 			}
-			return fmt.Errorf("provisioning failed")
+			return &provisionerror{errs: errs}
 		}
 
 		return nil
 	}
 }
+
+// provisionerror is returned by [Provision], wrapping every failure so they can be
+// inspected with errors.Is and errors.As, while the message stays short as the
+// failures are already reported.
+type provisionerror struct {
+	errs []error
+}
+
+func (e *provisionerror) Error() string {
+	return "provisioning failed"
+}
+
+func (e *provisionerror) Unwrap() []error {
+	return e.errs
+}
+
+// CleanTools removes the bin directory along with every binary provisioned into it,
+// see [binary.Clean]. Binaries are provisioned again the next time they're ensured.
+func CleanTools() harness.Task {
+	return func(_ context.Context) error {
+		return binary.Clean("")
+	}
+}
+
+// ListTools prints the inventory of binaries provisioned into the bin directory, with their
+// version, origin, size and when they were last used, see [binary.List].
+func ListTools() harness.Task {
+	return func(_ context.Context) error {
+		installed, err := binary.List("")
+		if err != nil {
+			return err
+		}
+
+		harness.LogStep(fmt.Sprintf("%d binaries provisioned", len(installed)))
+		if len(installed) == 0 {
+			return nil
+		}
+
+		var out bytes.Buffer
+		table := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "NAME\tVERSION\tORIGIN\tSIZE\tINSTALLED\tLAST USED")
+		for _, bin := range installed {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				bin.Name, bin.Version, orunknown(bin.Origin), humansize(bin.Size),
+				humantime(bin.Installed), humantime(bin.LastUsed),
+			)
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+
+		internal.LogMessage(color.Reset, strings.TrimRight(out.String(), "\n"))
+		return nil
+	}
+}
+
+// humansize formats the size in bytes with binary units, e.g. 12.3 MiB.
+func humansize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// humantime formats the time as a date, or "unknown" if it's zero.
+func humantime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// orunknown returns the value, or "unknown" if it's empty.
+func orunknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
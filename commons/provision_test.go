@@ -0,0 +1,39 @@
+package commons
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/binary"
+)
+
+func TestHumanSize(t *testing.T) {
+	assert.Equal(t, "512 B", humansize(512))
+	assert.Equal(t, "1.0 KiB", humansize(1024))
+	assert.Equal(t, "12.3 MiB", humansize(12_900_000))
+	assert.Equal(t, "1.5 GiB", humansize(3<<29))
+}
+
+func TestProvisionErrors(t *testing.T) {
+	dir := t.TempDir()
+	failing := &binary.DownloadError{URL: "https://example.com/util", Status: http.StatusBadGateway, Err: errors.New("bad gateway")}
+
+	err := Provision(
+		binary.New("util", "1.0.0",
+			binary.FromScript(func(context.Context, binary.Template) error { return failing }),
+			binary.WithDirectory(dir),
+		),
+	)(t.Context())
+
+	require.EqualError(t, err, "provisioning failed")
+
+	var dlerr *binary.DownloadError
+	require.ErrorAs(t, err, &dlerr)
+	assert.Equal(t, http.StatusBadGateway, dlerr.Status)
+	assert.True(t, binary.IsTransient(err))
+}
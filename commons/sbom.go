@@ -0,0 +1,183 @@
+package commons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/binary"
+)
+
+// Sbom generates software bills of materials with syft, listing the dependencies of the
+// go module and of container images, so they can be attached to releases.
+//
+// By default only the module in the current directory is scanned and its sbom is written
+// to sbom.spdx.json. The sbom of each image is written next to it, named after the image,
+// e.g. sbom-ghcr.io-acme-app-1.0.spdx.json for ghcr.io/acme/app:1.0.
+//
+// https://github.com/anchore/syft
+func Sbom(opts ...SbomOpt) harness.Task {
+	conf := newsbomconf(opts...)
+
+	return func(ctx context.Context) error {
+		scans, err := sbomscans(conf)
+		if err != nil {
+			return err
+		}
+		if len(scans) == 0 {
+			return errors.New("nothing to generate a sbom for")
+		}
+
+		syft := binary.New(
+			"syft",
+			strings.TrimPrefix(conf.version, "v"),
+			binary.RemoteArchiveDownload(
+				"https://github.com/anchore/syft/releases/download/v{{.Version}}/syft_{{.Version}}_{{.GOOS}}_{{.GOARCH}}.tar.gz",
+				map[string]string{"syft{{.Extension}}": "syft"},
+				binary.WithLatestGitHubRelease("anchore/syft"),
+			),
+		)
+
+		if err := syft.Ensure(ctx); err != nil {
+			return fmt.Errorf("failed to provision syft binary: %w", err)
+		}
+
+		if err := os.MkdirAll(conf.outputdir, 0o755); err != nil {
+			return fmt.Errorf("failed to create sbom output directory: %w", err)
+		}
+
+		for _, scan := range scans {
+			harness.LogStep(fmt.Sprintf("generating sbom for %s", scan.source))
+
+			err := harness.Run(
+				ctx,
+				syft.BinPath(),
+				harness.WithArgs(scan.args()...),
+				harness.WithErrMsg(fmt.Sprintf("failed to generate sbom for %s", scan.source)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// newsbomconf returns the configuration of [Sbom] with the options applied over the defaults.
+func newsbomconf(opts ...SbomOpt) sbomconf {
+	conf := sbomconf{
+		version:   "latest",
+		module:    true,
+		format:    "spdx-json",
+		outputdir: ".",
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return conf
+}
+
+// sbomextensions are the output formats of syft and the extension of their files.
+var sbomextensions = map[string]string{
+	"spdx-json":      ".spdx.json",
+	"spdx-tag-value": ".spdx",
+	"cyclonedx-json": ".cdx.json",
+	"cyclonedx-xml":  ".cdx.xml",
+	"syft-json":      ".syft.json",
+}
+
+// sbomscan is a source syft generates a sbom for and the file it's written to.
+type sbomscan struct {
+	source string
+	format string
+	output string
+}
+
+// args returns the arguments syft is run with for the scan.
+func (s sbomscan) args() []string {
+	return []string{"scan", s.source, "--output", s.format + "=" + s.output}
+}
+
+// sbomfilename matches the characters of image references that don't belong in filenames.
+var sbomfilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sbomscans returns the sources scanned for the configuration and their output files.
+func sbomscans(conf sbomconf) ([]sbomscan, error) {
+	format := strings.ToLower(conf.format)
+	extension, ok := sbomextensions[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid sbom format %q", conf.format)
+	}
+
+	var scans []sbomscan
+	if conf.module {
+		scans = append(scans, sbomscan{source: "dir:.", format: format, output: filepath.Join(conf.outputdir, "sbom"+extension)})
+	}
+	for _, image := range conf.images {
+		name := sbomfilename.ReplaceAllString(image, "-")
+		scans = append(scans, sbomscan{source: image, format: format, output: filepath.Join(conf.outputdir, "sbom-"+name+extension)})
+	}
+
+	return scans, nil
+}
+
+type sbomconf struct {
+	version   string
+	module    bool
+	images    []string
+	format    string
+	outputdir string
+}
+
+type SbomOpt func(c *sbomconf)
+
+// WithSbomVersion allows specifying the syft version
+// that should be used when running this task.
+func WithSbomVersion(version string) SbomOpt {
+	return func(c *sbomconf) {
+		c.version = version
+	}
+}
+
+// WithSbomModule controls if a sbom is generated for the go module in the
+// current directory, enabled by default.
+func WithSbomModule(enabled bool) SbomOpt {
+	return func(c *sbomconf) {
+		c.module = enabled
+	}
+}
+
+// WithSbomImages generates a sbom for each of the container images too.
+// Images are looked up in the local docker daemon first and then pulled from their
+// registry, unless the source is explicit, e.g. "registry:ghcr.io/acme/app:1.0".
+func WithSbomImages(images ...string) SbomOpt {
+	return func(c *sbomconf) {
+		c.images = append(c.images, images...)
+	}
+}
+
+// WithSbomFormat specifies the format of the sboms; one of spdx-json, spdx-tag-value,
+// cyclonedx-json, cyclonedx-xml or syft-json, spdx-json by default.
+// https://spdx.dev
+// https://cyclonedx.org
+func WithSbomFormat(format string) SbomOpt {
+	return func(c *sbomconf) {
+		c.format = format
+	}
+}
+
+// WithSbomOutputDir specifies the directory the sboms are written to,
+// the current directory by default.
+func WithSbomOutputDir(dir string) SbomOpt {
+	return func(c *sbomconf) {
+		c.outputdir = dir
+	}
+}
@@ -0,0 +1,54 @@
+package commons
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSbomScans(t *testing.T) {
+	t.Run("defaults",
+		func(t *testing.T) {
+			scans, err := sbomscans(newsbomconf())
+			require.NoError(t, err)
+			require.Len(t, scans, 1)
+			assert.Equal(t, []string{"scan", "dir:.", "--output", "spdx-json=sbom.spdx.json"}, scans[0].args())
+		},
+	)
+
+	t.Run("all options",
+		func(t *testing.T) {
+			scans, err := sbomscans(newsbomconf(
+				WithSbomFormat("CycloneDX-JSON"),
+				WithSbomImages("ghcr.io/acme/app:1.0", "registry:acme/worker@sha256:abc"),
+				WithSbomOutputDir("dist"),
+			))
+			require.NoError(t, err)
+			assert.Equal(t,
+				[]sbomscan{
+					{source: "dir:.", format: "cyclonedx-json", output: filepath.Join("dist", "sbom.cdx.json")},
+					{source: "ghcr.io/acme/app:1.0", format: "cyclonedx-json", output: filepath.Join("dist", "sbom-ghcr.io-acme-app-1.0.cdx.json")},
+					{source: "registry:acme/worker@sha256:abc", format: "cyclonedx-json", output: filepath.Join("dist", "sbom-registry-acme-worker-sha256-abc.cdx.json")},
+				},
+				scans,
+			)
+		},
+	)
+
+	t.Run("images only",
+		func(t *testing.T) {
+			scans, err := sbomscans(newsbomconf(WithSbomModule(false), WithSbomImages("acme/app")))
+			require.NoError(t, err)
+			assert.Equal(t, []sbomscan{{source: "acme/app", format: "spdx-json", output: "sbom-acme-app.spdx.json"}}, scans)
+		},
+	)
+
+	t.Run("invalid format",
+		func(t *testing.T) {
+			_, err := sbomscans(newsbomconf(WithSbomFormat("json")))
+			require.Error(t, err)
+		},
+	)
+}
@@ -0,0 +1,232 @@
+package commons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/aexvir/harness"
+	"github.com/aexvir/harness/binary"
+	"github.com/aexvir/harness/internal"
+)
+
+// Semgrep runs static analysis over the repository using semgrep compatible rulesets.
+// The analysis is run with opengrep, the open source fork of semgrep, which is distributed
+// as a standalone binary.
+//
+// The task fails when there are findings with a severity equal or higher than the
+// configured threshold, ERROR by default.
+//
+// https://opengrep.dev
+// https://semgrep.dev/docs/writing-rules/overview
+func Semgrep(opts ...SemgrepOpt) harness.Task {
+	conf := semgrepconf{
+		version:   "latest",
+		threshold: "ERROR",
+		target:    ".",
+		sariffile: "semgrep.sarif",
+		jsonfile:  "semgrep.json",
+	}
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return func(ctx context.Context) error {
+		if len(conf.configs) == 0 {
+			return fmt.Errorf("no semgrep rulesets configured")
+		}
+
+		threshold, ok := semgrepseverities[strings.ToUpper(conf.threshold)]
+		if !ok {
+			return fmt.Errorf("invalid semgrep severity threshold %q", conf.threshold)
+		}
+
+		url := "https://github.com/opengrep/opengrep/releases/download/v{{.Version}}/opengrep_{{.GOOS}}_{{.GOARCH}}{{.Extension}}"
+		if conf.version == "latest" {
+			url = "https://github.com/opengrep/opengrep/releases/latest/download/opengrep_{{.GOOS}}_{{.GOARCH}}{{.Extension}}"
+		}
+
+		archmapping := map[string]string{"amd64": "x86"}
+		if runtime.GOOS == "linux" {
+			archmapping["arm64"] = "aarch64"
+		}
+
+		sg := binary.New(
+			"opengrep",
+			strings.TrimPrefix(conf.version, "v"),
+			binary.RemoteBinaryDownload(url),
+			binary.WithGOOSMapping(map[string]string{"linux": "manylinux", "darwin": "osx"}),
+			binary.WithGOARCHMapping(archmapping),
+		)
+
+		if err := sg.Ensure(ctx); err != nil {
+			return fmt.Errorf("failed to provision opengrep binary: %w", err)
+		}
+
+		// the json report is always generated, as it's used to evaluate the findings
+		jsonfile := conf.jsonfile
+		if !conf.json {
+			tmp, err := os.MkdirTemp("", "semgrep-")
+			if err != nil {
+				return fmt.Errorf("failed to create temp dir: %w", err)
+			}
+			defer func() { _ = os.RemoveAll(tmp) }()
+			jsonfile = filepath.Join(tmp, "semgrep.json")
+		}
+
+		args := []string{"scan", "--json-output", jsonfile}
+		for _, config := range conf.configs {
+			args = append(args, "--config", config)
+		}
+		if conf.sarif {
+			args = append(args, "--sarif-output", conf.sariffile)
+		}
+		args = append(args, conf.target)
+
+		if err := harness.Run(ctx, sg.BinPath(), harness.WithArgs(args...)); err != nil {
+			return err
+		}
+
+		report, err := os.ReadFile(jsonfile)
+		if err != nil {
+			return fmt.Errorf("failed to read semgrep report: %w", err)
+		}
+
+		findings, err := semgrepfindings(report, threshold)
+		if err != nil {
+			return err
+		}
+
+		if len(findings) > 0 {
+			for _, finding := range findings {
+				internal.LogErrorItem(fmt.Sprintf(
+					"%s:%d        [%s] %s",
+					finding.Path, finding.Start.Line, finding.Extra.Severity, finding.Extra.Message,
+				))
+			}
+			return fmt.Errorf("semgrep found %d issues with severity %s or higher", len(findings), strings.ToUpper(conf.threshold))
+		}
+
+		return nil
+	}
+}
+
+// semgrepseverities ranks the severities semgrep rules can have.
+var semgrepseverities = map[string]int{
+	"INFO":    0,
+	"WARNING": 1,
+	"ERROR":   2,
+}
+
+// basic semgrep finding.
+type semgrepfinding struct {
+	CheckID string `json:"check_id"`
+	Path    string `json:"path"`
+	Start   struct {
+		Line int `json:"line"`
+	} `json:"start"`
+	Extra struct {
+		Message  string `json:"message"`
+		Severity string `json:"severity"`
+	} `json:"extra"`
+}
+
+// semgrepfindings returns the findings from a semgrep json report with a severity
+// equal or higher than the threshold.
+func semgrepfindings(report []byte, threshold int) ([]semgrepfinding, error) {
+	var parsed struct {
+		Results []semgrepfinding `json:"results"`
+	}
+
+	if err := json.Unmarshal(report, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse semgrep report: %w", err)
+	}
+
+	var findings []semgrepfinding
+	for _, finding := range parsed.Results {
+		if semgrepseverities[strings.ToUpper(finding.Extra.Severity)] >= threshold {
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings, nil
+}
+
+type semgrepconf struct {
+	version   string
+	configs   []string
+	threshold string
+	target    string
+
+	sarif     bool
+	sariffile string
+	json      bool
+	jsonfile  string
+}
+
+type SemgrepOpt func(c *semgrepconf)
+
+// WithSemgrepVersion allows specifying the opengrep version
+// that should be used when running this task.
+func WithSemgrepVersion(version string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.version = version
+	}
+}
+
+// WithSemgrepConfigs specifies the rulesets used for the analysis; they can be paths
+// to rule files or directories, or registry rulesets.
+func WithSemgrepConfigs(configs ...string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.configs = append(c.configs, configs...)
+	}
+}
+
+// WithSemgrepSeverityThreshold specifies the minimum severity of the findings that make
+// the task fail; one of INFO, WARNING or ERROR.
+func WithSemgrepSeverityThreshold(severity string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.threshold = severity
+	}
+}
+
+// WithSemgrepTarget limits the analysis to a path relative to the root path.
+func WithSemgrepTarget(target string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.target = target
+	}
+}
+
+// WithSemgrepSARIF controls if a sarif report file should be generated.
+// https://sarifweb.azurewebsites.net
+func WithSemgrepSARIF(enabled bool) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.sarif = enabled
+	}
+}
+
+// WithSemgrepSARIFOutput specifies the filename for the sarif report.
+func WithSemgrepSARIFOutput(filename string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.sariffile = filename
+	}
+}
+
+// WithSemgrepJSON controls if a json report file should be generated.
+func WithSemgrepJSON(enabled bool) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.json = enabled
+	}
+}
+
+// WithSemgrepJSONOutput specifies the filename for the json report.
+func WithSemgrepJSONOutput(filename string) SemgrepOpt {
+	return func(c *semgrepconf) {
+		c.jsonfile = filename
+	}
+}
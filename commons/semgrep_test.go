@@ -0,0 +1,49 @@
+package commons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemgrepFindings(t *testing.T) {
+	report, err := os.ReadFile(filepath.Join("testdata", "semgrep-report.json"))
+	require.NoError(t, err)
+
+	t.Run("error threshold",
+		func(t *testing.T) {
+			findings, err := semgrepfindings(report, semgrepseverities["ERROR"])
+			require.NoError(t, err)
+
+			require.Len(t, findings, 1)
+			assert.Equal(t, "pkg/db.go", findings[0].Path)
+			assert.Equal(t, 40, findings[0].Start.Line)
+		},
+	)
+
+	t.Run("warning threshold",
+		func(t *testing.T) {
+			findings, err := semgrepfindings(report, semgrepseverities["WARNING"])
+			require.NoError(t, err)
+			assert.Len(t, findings, 2)
+		},
+	)
+
+	t.Run("info threshold",
+		func(t *testing.T) {
+			findings, err := semgrepfindings(report, semgrepseverities["INFO"])
+			require.NoError(t, err)
+			assert.Len(t, findings, 3)
+		},
+	)
+
+	t.Run("invalid report",
+		func(t *testing.T) {
+			_, err := semgrepfindings([]byte("not json"), 0)
+			require.Error(t, err)
+		},
+	)
+}
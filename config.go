@@ -0,0 +1,34 @@
+package harness
+
+import "github.com/aexvir/harness/internal"
+
+// LoadConfig reads the defaults of the harness and binaries from the specified yaml file,
+// for repositories keeping it somewhere else than the harness.yaml file at their root, which
+// is read by default. It needs to be called before creating any harness or binary.
+//
+// The file can specify the directory binaries are provisioned into, their versions, env
+// variables set for every command, output and ci preferences and aliases of registered tasks:
+//
+//	bindir: bin
+//	versions:
+//	  golangci-lint: v1.63.3
+//	env:
+//	  CGO_ENABLED: "0"
+//	output:
+//	  verbosity: normal
+//	ci:
+//	  github_actions: true
+//	aliases:
+//	  l: lint
+//
+// Versions can be written with or without the "v" prefix, they're used the way the tasks
+// provisioning the binaries expect them; see [github.com/aexvir/harness/binary.New].
+func LoadConfig(path string) error {
+	config, err := internal.ReadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	internal.SetConfig(config)
+	return nil
+}
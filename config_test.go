@@ -0,0 +1,52 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/internal"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "harness.yaml")
+	content := "env:\n  HARNESS_CONFIG_TEST: from-config\naliases:\n  l: lint\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	require.NoError(t, LoadConfig(path))
+	t.Cleanup(func() { internal.SetConfig(&internal.Config{}) })
+
+	t.Run("sets env for every command",
+		func(t *testing.T) {
+			r, err := Cmd(t.Context(), "go")
+			require.NoError(t, err)
+			assert.Contains(t, r.cmd.Env, "HARNESS_CONFIG_TEST=from-config")
+
+			// options take precedence, as the last value wins
+			r, err = Cmd(t.Context(), "go", WithEnv("HARNESS_CONFIG_TEST=from-option"))
+			require.NoError(t, err)
+			assert.Equal(t, "HARNESS_CONFIG_TEST=from-option", r.cmd.Env[len(r.cmd.Env)-1])
+		},
+	)
+
+	t.Run("resolves task aliases",
+		func(t *testing.T) {
+			h := New()
+			h.Register("lint", noop)
+
+			task, ok := h.Lookup("l")
+			assert.True(t, ok)
+			assert.NotNil(t, task)
+		},
+	)
+
+	t.Run("returns error for missing files",
+		func(t *testing.T) {
+			err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		},
+	)
+}
@@ -0,0 +1,174 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ContainerWorkspace is where the workspace is mounted inside containers.
+const ContainerWorkspace = "/workspace"
+
+// RunInContainer runs a command inside a container of the specified image, with the current
+// directory mounted as workspace, so tasks like lint or test can run hermetically without the
+// toolchain installed locally. It accepts the same options as [Run]; see [WithContainer].
+func RunInContainer(ctx context.Context, image string, program string, opts ...RunnerOpt) error {
+	return Run(ctx, program, append(opts[:len(opts):len(opts)], WithContainer(image))...)
+}
+
+// WithContainer runs the command inside a container of the specified image using the docker
+// cli, streaming its output back as if it was run locally. The container is removed once the
+// command exits and the exit code of the command is the one reported.
+//
+// The current directory is mounted as workspace at [ContainerWorkspace], which is also the
+// directory the command runs in. Options like [WithEnv] and [WithDir] apply to the local
+// docker cli; use [WithContainerEnv] and [WithContainerWorkdir] for the command itself.
+func WithContainer(image string, opts ...ContainerOpt) RunnerOpt {
+	return func(r *TaskRunner) error {
+		if image == "" {
+			return errors.New("container image can't be empty")
+		}
+
+		conf := containerconf{
+			image:  image,
+			engine: "docker",
+		}
+
+		for _, opt := range opts {
+			opt(&conf)
+		}
+
+		r.container = &conf
+		return nil
+	}
+}
+
+type containerconf struct {
+	image     string
+	engine    string
+	workspace string
+	workdir   string
+	env       []string
+	volumes   []string
+	args      []string
+}
+
+type ContainerOpt func(c *containerconf)
+
+// WithContainerEngine specifies the cli used to run containers; any cli compatible with
+// "docker run" works, like podman.
+func WithContainerEngine(engine string) ContainerOpt {
+	return func(c *containerconf) {
+		c.engine = engine
+	}
+}
+
+// WithContainerWorkspace specifies the directory mounted as workspace; defaults to the
+// current directory.
+func WithContainerWorkspace(dir string) ContainerOpt {
+	return func(c *containerconf) {
+		c.workspace = dir
+	}
+}
+
+// WithContainerWorkdir specifies the directory the command runs in; relative directories
+// are resolved against the workspace.
+func WithContainerWorkdir(dir string) ContainerOpt {
+	return func(c *containerconf) {
+		c.workdir = dir
+	}
+}
+
+// WithContainerEnv sets up environment variables, as NAME=value, for the command.
+func WithContainerEnv(vars ...string) ContainerOpt {
+	return func(c *containerconf) {
+		c.env = append(c.env, vars...)
+	}
+}
+
+// WithContainerVolumes mounts additional volumes, as host:container[:options], e.g. for
+// sharing the go module cache between runs.
+func WithContainerVolumes(volumes ...string) ContainerOpt {
+	return func(c *containerconf) {
+		c.volumes = append(c.volumes, volumes...)
+	}
+}
+
+// WithContainerArgs passes additional arguments to the run command of the container cli,
+// e.g. "--network", "host".
+func WithContainerArgs(args ...string) ContainerOpt {
+	return func(c *containerconf) {
+		c.args = append(c.args, args...)
+	}
+}
+
+// usecontainer makes the command run inside a container.
+func (r *TaskRunner) usecontainer() error {
+	conf := r.container
+
+	engine, err := exec.LookPath(conf.engine)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", conf.engine, err)
+	}
+
+	workspace := conf.workspace
+	if workspace == "" {
+		workspace = "."
+	}
+	workspace, err = filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace %s: %w", conf.workspace, err)
+	}
+
+	workdir := ContainerWorkspace
+	if conf.workdir != "" {
+		workdir = path.Join(ContainerWorkspace, conf.workdir)
+		if path.IsAbs(conf.workdir) {
+			workdir = conf.workdir
+		}
+	}
+
+	// the cli passes stdin through, so don't hand it the terminal unless needed
+	if r.cmd.Stdin == os.Stdin && !r.interactive {
+		r.cmd.Stdin = nil
+	}
+
+	args := []string{conf.engine, "run", "--rm", "--init"}
+	if r.cmd.Stdin != nil {
+		args = append(args, "-i")
+	}
+	if r.interactive {
+		args = append(args, "-t")
+	}
+	args = append(args, "-v", workspace+":"+ContainerWorkspace, "-w", workdir)
+
+	for _, vrb := range conf.env {
+		if name, _, found := strings.Cut(vrb, "="); !found || name == "" {
+			return fmt.Errorf("invalid env format; %s doesn't match NAME=value expectation", vrb)
+		}
+		args = append(args, "-e", vrb)
+	}
+	for _, volume := range conf.volumes {
+		args = append(args, "-v", volume)
+	}
+	args = append(args, conf.args...)
+	args = append(args, conf.image)
+
+	if r.shell {
+		r.script = strings.Join(append([]string{r.Executable}, r.Arguments...), " ")
+		args = append(args, "sh", "-c", r.script)
+	} else {
+		args = append(args, r.Executable)
+		args = append(args, r.Arguments...)
+	}
+
+	r.cmd.Path, r.cmd.Err = engine, nil
+	r.cmd.Args = args
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package harness
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a fake docker shell script")
+	}
+
+	// fake docker cli that prints the arguments it receives
+	bin := t.TempDir()
+	fake := "#!/bin/sh\nprintf '%s\\n' \"$@\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte(fake), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	workspace, err := filepath.Abs(".")
+	require.NoError(t, err)
+
+	t.Run("mounts the workspace and runs the command",
+		func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := RunInContainer(t.Context(), "golang:1.25", "go",
+				WithArgs("test", "./..."),
+				WithStdOut(&out),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t,
+				[]string{
+					"run", "--rm", "--init",
+					"-v", workspace + ":/workspace", "-w", "/workspace",
+					"golang:1.25", "go", "test", "./...",
+				},
+				strings.Fields(out.String()),
+			)
+		},
+	)
+
+	t.Run("applies container options",
+		func(t *testing.T) {
+			r, err := Cmd(t.Context(), "go test ./... | tee out.txt",
+				WithShell(),
+				WithStdInString("input"),
+				WithContainer("golang:1.25",
+					WithContainerWorkdir("tools"),
+					WithContainerEnv("CGO_ENABLED=0"),
+					WithContainerVolumes("gomodcache:/go/pkg/mod"),
+					WithContainerArgs("--network", "host"),
+				),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t,
+				[]string{
+					"docker", "run", "--rm", "--init", "-i",
+					"-v", workspace + ":/workspace", "-w", "/workspace/tools",
+					"-e", "CGO_ENABLED=0", "-v", "gomodcache:/go/pkg/mod", "--network", "host",
+					"golang:1.25", "sh", "-c", "go test ./... | tee out.txt",
+				},
+				r.cmd.Args,
+			)
+			assert.Equal(t, "golang:1.25: go test ./... | tee out.txt", r.describe())
+		},
+	)
+
+	t.Run("can't run over ssh too",
+		func(t *testing.T) {
+			_, err := Cmd(t.Context(), "go", WithContainer("golang:1.25"), WithSSH("agent"))
+			require.Error(t, err)
+		},
+	)
+}
@@ -0,0 +1,100 @@
+package harness
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readenvfile parses the dotenv file at path, returning its variables as NAME=value.
+func readenvfile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var (
+		vars   []string
+		number int
+	)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		number++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid env file %s:%d; %q doesn't match NAME=value expectation", path, number, line)
+		}
+
+		value, err := parseenvvalue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid env file %s:%d: %w", path, number, err)
+		}
+
+		vars = append(vars, name+"="+value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+
+	return vars, nil
+}
+
+// parseenvvalue unquotes a dotenv value; unquoted values end at the first " #" comment.
+func parseenvvalue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch quote := value[0]; quote {
+	case '\'':
+		end := strings.IndexByte(value[1:], quote)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value %s", value)
+		}
+		return value[1 : end+1], nil
+
+	case '"':
+		var unquoted strings.Builder
+		for idx := 1; idx < len(value); idx++ {
+			char := value[idx]
+			switch {
+			case char == '"':
+				return unquoted.String(), nil
+			case char == '\\' && idx+1 < len(value):
+				idx++
+				switch value[idx] {
+				case 'n':
+					unquoted.WriteByte('\n')
+				case 't':
+					unquoted.WriteByte('\t')
+				case '"', '\\':
+					unquoted.WriteByte(value[idx])
+				default:
+					unquoted.WriteByte('\\')
+					unquoted.WriteByte(value[idx])
+				}
+			default:
+				unquoted.WriteByte(char)
+			}
+		}
+		return "", fmt.Errorf("unterminated quoted value %s", value)
+	}
+
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value, nil
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package harness
+
+// resolveextension finds the executable file a path refers to; executables don't need
+// extensions outside windows, so the path is returned as is.
+func resolveextension(path string) string {
+	return path
+}
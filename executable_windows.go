@@ -0,0 +1,37 @@
+package harness
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// resolveextension finds the executable file a path refers to, trying the extensions in
+// PATHEXT when the path has none of them, the same way cmd does; so "scripts\build"
+// resolves to "scripts\build.bat".
+func resolveextension(path string) string {
+	pathext := os.Getenv("PATHEXT")
+	if pathext == "" {
+		pathext = ".com;.exe;.bat;.cmd"
+	}
+
+	var exts []string
+	for ext := range strings.SplitSeq(strings.ToLower(pathext), ";") {
+		if ext != "" && ext[0] == '.' {
+			exts = append(exts, ext)
+		}
+	}
+
+	if slices.Contains(exts, strings.ToLower(filepath.Ext(path))) {
+		return path
+	}
+
+	for _, ext := range exts {
+		if info, err := os.Stat(path + ext); err == nil && !info.IsDir() {
+			return path + ext
+		}
+	}
+
+	return path
+}
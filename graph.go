@@ -0,0 +1,188 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// TaskDef defines a task along with the names of the tasks it depends on.
+type TaskDef struct {
+	Name      string
+	Fn        Task
+	DependsOn []string
+}
+
+// ExecuteGraph runs a set of tasks inside the harness respecting the dependencies between them.
+// Every task is run only once and only after all of its dependencies finished successfully,
+// while tasks that don't depend on each other are run in parallel.
+// As the output of tasks running in parallel interleaves, every line commands write
+// is prefixed with the name of the task that ran them.
+// If a dependency fails, the tasks depending on it are not run and are reported as failed.
+//
+// Dependencies that aren't part of the definitions are looked up in the tasks registered
+// in the harness, along with the dependencies declared for them via [WithTaskDependencies].
+// Missing dependencies or dependency cycles make the execution fail before any task is run.
+func (h *Harness) ExecuteGraph(ctx context.Context, defs ...TaskDef) error {
+	order, err := h.resolve(defs)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.execute(ctx, len(order),
+		func(ctx context.Context, run taskrunner) {
+			type node struct {
+				done   chan struct{}
+				failed bool
+			}
+
+			var width int
+
+			nodes := make(map[string]*node, len(order))
+			for _, def := range order {
+				nodes[def.Name] = &node{done: make(chan struct{})}
+				width = max(width, len(def.Name))
+			}
+
+			// a single task doesn't interleave with anything
+			if len(order) == 1 {
+				width = 0
+			}
+
+			var wg sync.WaitGroup
+
+			for idx, def := range order {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					current := nodes[def.Name]
+					defer close(current.done)
+
+					task := Named(def.Name, def.Fn)
+					for _, dep := range def.DependsOn {
+						dependency, ok := nodes[dep]
+						if !ok {
+							task = Named(def.Name, func(_ context.Context) error {
+								return fmt.Errorf("unknown dependency %s", dep)
+							})
+							break
+						}
+
+						<-dependency.done
+						if dependency.failed {
+							task = Named(def.Name, func(_ context.Context) error {
+								return fmt.Errorf("skipped as its dependency %s failed", dep)
+							})
+							break
+						}
+					}
+
+					current.failed = run(ctx, idx, def.Name, prefixed(task, width)) != nil
+				}()
+			}
+
+			wg.Wait()
+		},
+	)
+	return err
+}
+
+// resolve returns the definitions, along with the registered tasks they depend on, sorted
+// so that every task comes after all its dependencies.
+func (h *Harness) resolve(defs []TaskDef) ([]TaskDef, error) {
+	known := make(map[string]TaskDef, len(defs))
+	for _, def := range defs {
+		if def.Fn == nil {
+			return nil, fmt.Errorf("task %q has no function", def.Name)
+		}
+		if _, ok := known[def.Name]; ok {
+			return nil, fmt.Errorf("task %q is defined more than once", def.Name)
+		}
+		known[def.Name] = def
+	}
+
+	lookup := func(name, dependent string) (TaskDef, error) {
+		if def, ok := known[name]; ok {
+			return def, nil
+		}
+
+		name = resolvealias(name)
+		if def, ok := known[name]; ok {
+			return def, nil
+		}
+
+		for _, reg := range h.registry {
+			if reg.info.Name == name {
+				def := TaskDef{Name: name, Fn: reg.task, DependsOn: reg.info.DependsOn}
+				known[name] = def
+				return def, nil
+			}
+		}
+
+		return TaskDef{}, fmt.Errorf("unknown task %q required by %q", name, dependent)
+	}
+
+	var (
+		order    []TaskDef
+		visited  = make(map[string]bool)
+		visiting []string
+	)
+
+	var visit func(def TaskDef) error
+	visit = func(def TaskDef) error {
+		if visited[def.Name] {
+			return nil
+		}
+
+		for idx, name := range visiting {
+			if name == def.Name {
+				cycle := append(append([]string{}, visiting[idx:]...), def.Name)
+				return fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		visiting = append(visiting, def.Name)
+		// dependencies are referenced by the name they resolve to, as aliases aren't nodes
+		deps := make([]string, 0, len(def.DependsOn))
+		for _, name := range def.DependsOn {
+			dep, err := lookup(name, def.Name)
+			if err != nil {
+				return err
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+			deps = append(deps, dep.Name)
+		}
+		def.DependsOn = deps
+		visiting = visiting[:len(visiting)-1]
+
+		visited[def.Name] = true
+		order = append(order, def)
+		return nil
+	}
+
+	for _, def := range defs {
+		if err := visit(def); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// prefixed makes the commands run by the task prefix their output lines with the task
+// name, padded to the specified width.
+func prefixed(task Task, width int) Task {
+	return func(ctx context.Context) error {
+		if state := currenttask(ctx); state != nil {
+			state.mtx.Lock()
+			state.prefixwidth = width
+			state.mtx.Unlock()
+		}
+		return task(ctx)
+	}
+}
@@ -0,0 +1,217 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/internal"
+)
+
+func TestHarnessExecuteGraph(t *testing.T) {
+	t.Run("runs dependencies first and only once",
+		func(t *testing.T) {
+			var (
+				mtx   sync.Mutex
+				order []string
+			)
+
+			record := func(name string) Task {
+				return func(_ context.Context) error {
+					mtx.Lock()
+					defer mtx.Unlock()
+					order = append(order, name)
+					return nil
+				}
+			}
+
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "test", Fn: record("test"), DependsOn: []string{"provision", "generate"}},
+				TaskDef{Name: "generate", Fn: record("generate"), DependsOn: []string{"provision"}},
+				TaskDef{Name: "provision", Fn: record("provision")},
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"provision", "generate", "test"}, order)
+		},
+	)
+
+	t.Run("runs independent tasks in parallel",
+		func(t *testing.T) {
+			// each task waits for the other one to start, which only works if they run at the same time
+			uno, dos := make(chan struct{}), make(chan struct{})
+			wait := func(started, other chan struct{}) Task {
+				return func(_ context.Context) error {
+					close(started)
+					select {
+					case <-other:
+						return nil
+					case <-time.After(5 * time.Second):
+						return errors.New("timed out waiting for the other task")
+					}
+				}
+			}
+
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "uno", Fn: wait(uno, dos)},
+				TaskDef{Name: "dos", Fn: wait(dos, uno)},
+			)
+
+			require.NoError(t, err)
+		},
+	)
+
+	t.Run("skips tasks whose dependencies failed",
+		func(t *testing.T) {
+			called := false
+
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "lint", Fn: func(_ context.Context) error { called = true; return nil }, DependsOn: []string{"tidy"}},
+				TaskDef{Name: "tidy", Fn: func(_ context.Context) error { return errors.New("boom") }},
+			)
+
+			require.Error(t, err)
+			assert.False(t, called)
+		},
+	)
+
+	t.Run("names tasks skipped after a failure",
+		func(t *testing.T) {
+			buf := captureoutput(t)
+			called := false
+
+			err := New(WithFailFast()).ExecuteGraph(t.Context(),
+				TaskDef{Name: "tidy", Fn: func(_ context.Context) error { return errors.New("boom") }},
+				TaskDef{Name: "build", Fn: func(_ context.Context) error { time.Sleep(50 * time.Millisecond); return nil }},
+				TaskDef{Name: "lint", Fn: func(_ context.Context) error { called = true; return nil }, DependsOn: []string{"build"}},
+			)
+
+			require.Error(t, err)
+			assert.False(t, called)
+			assert.Regexp(t, `lint +\S+ skipped`, buf.String())
+		},
+	)
+
+	t.Run("resolves dependencies from the registry",
+		func(t *testing.T) {
+			var order []string
+			h := New()
+
+			h.Register("download", func(_ context.Context) error { order = append(order, "download"); return nil })
+			h.Register("provision",
+				func(_ context.Context) error { order = append(order, "provision"); return nil },
+				WithTaskDependencies("download"),
+			)
+
+			err := h.ExecuteGraph(t.Context(),
+				TaskDef{
+					Name:      "lint",
+					Fn:        func(_ context.Context) error { order = append(order, "lint"); return nil },
+					DependsOn: []string{"provision"},
+				},
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"download", "provision", "lint"}, order)
+		},
+	)
+
+	t.Run("resolves aliased dependencies",
+		func(t *testing.T) {
+			internal.SetConfig(&internal.Config{Aliases: map[string]string{"b": "build"}})
+			t.Cleanup(func() { internal.SetConfig(&internal.Config{}) })
+
+			var order []string
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "test", Fn: func(_ context.Context) error { order = append(order, "test"); return nil }, DependsOn: []string{"b"}},
+				TaskDef{Name: "build", Fn: func(_ context.Context) error { order = append(order, "build"); return nil }},
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"build", "test"}, order)
+		},
+	)
+
+	t.Run("fails on unknown dependencies",
+		func(t *testing.T) {
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "lint", Fn: noop, DependsOn: []string{"missing"}},
+			)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `unknown task "missing" required by "lint"`)
+		},
+	)
+
+	t.Run("fails on cycles",
+		func(t *testing.T) {
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "uno", Fn: noop, DependsOn: []string{"dos"}},
+				TaskDef{Name: "dos", Fn: noop, DependsOn: []string{"tres"}},
+				TaskDef{Name: "tres", Fn: noop, DependsOn: []string{"uno"}},
+			)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "dependency cycle detected: uno -> dos -> tres -> uno")
+		},
+	)
+
+	t.Run("fails on duplicated definitions",
+		func(t *testing.T) {
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "uno", Fn: noop},
+				TaskDef{Name: "uno", Fn: noop},
+			)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "defined more than once")
+		},
+	)
+
+	t.Run("prefixes the output of parallel tasks",
+		func(t *testing.T) {
+			nocolor := color.NoColor
+			color.NoColor = true
+			defer func() { color.NoColor = nocolor }()
+
+			var out bytes.Buffer
+			stdout, stderr := internal.Stdout, internal.Stderr
+			internal.Stdout = internal.NewSyncWriter(&out)
+			internal.Stderr = internal.Stdout
+			defer func() { internal.Stdout, internal.Stderr = stdout, stderr }()
+
+			mixed := func(ctx context.Context) error {
+				return Run(ctx, "testdata/util.sh", WithArgs("mixed"))
+			}
+
+			var listing bytes.Buffer
+			err := New().ExecuteGraph(t.Context(),
+				TaskDef{Name: "lint", Fn: mixed},
+				TaskDef{Name: "test", Fn: mixed, DependsOn: []string{"lint"}},
+				TaskDef{Name: "generate", Fn: func(ctx context.Context) error {
+					// output captured explicitly is left untouched
+					return Run(ctx, "testdata/util.sh", WithArgs("success"), WithStdOut(&listing))
+				}},
+			)
+			require.NoError(t, err)
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			assert.ElementsMatch(t,
+				[]string{
+					"lint     | one", "lint     | two", "lint     | three",
+					"test     | one", "test     | two", "test     | three",
+				},
+				lines,
+			)
+			assert.Equal(t, "ok", listing.String())
+		},
+	)
+}
@@ -1,8 +1,15 @@
 package harness
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aexvir/harness/internal"
@@ -12,15 +19,62 @@ import (
 // pre- and post- execution hook functions, where common functionality to all tasks
 // can be defined.
 type Harness struct {
-	PreExecHook  Task
+	// PreExecHook is run before the tasks of every execution, ahead of the hooks
+	// specified via [WithPreExecFunc].
+	//
+	// Deprecated: use [WithPreExecFunc] instead, which can be specified multiple times.
+	PreExecHook Task
+	// PostExecHook is run after the tasks of every execution, following the hooks
+	// specified via [WithPostExecFunc] and [WithPostExecHook].
+	//
+	// Deprecated: use [WithPostExecFunc] or [WithPostExecHook] instead, which can be
+	// specified multiple times.
 	PostExecHook Task
+
+	prehooks   []Task
+	posthooks  []ResultHook
+	decorators []ContextDecorator
+	registry   []registration
+	live       bool
+	capture    bool
+	failfast   bool
+
+	sections    sections
+	stepsummary bool
+
+	metrics []MetricsSink
+
+	includetags []string
+	excludetags []string
+
+	summaryfile string
+
+	nosignals bool
+	grace     time.Duration
 }
 
 // New constructs a harness.
 func New(opts ...Option) *Harness {
-	h := Harness{
-		PreExecHook:  func(_ context.Context) error { return nil },
-		PostExecHook: func(_ context.Context) error { return nil },
+	h := Harness{grace: 10 * time.Second}
+
+	switch {
+	case isgitlabci():
+		h.sections = gitlabsections{}
+	case isgithubactions():
+		h.sections = githubsections{}
+	}
+
+	// defaults from the config file are applied first, so options can override them
+	config := internal.CurrentConfig()
+	config.ApplyOutput()
+	if enabled := config.CI.GitHubActions; enabled != nil {
+		WithGitHubActions(*enabled)(&h)
+	}
+	if enabled := config.CI.GitLabCI; enabled != nil {
+		WithGitLabCI(*enabled)(&h)
+	}
+	if enabled := config.CI.GitHubStepSummary; enabled != nil {
+		WithGitHubStepSummary(*enabled)(&h)
 	}
 
 	for _, opt := range opts {
@@ -33,46 +87,264 @@ func New(opts ...Option) *Harness {
 // Execute a list of tasks inside the harness.
 // Every task inside the harness is run sequentially, showing a consistent output where
 // the task status and timing info are clearly visible.
+// By default all tasks are run even if some of them fail; see [WithFailFast].
 func (h *Harness) Execute(ctx context.Context, tasks ...Task) error {
-	var errs []string
+	_, err := h.ExecuteWithResults(ctx, tasks...)
+	return err
+}
+
+// ExecuteWithResults runs a list of tasks inside the harness the same way [Harness.Execute] does,
+// additionally returning the result of every task, in the same order the tasks were specified.
+// Tasks can be given a name to be identified in the results via [Named].
+func (h *Harness) ExecuteWithResults(ctx context.Context, tasks ...Task) ([]TaskResult, error) {
+	return h.execute(ctx, len(tasks),
+		func(ctx context.Context, run taskrunner) {
+			for idx, task := range tasks {
+				_ = run(ctx, idx, "", task)
+			}
+		},
+	)
+}
+
+// taskrunner runs a single task of an execution, keeping track of its outcome.
+// The name identifies the task when it's known before running it, e.g. from its definition,
+// otherwise it's empty. It's safe to be called concurrently.
+type taskrunner func(ctx context.Context, idx int, name string, task Task) error
+
+// execute runs the hooks around the tasks run by the schedule function and reports the
+// outcome of the execution; the schedule function decides in which order tasks are run.
+func (h *Harness) execute(ctx context.Context, total int, schedule func(ctx context.Context, run taskrunner)) ([]TaskResult, error) {
+	var mtx sync.Mutex
+	results := make([]TaskResult, total)
+
 	start := time.Now()
 
+	for _, decorate := range h.decorators {
+		ctx = decorate(ctx)
+	}
+
 	internal.LogBlank()
 
-	if err := h.PreExecHook(ctx); err != nil {
-		return fmt.Errorf("failed to initialize ci harness: %s", err.Error())
+	// post hooks run even if the pre hooks fail or a task panics
+	var preerr error
+	posthooks := sync.OnceValue(
+		func() error {
+			mtx.Lock()
+			errs := []error{}
+			if preerr != nil {
+				errs = append(errs, preerr)
+			}
+			for _, result := range results {
+				if result.Err != nil {
+					errs = append(errs, result.Err)
+				}
+			}
+			mtx.Unlock()
+
+			return h.runposthooks(ctx, errs)
+		},
+	)
+	defer func() { _ = posthooks() }()
+
+	prehooks := h.prehooks
+	if h.PreExecHook != nil {
+		prehooks = append([]Task{h.PreExecHook}, prehooks...)
 	}
 
-	progress := internal.NewTaskProgressTracker(ctx, len(tasks))
+	for _, hook := range prehooks {
+		if preerr = hook(ctx); preerr != nil {
+			err := fmt.Errorf("failed to initialize ci harness: %s", preerr.Error())
+			if posterr := posthooks(); posterr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to run post exec hook: %s", posterr.Error()))
+			}
+			return nil, err
+		}
+	}
+
+	progress := internal.NewTaskProgressTracker(ctx, total)
 	defer progress.Clear()
 
-	for _, task := range tasks {
-		err := task(ctx)
-		if err != nil {
-			errs = append(errs, err.Error())
-		}
-		progress.TaskFinished(err)
+	var status *internal.LiveStatus
+	if h.live {
+		status = internal.StartLiveStatus(ctx, total)
 	}
 
-	if err := h.PostExecHook(ctx); err != nil {
-		return fmt.Errorf("failed to run post exec hook: %s", err.Error())
+	excluded := h.excludedtags()
+
+	// in fail fast mode the first failure, or an interruption, cancels the tasks
+	// still running and prevents the remaining ones from starting
+	taskctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
+	var (
+		aborted bool
+		skipped int
+	)
+
+	if !h.nosignals {
+		stop := trapsignals(abort, func() {
+			mtx.Lock()
+			defer mtx.Unlock()
+			aborted = true
+		})
+		defer stop()
+	}
+
+	schedule(taskctx,
+		func(ctx context.Context, idx int, name string, task Task) error {
+			mtx.Lock()
+			if aborted {
+				if name == "" {
+					name = fmt.Sprintf("task %d", idx+1)
+				}
+				results[idx] = TaskResult{Name: name, Skipped: true}
+				skipped++
+				mtx.Unlock()
+				return errAborted
+			}
+			mtx.Unlock()
+
+			if status != nil {
+				status.TaskStarted(idx)
+			}
+
+			state := &taskstate{idx: idx, exclude: excluded, grace: h.grace}
+
+			var section string
+			if h.sections != nil {
+				state.onbegin = func(name string) {
+					section = sectionid(idx, name)
+					h.sections.open(section, name)
+				}
+			}
+
+			if h.capture {
+				state.output = new(bytes.Buffer)
+				state.writer = internal.NewSyncWriter(state.output)
+			}
+
+			taskstart := time.Now()
+			err := runtask(context.WithValue(ctx, taskctxkey{}, state), task)
+			result := state.result(time.Since(taskstart), err)
+			internal.Metrics.ObserveTask(result.Name, result.Duration, err)
+
+			if section != "" {
+				h.sections.close(section)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			results[idx] = result
+			progress.TaskFinished(err)
+
+			if err != nil && h.failfast && !state.continueonerror && !aborted {
+				aborted = true
+				abort(nil)
+			}
+
+			if status != nil {
+				status.TaskFinished(err)
+			}
+
+			return err
+		},
+	)
+
+	if status != nil {
+		status.Stop()
+	}
+
+	internal.Metrics.ObserveExecution(time.Since(start))
+	h.exportmetrics(ctx)
+
+	if err := posthooks(); err != nil {
+		return results, fmt.Errorf("failed to run post exec hook: %s", err.Error())
+	}
+
+	if h.stepsummary {
+		if err := writestepsummary(results); err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to write github step summary: %s", err))
+		}
 	}
 
 	elapsed := time.Since(start).Round(time.Millisecond)
 	internal.LogSeparator()
 
-	if len(errs) > 0 {
+	h.summarize(results)
+
+	var failed []TaskResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	if len(failed) > 0 {
 		internal.LogError(fmt.Sprintf("finished with errors after %s", elapsed))
-		for _, errmsg := range errs {
-			internal.LogErrorItem(errmsg)
+		for _, result := range failed {
+			internal.LogErrorItem(fmt.Sprintf("%s: %s", result.Name, result.Err.Error()))
+		}
+		cause := context.Cause(taskctx)
+		interrupted := errors.Is(cause, ErrInterrupted)
+
+		if skipped > 0 {
+			reason := "the first failure"
+			if interrupted {
+				reason = "the interruption"
+			}
+			internal.LogDetail(fmt.Sprintf("%d tasks skipped after %s", skipped, reason))
 		}
 		internal.LogBlank()
-		return fmt.Errorf("task finished with errors")
+
+		if interrupted {
+			return results, cause
+		}
+		return results, fmt.Errorf("task finished with errors")
 	}
 
 	internal.LogSuccess(fmt.Sprintf("all good after %s", elapsed))
 	internal.LogBlank()
-	return nil
+	return results, nil
+}
+
+// summarize prints a table with the outcome of every task, also writing it to the
+// summary file if configured.
+func (h *Harness) summarize(results []TaskResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	rows := make([]internal.SummaryRow, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, internal.SummaryRow{Name: result.Name, Status: result.status(), Duration: result.Duration})
+	}
+
+	internal.LogSummary(rows)
+	internal.LogBlank()
+
+	if h.summaryfile != "" {
+		if err := os.WriteFile(h.summaryfile, []byte(internal.RenderSummary(rows, false)), 0o644); err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to write summary file: %s", err))
+		}
+	}
+}
+
+// runposthooks runs all post hooks, even if some of them fail, passing them the errors of
+// the execution.
+func (h *Harness) runposthooks(ctx context.Context, errs []error) error {
+	var hookerrs []error
+	for _, hook := range h.posthooks {
+		if err := hook(ctx, errs); err != nil {
+			hookerrs = append(hookerrs, err)
+		}
+	}
+	if h.PostExecHook != nil {
+		if err := h.PostExecHook(ctx); err != nil {
+			hookerrs = append(hookerrs, err)
+		}
+	}
+	return errors.Join(hookerrs...)
 }
 
 // Logs the name of a task step.
@@ -90,18 +362,242 @@ type Task func(ctx context.Context) error
 
 type Option func(h *Harness)
 
+// ResultHook is run after the tasks of an execution, receiving the errors of the
+// tasks that failed, or of the pre execution hook if it failed; empty when all succeeded.
+type ResultHook func(ctx context.Context, errs []error) error
+
+// ContextDecorator derives the context that is passed to the hooks and tasks of
+// an execution; typically by attaching values to it.
+type ContextDecorator func(ctx context.Context) context.Context
+
 // WithPreExecFunc allows specifying a [Task] that will be run every execution, **before** the
 // specific execution tasks are run.
+// Can be specified multiple times; hooks are run in order and if any of them fails, the
+// execution is aborted.
 func WithPreExecFunc(hook Task) Option {
 	return func(h *Harness) {
-		h.PreExecHook = hook
+		h.prehooks = append(h.prehooks, hook)
 	}
 }
 
 // WithPostExecFunc allows specifying a [Task] that will be run every execution, **after** the
 // specific execution tasks are run.
+// Post hooks are always run, even if tasks or pre hooks fail; see [WithPostExecHook] for
+// hooks that need to know about failures.
 func WithPostExecFunc(hook Task) Option {
+	return WithPostExecHook(
+		func(ctx context.Context, _ []error) error {
+			return hook(ctx)
+		},
+	)
+}
+
+// WithPostExecHook allows specifying a [ResultHook] that will be run every execution, **after**
+// the specific execution tasks are run, receiving the errors the execution accumulated.
+// Can be specified multiple times; hooks are run in order, all of them even if some fail.
+// Post hooks are always run, even if tasks or pre hooks fail.
+func WithPostExecHook(hook ResultHook) Option {
+	return func(h *Harness) {
+		h.posthooks = append(h.posthooks, hook)
+	}
+}
+
+// WithContextDecorator allows specifying a [ContextDecorator] that will be applied to
+// the context every execution, before any hook or task is run.
+// This is useful to share values like loggers, ci metadata or run ids with every task
+// without relying on global variables.
+// Decorators are applied in the order they were specified.
+func WithContextDecorator(decorator ContextDecorator) Option {
+	return func(h *Harness) {
+		h.decorators = append(h.decorators, decorator)
+	}
+}
+
+// WithLiveProgress enables displaying which task is currently running and for how long.
+// On terminals, the status is updated in place with a spinner below the output of the task;
+// while it's active, command output is routed through the harness to keep both from
+// overlapping. On other outputs, like ci logs, a plain line is logged when each task
+// starts and finishes.
+func WithLiveProgress() Option {
+	return func(h *Harness) {
+		h.live = true
+	}
+}
+
+// WithTaskOutputCapture enables capturing the output of the commands run by every task,
+// which is then available in the [TaskResult] of the task.
+// Output keeps being displayed as usual, but commands no longer write directly to the
+// terminal, which makes some of them disable colored output.
+func WithTaskOutputCapture() Option {
+	return func(h *Harness) {
+		h.capture = true
+	}
+}
+
+// WithFailFast makes the harness stop at the first failing task instead of running all of them;
+// tasks still running are cancelled and the remaining ones are skipped.
+// Tasks wrapped with [ContinueOnError] don't stop the execution when they fail.
+func WithFailFast() Option {
+	return func(h *Harness) {
+		h.failfast = true
+	}
+}
+
+// WithLogHandler sends all harness and binary logs to the specified handler instead of
+// the default pretty output. Every record carries a "kind" attribute describing the
+// type of line, e.g. "command", "step", "detail" or "error".
+// Logging is process-wide, so this affects every harness.
+func WithLogHandler(handler slog.Handler) Option {
+	return func(_ *Harness) {
+		internal.SetLogHandler(handler)
+	}
+}
+
+// WithLogLevel sets the minimum level of the logs that are emitted; details are logged at
+// debug level, errors at error level and everything else at info level. All logs down to
+// debug level are emitted by default.
+// Logging is process-wide, so this affects every harness.
+func WithLogLevel(level slog.Level) Option {
+	return func(_ *Harness) {
+		internal.SetLogLevel(level)
+	}
+}
+
+// WithVerbosity sets how much is shown while tasks run, from only errors to how every
+// command is run; the output of commands is hidden when quiet, except for failing ones.
+// It defaults to [VerbosityVerbose], or to the value of the HARNESS_VERBOSITY env variable:
+// quiet, normal, verbose or debug.
+// Verbosity is process-wide, so this affects every harness.
+func WithVerbosity(verbosity Verbosity) Option {
+	return func(_ *Harness) {
+		internal.SetVerbosity(verbosity)
+	}
+}
+
+// WithoutColor disables colored output.
+func WithoutColor() Option {
+	return func(_ *Harness) {
+		internal.DisableColor()
+	}
+}
+
+// WithPlainOutput disables colors along with the live status, progress reports and
+// progress bars, for ci systems that don't render terminal escape sequences; commands
+// are asked to avoid colors too via the NO_COLOR env variable.
+// Colors are also disabled when NO_COLOR is set, and escape sequences on dumb terminals.
+// Output is process-wide, so this affects every harness.
+func WithPlainOutput() Option {
+	return func(_ *Harness) {
+		internal.SetPlainOutput()
+	}
+}
+
+// WithGitHubActions controls if the output of every task is wrapped in a collapsible group of
+// the github actions job log. It's enabled automatically when running on github actions.
+// Groups can't be nested nor interleaved, so they're mostly useful when tasks run sequentially.
+// Disabling it also disables the step summary, see [WithGitHubStepSummary].
+func WithGitHubActions(enabled bool) Option {
+	return func(h *Harness) {
+		if enabled {
+			h.sections = githubsections{}
+			return
+		}
+
+		if h.sections == (githubsections{}) {
+			h.sections = nil
+		}
+		h.stepsummary = false
+	}
+}
+
+// WithGitLabCI controls if the output of every task is wrapped in a collapsible section of
+// the gitlab ci job log. It's enabled automatically when running on gitlab ci.
+func WithGitLabCI(enabled bool) Option {
+	return func(h *Harness) {
+		switch {
+		case enabled:
+			h.sections = gitlabsections{}
+		case h.sections == (gitlabsections{}):
+			h.sections = nil
+		}
+	}
+}
+
+// WithGitHubStepSummary writes a markdown table with the result of every task to the
+// github actions step summary once the execution finishes.
+// Nothing is written when not running on github actions.
+func WithGitHubStepSummary(enabled bool) Option {
+	return func(h *Harness) {
+		h.stepsummary = enabled
+	}
+}
+
+// WithIncludeTags only runs the tagged tasks that have at least one of the specified tags;
+// see [Tagged] and [WithTaskTags]. Tasks without tags aren't affected.
+func WithIncludeTags(tags ...string) Option {
+	return func(h *Harness) {
+		h.includetags = append(h.includetags, tags...)
+	}
+}
+
+// WithExcludeTags skips the tasks tagged with any of the specified tags; see [Tagged]
+// and [WithTaskTags]. Tags can also be excluded using the HARNESS_SKIP_TAGS environment
+// variable, as a comma separated list, e.g. HARNESS_SKIP_TAGS=slow,network.
+func WithExcludeTags(tags ...string) Option {
+	return func(h *Harness) {
+		h.excludetags = append(h.excludetags, tags...)
+	}
+}
+
+// excludedtags returns the function deciding if tasks with the specified tags are skipped
+// in the current execution, along with the reason why.
+func (h *Harness) excludedtags() func(tags []string) (string, bool) {
+	exclude := slices.Clone(h.excludetags)
+	for _, tag := range strings.Split(os.Getenv("HARNESS_SKIP_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			exclude = append(exclude, tag)
+		}
+	}
+
+	return func(tags []string) (string, bool) {
+		for _, tag := range tags {
+			if slices.Contains(exclude, tag) {
+				return fmt.Sprintf("tagged %s", tag), true
+			}
+		}
+
+		if len(h.includetags) == 0 {
+			return "", false
+		}
+		for _, tag := range tags {
+			if slices.Contains(h.includetags, tag) {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("not tagged %s", strings.Join(h.includetags, ", ")), true
+	}
+}
+
+// WithSummaryFile additionally writes the summary table printed at the end of every
+// execution to the specified file, replacing it.
+func WithSummaryFile(path string) Option {
+	return func(h *Harness) {
+		h.summaryfile = path
+	}
+}
+
+// WithShutdownGracePeriod specifies how long commands are given to exit after being asked
+// to shut down when the execution is interrupted, before being killed; 10s by default.
+func WithShutdownGracePeriod(grace time.Duration) Option {
+	return func(h *Harness) {
+		h.grace = grace
+	}
+}
+
+// WithoutSignalHandling disables trapping SIGINT and SIGTERM during executions, restoring the
+// default behavior of terminating the process right away.
+func WithoutSignalHandling() Option {
 	return func(h *Harness) {
-		h.PostExecHook = hook
+		h.nosignals = true
 	}
 }
@@ -6,7 +6,9 @@ import (
 	"flag"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -126,4 +128,478 @@ func TestHarnessExecute(t *testing.T) {
 			assert.True(t, called)
 		},
 	)
+
+	t.Run("runs multiple hooks in order",
+		func(t *testing.T) {
+			var order []string
+			hook := func(name string) Task {
+				return func(_ context.Context) error { order = append(order, name); return nil }
+			}
+
+			h := New(
+				WithPreExecFunc(hook("pre1")),
+				WithPreExecFunc(hook("pre2")),
+				WithPostExecFunc(hook("post1")),
+				WithPostExecFunc(hook("post2")),
+			)
+
+			err := h.Execute(t.Context(), hook("uno"))
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"pre1", "pre2", "uno", "post1", "post2"}, order)
+		},
+	)
+
+	t.Run("passes task errors to post hooks",
+		func(t *testing.T) {
+			var received []error
+			h := New(
+				WithPostExecHook(
+					func(_ context.Context, errs []error) error {
+						received = errs
+						return nil
+					},
+				),
+			)
+
+			err := h.Execute(t.Context(),
+				func(_ context.Context) error { return errors.New("first error") },
+				func(_ context.Context) error { return nil },
+				func(_ context.Context) error { return errors.New("second error") },
+			)
+
+			require.Error(t, err)
+			require.Len(t, received, 2)
+			assert.EqualError(t, received[0], "first error")
+			assert.EqualError(t, received[1], "second error")
+		},
+	)
+
+	t.Run("runs post hooks when pre exec hook fails",
+		func(t *testing.T) {
+			var received []error
+			ran := false
+
+			h := New(
+				WithPreExecFunc(func(_ context.Context) error { return errors.New("pre boom") }),
+				WithPostExecHook(
+					func(_ context.Context, errs []error) error {
+						received = errs
+						return nil
+					},
+				),
+			)
+
+			err := h.Execute(t.Context(), func(_ context.Context) error { ran = true; return nil })
+
+			require.Error(t, err)
+			assert.False(t, ran)
+			require.Len(t, received, 1)
+			assert.EqualError(t, received[0], "pre boom")
+		},
+	)
+
+	t.Run("reports post hook errors when pre exec hook fails",
+		func(t *testing.T) {
+			h := New(
+				WithPreExecFunc(func(_ context.Context) error { return errors.New("pre boom") }),
+				WithPostExecFunc(func(_ context.Context) error { return errors.New("post boom") }),
+			)
+
+			err := h.Execute(t.Context(), noop)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "pre boom")
+			assert.Contains(t, err.Error(), "post boom")
+		},
+	)
+
+	t.Run("runs deprecated hook fields",
+		func(t *testing.T) {
+			var order []string
+			hook := func(name string) Task {
+				return func(_ context.Context) error {
+					order = append(order, name)
+					return nil
+				}
+			}
+
+			h := New(WithPreExecFunc(hook("pre")), WithPostExecFunc(hook("post")))
+			h.PreExecHook = hook("legacy pre")
+			h.PostExecHook = hook("legacy post")
+
+			require.NoError(t, h.Execute(t.Context(), hook("uno")))
+			assert.Equal(t, []string{"legacy pre", "pre", "uno", "post", "legacy post"}, order)
+
+			order = nil
+			legacy := Harness{PreExecHook: hook("legacy pre"), PostExecHook: hook("legacy post")}
+			require.NoError(t, legacy.Execute(t.Context(), hook("uno")))
+			assert.Equal(t, []string{"legacy pre", "uno", "legacy post"}, order)
+		},
+	)
+
+	t.Run("runs all post hooks even if some fail",
+		func(t *testing.T) {
+			called := false
+			h := New(
+				WithPostExecFunc(func(_ context.Context) error { return errors.New("post boom") }),
+				WithPostExecFunc(func(_ context.Context) error { called = true; return nil }),
+			)
+
+			err := h.Execute(t.Context(), func(_ context.Context) error { return nil })
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "post boom")
+			assert.True(t, called)
+		},
+	)
+
+	t.Run("recovers panicking tasks",
+		func(t *testing.T) {
+			var received []error
+			ran := false
+
+			h := New(
+				WithPostExecHook(
+					func(_ context.Context, errs []error) error {
+						received = errs
+						return nil
+					},
+				),
+			)
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				func(_ context.Context) error { panic("boom") },
+				func(_ context.Context) error { ran = true; return nil },
+			)
+
+			require.Error(t, err)
+			assert.True(t, ran)
+			require.Len(t, received, 1)
+
+			var perr *PanicError
+			require.ErrorAs(t, results[0].Err, &perr)
+			assert.Equal(t, "boom", perr.Value)
+			assert.Contains(t, string(perr.Stack), "runtime/debug.Stack")
+			assert.EqualError(t, perr, "task panicked: boom")
+		},
+	)
+
+	t.Run("panicking task stops execution in fail fast mode",
+		func(t *testing.T) {
+			ran := false
+			h := New(WithFailFast())
+
+			err := h.Execute(t.Context(),
+				func(_ context.Context) error { panic("boom") },
+				func(_ context.Context) error { ran = true; return nil },
+			)
+
+			require.Error(t, err)
+			assert.False(t, ran)
+		},
+	)
+
+	t.Run("recovers panics of tasks run in parallel",
+		func(t *testing.T) {
+			h := New()
+
+			err := h.ExecuteGraph(t.Context(),
+				TaskDef{Name: "boom", Fn: func(_ context.Context) error { panic("boom") }},
+				TaskDef{Name: "ok", Fn: func(_ context.Context) error { return nil }},
+			)
+
+			require.Error(t, err)
+		},
+	)
+
+	t.Run("decorates context for hooks and tasks",
+		func(t *testing.T) {
+			type key string
+			var seen []any
+
+			h := New(
+				WithContextDecorator(
+					func(ctx context.Context) context.Context {
+						return context.WithValue(ctx, key("run"), "uno")
+					},
+				),
+				WithContextDecorator(
+					func(ctx context.Context) context.Context {
+						return context.WithValue(ctx, key("profile"), ctx.Value(key("run")).(string)+"-dos")
+					},
+				),
+				WithPreExecFunc(
+					func(ctx context.Context) error {
+						seen = append(seen, ctx.Value(key("run")))
+						return nil
+					},
+				),
+			)
+
+			err := h.Execute(t.Context(),
+				func(ctx context.Context) error {
+					seen = append(seen, ctx.Value(key("profile")))
+					return nil
+				},
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, []any{"uno", "uno-dos"}, seen)
+		},
+	)
+
+	t.Run("live progress doesn't alter execution",
+		func(t *testing.T) {
+			var order []string
+			h := New(WithLiveProgress())
+
+			err := h.Execute(t.Context(),
+				func(_ context.Context) error { order = append(order, "uno"); return nil },
+				func(_ context.Context) error { order = append(order, "dos"); return errors.New("boom") },
+			)
+
+			require.Error(t, err)
+			assert.Equal(t, []string{"uno", "dos"}, order)
+		},
+	)
+}
+
+func TestHarnessExecuteWithResults(t *testing.T) {
+	t.Run("returns a result per task in order",
+		func(t *testing.T) {
+			h := New()
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				Named("lint", func(_ context.Context) error { return nil }),
+				func(_ context.Context) error { return errors.New("boom") },
+			)
+
+			require.Error(t, err)
+			require.Len(t, results, 2)
+
+			assert.Equal(t, "lint", results[0].Name)
+			assert.NoError(t, results[0].Err)
+
+			assert.Equal(t, "task 2", results[1].Name)
+			assert.EqualError(t, results[1].Err, "boom")
+		},
+	)
+
+	t.Run("outermost name wins",
+		func(t *testing.T) {
+			h := New()
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				Named("outer", Named("inner", func(_ context.Context) error { return nil })),
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, "outer", results[0].Name)
+		},
+	)
+
+	t.Run("captures command output when enabled",
+		func(t *testing.T) {
+			h := New(WithTaskOutputCapture())
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				func(ctx context.Context) error {
+					return Run(ctx, "sh", WithArgs("testdata/util.sh", "mixed"))
+				},
+			)
+
+			require.NoError(t, err)
+			assert.Contains(t, results[0].Output, "one\n")
+			assert.Contains(t, results[0].Output, "two\n")
+			assert.Contains(t, results[0].Output, "three\n")
+		},
+	)
+
+	t.Run("doesn't capture output by default",
+		func(t *testing.T) {
+			h := New()
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				func(ctx context.Context) error {
+					return Run(ctx, "sh", WithArgs("testdata/util.sh", "success"))
+				},
+			)
+
+			require.NoError(t, err)
+			assert.Empty(t, results[0].Output)
+		},
+	)
+}
+
+func TestHarnessFailFast(t *testing.T) {
+	t.Run("runs all tasks by default",
+		func(t *testing.T) {
+			var order []string
+			h := New()
+
+			err := h.Execute(t.Context(),
+				func(_ context.Context) error { order = append(order, "uno"); return errors.New("boom") },
+				func(_ context.Context) error { order = append(order, "dos"); return nil },
+			)
+
+			require.Error(t, err)
+			assert.Equal(t, []string{"uno", "dos"}, order)
+		},
+	)
+
+	t.Run("stops at the first failure",
+		func(t *testing.T) {
+			var order []string
+			h := New(WithFailFast())
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				func(_ context.Context) error { order = append(order, "uno"); return errors.New("boom") },
+				Named("dos", func(_ context.Context) error { order = append(order, "dos"); return nil }),
+			)
+
+			require.Error(t, err)
+			assert.Equal(t, []string{"uno"}, order)
+			assert.True(t, results[1].Skipped)
+			assert.NoError(t, results[1].Err)
+		},
+	)
+
+	t.Run("continues when failing task is marked as continue on error",
+		func(t *testing.T) {
+			var order []string
+			h := New(WithFailFast())
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				ContinueOnError(func(_ context.Context) error { order = append(order, "uno"); return errors.New("boom") }),
+				func(_ context.Context) error { order = append(order, "dos"); return errors.New("bang") },
+				func(_ context.Context) error { order = append(order, "tres"); return nil },
+			)
+
+			require.Error(t, err)
+			assert.Equal(t, []string{"uno", "dos"}, order)
+			assert.EqualError(t, results[0].Err, "boom")
+			assert.EqualError(t, results[1].Err, "bang")
+			assert.True(t, results[2].Skipped)
+		},
+	)
+
+	t.Run("cancels running graph tasks",
+		func(t *testing.T) {
+			h := New(WithFailFast())
+
+			err := h.ExecuteGraph(t.Context(),
+				TaskDef{Name: "slow", Fn: func(ctx context.Context) error {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(5 * time.Second):
+						return nil
+					}
+				}},
+				TaskDef{Name: "broken", Fn: func(_ context.Context) error { return errors.New("boom") }},
+			)
+
+			require.Error(t, err)
+		},
+	)
+}
+
+func TestHarnessTags(t *testing.T) {
+	tasks := func(order *[]string) []Task {
+		return []Task{
+			Named("fmt", func(_ context.Context) error { *order = append(*order, "fmt"); return nil }),
+			Named("test", Tagged(func(_ context.Context) error { *order = append(*order, "test"); return nil }, "slow")),
+			Named("e2e", Tagged(func(_ context.Context) error { *order = append(*order, "e2e"); return nil }, "slow", "network")),
+		}
+	}
+
+	t.Run("runs all tasks without filters",
+		func(t *testing.T) {
+			var order []string
+			require.NoError(t, New().Execute(t.Context(), tasks(&order)...))
+			assert.Equal(t, []string{"fmt", "test", "e2e"}, order)
+		},
+	)
+
+	t.Run("skips excluded tags",
+		func(t *testing.T) {
+			var order []string
+			h := New(WithExcludeTags("network"))
+
+			results, err := h.ExecuteWithResults(t.Context(), tasks(&order)...)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"fmt", "test"}, order)
+			assert.False(t, results[1].Skipped)
+			assert.True(t, results[2].Skipped)
+			assert.Equal(t, "e2e", results[2].Name)
+		},
+	)
+
+	t.Run("only runs tagged tasks with included tags",
+		func(t *testing.T) {
+			var order []string
+			h := New(WithIncludeTags("network"))
+
+			require.NoError(t, h.Execute(t.Context(), tasks(&order)...))
+			assert.Equal(t, []string{"fmt", "e2e"}, order)
+		},
+	)
+
+	t.Run("skips tags from environment",
+		func(t *testing.T) {
+			t.Setenv("HARNESS_SKIP_TAGS", "foo, slow")
+
+			var order []string
+			require.NoError(t, New().Execute(t.Context(), tasks(&order)...))
+			assert.Equal(t, []string{"fmt"}, order)
+		},
+	)
+
+	t.Run("uses tags of registered tasks",
+		func(t *testing.T) {
+			ran := false
+			h := New(WithExcludeTags("slow"))
+			task := h.Register("test", func(_ context.Context) error { ran = true; return nil }, WithTaskTags("slow"))
+
+			require.NoError(t, h.Execute(t.Context(), task))
+			assert.False(t, ran)
+		},
+	)
+}
+
+func TestHarnessSummary(t *testing.T) {
+	t.Run("prints table with every task",
+		func(t *testing.T) {
+			buf := captureoutput(t)
+			h := New()
+
+			err := h.Execute(t.Context(),
+				Named("lint", func(_ context.Context) error { return nil }),
+				Named("integration", func(_ context.Context) error { return errors.New("boom") }),
+			)
+
+			require.Error(t, err)
+			assert.Regexp(t, `lint +\S+ passed`, buf.String())
+			assert.Regexp(t, `integration +\S+ failed`, buf.String())
+		},
+	)
+
+	t.Run("writes table to summary file",
+		func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "summary.txt")
+			h := New(WithSummaryFile(path))
+
+			err := h.Execute(t.Context(),
+				Named("lint", func(_ context.Context) error { return nil }),
+				Named("e2e", Tagged(func(_ context.Context) error { return nil }, "slow")),
+			)
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Regexp(t, `^   lint  \S+ passed  \S+\n   e2e   \S+ passed  \S+\n$`, string(content))
+		},
+	)
 }
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFiles are the files the configuration is read from by default, in order of preference.
+var ConfigFiles = []string{"harness.yaml", "harness.yml"}
+
+// Config holds the defaults configured for a repository in its harness.yaml file.
+type Config struct {
+	// BinDir is the directory binaries are provisioned into.
+	BinDir string `yaml:"bindir"`
+	// Versions of the binaries to provision by command name; they take precedence over
+	// the versions specified in code.
+	Versions map[string]string `yaml:"versions"`
+	// Env variables set for every command.
+	Env map[string]string `yaml:"env"`
+	// Aliases of registered tasks, mapping each alias to the task name.
+	Aliases map[string]string `yaml:"aliases"`
+
+	Output struct {
+		Verbosity string `yaml:"verbosity"`
+		Plain     bool   `yaml:"plain"`
+		Color     *bool  `yaml:"color"`
+	} `yaml:"output"`
+
+	CI struct {
+		GitHubActions     *bool `yaml:"github_actions"`
+		GitHubStepSummary *bool `yaml:"github_step_summary"`
+		GitLabCI          *bool `yaml:"gitlab_ci"`
+	} `yaml:"ci"`
+}
+
+var (
+	configmtx    sync.Mutex
+	config       *Config
+	configloaded bool
+)
+
+// CurrentConfig returns the configuration, reading it from the first of the [ConfigFiles]
+// found in the current directory the first time it's needed. If there's no configuration
+// file, an empty configuration is returned; invalid files are reported and ignored.
+func CurrentConfig() *Config {
+	configmtx.Lock()
+	defer configmtx.Unlock()
+
+	if configloaded {
+		return config
+	}
+	configloaded = true
+	config = &Config{}
+
+	for _, path := range ConfigFiles {
+		loaded, err := ReadConfig(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			LogError(err.Error())
+			break
+		}
+
+		config = loaded
+		break
+	}
+
+	return config
+}
+
+// SetConfig replaces the current configuration.
+func SetConfig(c *Config) {
+	configmtx.Lock()
+	defer configmtx.Unlock()
+
+	config, configloaded = c, true
+}
+
+// ReadConfig reads the configuration from a yaml file.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if c.Output.Verbosity != "" {
+		if _, err := ParseVerbosity(c.Output.Verbosity); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	return &c, nil
+}
+
+// ApplyOutput applies the output preferences of the configuration.
+func (c *Config) ApplyOutput() {
+	if c.Output.Verbosity != "" && os.Getenv(VerbosityEnv) == "" {
+		verbosity, _ := ParseVerbosity(c.Output.Verbosity)
+		SetVerbosity(verbosity)
+	}
+	if c.Output.Plain {
+		SetPlainOutput()
+	}
+	if c.Output.Color != nil && !*c.Output.Color {
+		DisableColor()
+	}
+}
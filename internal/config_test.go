@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	t.Run("parses all settings",
+		func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "harness.yaml")
+			content := `
+bindir: tools/bin
+versions:
+  golangci-lint: v1.63.3
+env:
+  CGO_ENABLED: "0"
+aliases:
+  l: lint
+output:
+  verbosity: normal
+  color: false
+ci:
+  github_actions: true
+`
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+			config, err := ReadConfig(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, "tools/bin", config.BinDir)
+			assert.Equal(t, map[string]string{"golangci-lint": "v1.63.3"}, config.Versions)
+			assert.Equal(t, map[string]string{"CGO_ENABLED": "0"}, config.Env)
+			assert.Equal(t, map[string]string{"l": "lint"}, config.Aliases)
+			assert.Equal(t, "normal", config.Output.Verbosity)
+			require.NotNil(t, config.Output.Color)
+			assert.False(t, *config.Output.Color)
+			require.NotNil(t, config.CI.GitHubActions)
+			assert.True(t, *config.CI.GitHubActions)
+			assert.Nil(t, config.CI.GitLabCI)
+		},
+	)
+
+	t.Run("returns error for invalid files",
+		func(t *testing.T) {
+			dir := t.TempDir()
+
+			_, err := ReadConfig(filepath.Join(dir, "missing.yaml"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			invalid := filepath.Join(dir, "invalid.yaml")
+			require.NoError(t, os.WriteFile(invalid, []byte("versions: [unclosed"), 0o644))
+			_, err = ReadConfig(invalid)
+			require.Error(t, err)
+
+			verbosity := filepath.Join(dir, "verbosity.yaml")
+			require.NoError(t, os.WriteFile(verbosity, []byte("output:\n  verbosity: loud\n"), 0o644))
+			_, err = ReadConfig(verbosity)
+			require.ErrorContains(t, err, "invalid verbosity")
+		},
+	)
+}
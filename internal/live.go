@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// LiveStatus displays which task is running and for how long.
+//
+// On terminals, the status is rendered in place with a spinner on the last line of the output,
+// which is cleared before any other output is written and drawn again afterwards; for this to
+// work, harness logs and command output are routed through it while it's active.
+// On other writers, like ci logs, it degrades to plain log lines when tasks start and finish.
+type LiveStatus struct {
+	mtx sync.Mutex
+
+	out   io.Writer
+	live  bool
+	total int
+
+	current int
+	started time.Time
+	frame   int
+	drawn   bool
+	partial bool
+	paused  int
+
+	restore func()
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// StartLiveStatus starts displaying the status of the execution of the specified amount of tasks.
+// [LiveStatus.Stop] must be called once all tasks finished.
+func StartLiveStatus(ctx context.Context, total int) *LiveStatus {
+	status := &LiveStatus{
+		out:   Output,
+		live:  IsTerminalWriter(Output),
+		total: total,
+	}
+
+	if !status.live {
+		return status
+	}
+
+	output, stdout, stderr := Output, Stdout, Stderr
+	Output, Stdout, Stderr = status, status, status
+	status.restore = func() {
+		Output, Stdout, Stderr = output, stdout, stderr
+	}
+
+	status.done = make(chan struct{})
+	status.stopped = make(chan struct{})
+
+	go status.render(ctx, 100*time.Millisecond)
+
+	return status
+}
+
+// TaskStarted signals that the task with the specified index started running.
+func (s *LiveStatus) TaskStarted(index int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.current = index + 1
+	s.started = time.Now()
+
+	if !s.live {
+		LogStep(fmt.Sprintf("running task %d of %d", s.current, s.total))
+	}
+}
+
+// TaskFinished signals that the running task finished, with what error.
+func (s *LiveStatus) TaskFinished(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.live {
+		elapsed := time.Since(s.started).Round(time.Millisecond)
+		LogStatus(fmt.Sprintf("task %d of %d finished after %s", s.current, s.total, elapsed), err)
+	}
+
+	s.current = 0
+}
+
+// PauseLiveStatus stops drawing the live status, if it's active, until the returned function
+// is called; for commands writing to the terminal directly, which the status would overwrite.
+func PauseLiveStatus() (resume func()) {
+	s, ok := Output.(*LiveStatus)
+	if !ok {
+		return func() {}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.clear()
+	s.paused++
+
+	return func() {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		s.paused--
+	}
+}
+
+// Stop displaying the status, clearing it from the output.
+func (s *LiveStatus) Stop() {
+	if !s.live {
+		return
+	}
+
+	close(s.done)
+	<-s.stopped
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.clear()
+	s.restore()
+}
+
+// Write the data to the underlying output, making sure it doesn't overlap with the status line.
+func (s *LiveStatus) Write(data []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.clear()
+
+	n, err := s.out.Write(data)
+
+	// escape sequences like progress reports don't move the cursor
+	if len(data) > 0 && !bytes.HasPrefix(data, []byte("\x1b]")) {
+		s.partial = !bytes.HasSuffix(data, []byte("\n"))
+	}
+
+	return n, err
+}
+
+// IsTTY reports that the status is rendered on a terminal, so writers wrapped by it
+// keep being treated as terminals.
+func (s *LiveStatus) IsTTY() bool {
+	return s.live
+}
+
+func (s *LiveStatus) render(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(s.stopped)
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mtx.Lock()
+			s.draw()
+			s.mtx.Unlock()
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// draw renders the status line; must be called holding the lock.
+func (s *LiveStatus) draw() {
+	// never draw in the middle of a line that is still being written
+	if s.current == 0 || s.partial || s.paused > 0 {
+		return
+	}
+
+	spinner := Symbols.Spinner[s.frame%len(Symbols.Spinner)]
+	s.frame++
+
+	elapsed := time.Since(s.started).Round(100 * time.Millisecond)
+	line := fmt.Sprintf(" %s running task %d of %d %s", spinner, s.current, s.total, elapsed)
+
+	s.clear()
+	color.New(color.FgHiBlack).Fprint(s.out, line) //nolint:errcheck
+	s.drawn = true
+}
+
+// clear removes the status line if it's drawn; must be called holding the lock.
+func (s *LiveStatus) clear() {
+	if !s.drawn {
+		return
+	}
+
+	fmt.Fprint(s.out, "\r\x1b[K") //nolint:errcheck
+	s.drawn = false
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveStatus(t *testing.T) {
+	nocolor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = nocolor })
+
+	t.Run("renders status in place on terminals",
+		func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				buf := installOutputCapture(t)
+
+				status := StartLiveStatus(t.Context(), 2)
+				assert.Same(t, status, Output)
+				assert.Same(t, status, Stdout)
+
+				status.TaskStarted(0)
+				advance(t, 100*time.Millisecond)
+				assert.Equal(t, " ⠋ running task 1 of 2 100ms", buf.String())
+
+				buf.Reset()
+				fmt.Fprint(Stdout, "output\n")
+				advance(t, 100*time.Millisecond)
+				assert.Equal(t, "\r\x1b[Koutput\n ⠙ running task 1 of 2 200ms", buf.String())
+
+				buf.Reset()
+				status.TaskFinished(nil)
+				status.Stop()
+				assert.Equal(t, "\r\x1b[K", buf.String())
+				assert.Same(t, buf, Output)
+			})
+		},
+	)
+
+	t.Run("doesn't draw over partial lines",
+		func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				buf := installOutputCapture(t)
+
+				status := StartLiveStatus(t.Context(), 1)
+				status.TaskStarted(0)
+
+				fmt.Fprint(Stdout, "partial")
+				advance(t, 300*time.Millisecond)
+				assert.Equal(t, "partial", buf.String())
+
+				fmt.Fprint(Stdout, " line\n")
+				advance(t, 100*time.Millisecond)
+				assert.Contains(t, buf.String(), "partial line\n ")
+
+				status.Stop()
+			})
+		},
+	)
+
+	t.Run("doesn't draw while paused",
+		func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				buf := installOutputCapture(t)
+
+				status := StartLiveStatus(t.Context(), 1)
+				status.TaskStarted(0)
+				advance(t, 100*time.Millisecond)
+
+				buf.Reset()
+				resume := PauseLiveStatus()
+				advance(t, 300*time.Millisecond)
+				assert.Equal(t, "\r\x1b[K", buf.String())
+
+				resume()
+				advance(t, 100*time.Millisecond)
+				assert.Contains(t, buf.String(), "running task 1 of 1")
+
+				status.Stop()
+			})
+		},
+	)
+
+	t.Run("isn't rendered on plain output",
+		func(t *testing.T) {
+			buf := installOutputCapture(t)
+
+			nocolor := color.NoColor
+			t.Cleanup(func() {
+				plain.Store(false)
+				color.NoColor = nocolor
+			})
+
+			SetPlainOutput()
+			assert.True(t, color.NoColor)
+			assert.False(t, IsTerminalWriter(buf))
+
+			status := StartLiveStatus(t.Context(), 1)
+			assert.Same(t, buf, Output)
+
+			status.TaskStarted(0)
+			status.TaskFinished(nil)
+			status.Stop()
+
+			assert.Contains(t, buf.String(), "running task 1 of 1")
+		},
+	)
+
+	t.Run("degrades to plain logs",
+		func(t *testing.T) {
+			var buf bytes.Buffer
+			prev := Output
+			Output = &buf
+			t.Cleanup(func() { Output = prev })
+
+			status := StartLiveStatus(t.Context(), 2)
+			assert.Same(t, &buf, Output)
+
+			status.TaskStarted(0)
+			status.TaskFinished(nil)
+			status.TaskStarted(1)
+			status.TaskFinished(errors.New("boom"))
+			status.Stop()
+
+			assert.Contains(t, buf.String(), "running task 1 of 2")
+			assert.Contains(t, buf.String(), "task 1 of 2 finished after")
+			assert.Contains(t, buf.String(), "running task 2 of 2")
+			assert.Contains(t, buf.String(), "task 2 of 2 finished after")
+		},
+	)
+}
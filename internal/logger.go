@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Attribute keys attached to every log record, describing how the pretty handler renders it.
+const (
+	// KindKey holds the kind of log line, e.g. "command", "step" or "detail".
+	KindKey = "kind"
+	// ColorKey holds the [color.Attribute] of kind "message" records.
+	ColorKey = "color"
+	// ErrorKey holds the error of kind "status" records.
+	ErrorKey = "error"
+)
+
+var (
+	logmtx   sync.RWMutex
+	handler  slog.Handler = &PrettyHandler{}
+	loglevel              = func() *slog.LevelVar {
+		level := new(slog.LevelVar)
+		level.Set(slog.LevelDebug)
+		return level
+	}()
+)
+
+// SetLogHandler sets the handler that receives all harness and binary logs.
+func SetLogHandler(h slog.Handler) {
+	logmtx.Lock()
+	defer logmtx.Unlock()
+
+	handler = h
+}
+
+// SetLogLevel sets the minimum level of the logs that are emitted.
+// Details are logged at debug level, errors at error level and everything else at info level.
+func SetLogLevel(level slog.Level) {
+	loglevel.Set(level)
+}
+
+// DisableColor disables colored output.
+func DisableColor() {
+	color.NoColor = true
+}
+
+var plain atomic.Bool
+
+// SetPlainOutput disables colors along with everything that relies on terminal escape
+// sequences, like the live status, progress reports and progress bars.
+// Commands are asked to do the same via the NO_COLOR env variable.
+func SetPlainOutput() {
+	plain.Store(true)
+	DisableColor()
+}
+
+// IsPlainOutput reports if output is plain, see [SetPlainOutput].
+func IsPlainOutput() bool {
+	return plain.Load()
+}
+
+// emit sends a log record of the specified kind to the current handler.
+func emit(level slog.Level, kind, msg string, attrs ...slog.Attr) {
+	if level < loglevel.Level() {
+		return
+	}
+
+	logmtx.RLock()
+	h := handler
+	logmtx.RUnlock()
+
+	ctx := context.Background()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+
+	record := slog.NewRecord(time.Now(), level, Mask(msg), 0)
+	record.AddAttrs(slog.String(KindKey, kind))
+	record.AddAttrs(attrs...)
+
+	_ = h.Handle(ctx, record)
+}
+
+// layout renders spacing elements like blank lines and separators; these only make
+// sense for the pretty handler, so other handlers never receive them.
+func layout(render func(w io.Writer)) {
+	logmtx.RLock()
+	_, pretty := handler.(*PrettyHandler)
+	logmtx.RUnlock()
+
+	if pretty {
+		render(Output)
+	}
+}
+
+// PrettyHandler is the default log handler, rendering records as colored lines with
+// status symbols. Records are written to [Output].
+type PrettyHandler struct{}
+
+func (h *PrettyHandler) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, record slog.Record) error {
+	var (
+		kind string
+		attr = color.Reset
+		err  error
+	)
+
+	record.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case KindKey:
+			kind = a.Value.String()
+		case ColorKey:
+			attr = color.Attribute(a.Value.Int64())
+		case ErrorKey:
+			err, _ = a.Value.Any().(error)
+		}
+		return true
+	})
+
+	text := record.Message
+
+	var werr error
+	switch kind {
+	case "command":
+		_, werr = fmt.Fprintln(Output, color.MagentaString(" %s", Symbols.Command), color.New(color.Bold).Sprint(text))
+	case "step":
+		_, werr = fmt.Fprintln(Output, color.BlueString(" %s", Symbols.Dot), color.New(color.FgHiBlack).Sprint(text))
+	case "detail":
+		_, werr = fmt.Fprintln(Output, color.New(color.FgHiBlack).Sprintf("   %s", Symbols.Detail), color.New(color.FgHiBlack).Sprint(text))
+	case "success":
+		_, werr = color.New(color.FgGreen).Fprintf(Output, " %s %s\n", Symbols.Success, text)
+	case "error":
+		_, werr = color.New(color.FgRed).Fprintf(Output, " %s %s\n", Symbols.Error, text)
+	case "erroritem":
+		_, werr = color.New(color.FgRed).Fprintf(Output, "   %s %s\n", Symbols.Dot, text)
+	case "status":
+		if err != nil {
+			_, werr = color.New(color.FgRed).Fprintf(Output, "     %s %s\n", Symbols.Error, text)
+		} else {
+			_, werr = color.New(color.FgGreen).Fprintf(Output, "     %s %s\n", Symbols.Success, text)
+		}
+	default:
+		_, werr = color.New(attr).Fprintln(Output, text)
+	}
+
+	return werr
+}
+
+// WithAttrs returns the handler unchanged; the pretty output has no room for extra attributes.
+func (h *PrettyHandler) WithAttrs(_ []slog.Attr) slog.Handler {
+	return h
+}
+
+// WithGroup returns the handler unchanged; the pretty output has no room for groups.
+func (h *PrettyHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
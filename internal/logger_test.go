@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	nocolor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = nocolor })
+
+	capture := func(t *testing.T) *bytes.Buffer {
+		var buf bytes.Buffer
+		prev := Output
+		Output = &buf
+		t.Cleanup(func() { Output = prev })
+		return &buf
+	}
+
+	restore := func(t *testing.T) {
+		t.Cleanup(func() {
+			SetLogHandler(&PrettyHandler{})
+			SetLogLevel(slog.LevelDebug)
+		})
+	}
+
+	t.Run("pretty handler renders lines with symbols",
+		func(t *testing.T) {
+			buf := capture(t)
+
+			LogCommand("lint")
+			LogDetail("from path bin/lint")
+			LogStatus("1s", errors.New("boom"))
+			LogBlank()
+
+			assert.Equal(t,
+				" "+Symbols.Command+" lint\n"+
+					"   "+Symbols.Detail+" from path bin/lint\n"+
+					"     "+Symbols.Error+" 1s\n"+
+					"\n",
+				buf.String(),
+			)
+		},
+	)
+
+	t.Run("custom handler receives structured records",
+		func(t *testing.T) {
+			restore(t)
+			buf := capture(t)
+
+			var logs bytes.Buffer
+			SetLogHandler(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			LogStep("installing tool")
+			LogBlank()
+			LogSeparator()
+			LogError("failed")
+
+			assert.Empty(t, buf.String())
+
+			lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+			require.Len(t, lines, 2)
+			assert.Contains(t, string(lines[0]), `"level":"INFO","msg":"installing tool","kind":"step"`)
+			assert.Contains(t, string(lines[1]), `"level":"ERROR","msg":"failed","kind":"error"`)
+		},
+	)
+
+	t.Run("level filters out lower levels",
+		func(t *testing.T) {
+			restore(t)
+			buf := capture(t)
+
+			SetLogLevel(slog.LevelInfo)
+
+			LogDetail("hidden")
+			LogStep("visible")
+
+			assert.NotContains(t, buf.String(), "hidden")
+			assert.Contains(t, buf.String(), "visible")
+		},
+	)
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics collects timings of tasks, executions and binary provisioning during the life
+// of the process, to be exported using the prometheus text format.
+var Metrics = NewMetricsRegistry()
+
+// MetricsRegistry holds metric samples keyed by metric name and label values.
+type MetricsRegistry struct {
+	mtx     sync.Mutex
+	samples map[string]map[string]float64
+}
+
+type metricdesc struct {
+	name  string
+	kind  string
+	help  string
+	label string
+}
+
+var metricdescs = []metricdesc{
+	{"harness_execution_duration_seconds", "gauge", "Duration of the last execution.", ""},
+	{"harness_task_duration_seconds", "gauge", "Duration of the last run of each task.", "task"},
+	{"harness_task_success_total", "counter", "Amount of successful runs of each task.", "task"},
+	{"harness_task_failure_total", "counter", "Amount of failed runs of each task.", "task"},
+	{"harness_binary_provision_duration_seconds", "gauge", "Duration of the last provisioning of each binary.", "binary"},
+	{"harness_binary_provision_failure_total", "counter", "Amount of failed provisionings of each binary.", "binary"},
+}
+
+// NewMetricsRegistry returns an empty registry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{samples: make(map[string]map[string]float64)}
+}
+
+// ObserveExecution records the duration of an execution.
+func (m *MetricsRegistry) ObserveExecution(duration time.Duration) {
+	m.set("harness_execution_duration_seconds", "", duration.Seconds())
+}
+
+// ObserveTask records the duration and outcome of a task run.
+func (m *MetricsRegistry) ObserveTask(task string, duration time.Duration, err error) {
+	m.set("harness_task_duration_seconds", task, duration.Seconds())
+	if err != nil {
+		m.add("harness_task_failure_total", task, 1)
+		return
+	}
+	m.add("harness_task_success_total", task, 1)
+}
+
+// ObserveProvision records the duration and outcome of the provisioning of a binary.
+func (m *MetricsRegistry) ObserveProvision(binary string, duration time.Duration, err error) {
+	m.set("harness_binary_provision_duration_seconds", binary, duration.Seconds())
+	if err != nil {
+		m.add("harness_binary_provision_failure_total", binary, 1)
+	}
+}
+
+// WriteText writes all samples using the prometheus text exposition format.
+func (m *MetricsRegistry) WriteText(w io.Writer) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var sb strings.Builder
+
+	for _, desc := range metricdescs {
+		samples, ok := m.samples[desc.name]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "# HELP %s %s\n", desc.name, desc.help)
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", desc.name, desc.kind)
+
+		labels := make([]string, 0, len(samples))
+		for label := range samples {
+			labels = append(labels, label)
+		}
+		slices.Sort(labels)
+
+		for _, label := range labels {
+			value := strconv.FormatFloat(samples[label], 'g', -1, 64)
+			if desc.label == "" {
+				fmt.Fprintf(&sb, "%s %s\n", desc.name, value)
+				continue
+			}
+			fmt.Fprintf(&sb, "%s{%s=\"%s\"} %s\n", desc.name, desc.label, escapelabel(label), value)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (m *MetricsRegistry) set(name, label string, value float64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.series(name)[label] = value
+}
+
+func (m *MetricsRegistry) add(name, label string, value float64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.series(name)[label] += value
+}
+
+// series returns the samples of a metric; must be called holding the lock.
+func (m *MetricsRegistry) series(name string) map[string]float64 {
+	samples, ok := m.samples[name]
+	if !ok {
+		samples = make(map[string]float64)
+		m.samples[name] = samples
+	}
+	return samples
+}
+
+// escapelabel escapes a label value as required by the text exposition format.
+func escapelabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	t.Run("writes samples in text format",
+		func(t *testing.T) {
+			metrics := NewMetricsRegistry()
+
+			metrics.ObserveExecution(3 * time.Second)
+			metrics.ObserveTask("lint", 1500*time.Millisecond, nil)
+			metrics.ObserveTask("test", 2*time.Second, errors.New("boom"))
+			metrics.ObserveTask("lint", time.Second, nil)
+			metrics.ObserveProvision("golangci-lint", 250*time.Millisecond, nil)
+
+			var sb strings.Builder
+			require.NoError(t, metrics.WriteText(&sb))
+
+			assert.Equal(t,
+				"# HELP harness_execution_duration_seconds Duration of the last execution.\n"+
+					"# TYPE harness_execution_duration_seconds gauge\n"+
+					"harness_execution_duration_seconds 3\n"+
+					"# HELP harness_task_duration_seconds Duration of the last run of each task.\n"+
+					"# TYPE harness_task_duration_seconds gauge\n"+
+					"harness_task_duration_seconds{task=\"lint\"} 1\n"+
+					"harness_task_duration_seconds{task=\"test\"} 2\n"+
+					"# HELP harness_task_success_total Amount of successful runs of each task.\n"+
+					"# TYPE harness_task_success_total counter\n"+
+					"harness_task_success_total{task=\"lint\"} 2\n"+
+					"# HELP harness_task_failure_total Amount of failed runs of each task.\n"+
+					"# TYPE harness_task_failure_total counter\n"+
+					"harness_task_failure_total{task=\"test\"} 1\n"+
+					"# HELP harness_binary_provision_duration_seconds Duration of the last provisioning of each binary.\n"+
+					"# TYPE harness_binary_provision_duration_seconds gauge\n"+
+					"harness_binary_provision_duration_seconds{binary=\"golangci-lint\"} 0.25\n",
+				sb.String(),
+			)
+		},
+	)
+
+	t.Run("escapes label values",
+		func(t *testing.T) {
+			metrics := NewMetricsRegistry()
+			metrics.ObserveTask("say \"hi\"\n", time.Second, nil)
+
+			var sb strings.Builder
+			require.NoError(t, metrics.WriteText(&sb))
+
+			assert.Contains(t, sb.String(), `harness_task_duration_seconds{task="say \"hi\"\n"} 1`)
+		},
+	)
+}
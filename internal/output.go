@@ -2,7 +2,9 @@ package internal
 
 import (
 	"fmt"
+	"hash/fnv"
 	"io"
+	"log/slog"
 	"os"
 
 	"github.com/fatih/color"
@@ -11,17 +13,49 @@ import (
 
 var Output io.Writer = os.Stdout
 
+// Stdout and Stderr are the writers commands use by default for their output.
+var (
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
 func SetOutput(w io.Writer) {
 	Output = w
 }
 
+var prefixcolors = []color.Attribute{
+	color.FgCyan, color.FgMagenta, color.FgYellow, color.FgBlue, color.FgGreen,
+	color.FgHiCyan, color.FgHiMagenta, color.FgHiYellow, color.FgHiBlue, color.FgHiGreen,
+}
+
+// LinePrefix renders the prefix identifying the lines written by a command, padding the
+// label to the specified width so the output of multiple commands lines up.
+// The color is derived from the label, so the same label always gets the same color.
+func LinePrefix(label string, width int) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(label))
+	attr := prefixcolors[hash.Sum32()%uint32(len(prefixcolors))]
+
+	return color.New(attr).Sprintf("%-*s |", width, label) + " "
+}
+
+// IsTerminalWriter reports if w writes to a terminal capable of rendering escape sequences;
+// dumb terminals and plain output are not.
 func IsTerminalWriter(w io.Writer) bool {
+	if IsPlainOutput() {
+		return false
+	}
+
 	// IsTTY is implemented by the testing syncbuffer.
 	type tty interface{ IsTTY() bool }
 	if t, ok := w.(tty); ok {
 		return t.IsTTY()
 	}
 
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
 	file, ok := w.(*os.File)
 	if !ok {
 		return false
@@ -32,69 +66,71 @@ func IsTerminalWriter(w io.Writer) bool {
 
 // LogBlank writes an empty line to the output.
 func LogBlank() {
-	fmt.Fprintln(Output) //nolint:errcheck
+	layout(func(w io.Writer) {
+		fmt.Fprintln(w) //nolint:errcheck
+	})
 }
 
 // LogSeparator writes a dim horizontal rule.
 func LogSeparator() {
-	color.New(color.FgHiBlack).Fprintf(Output, "------------------------\n\n") //nolint:errcheck
+	layout(func(w io.Writer) {
+		color.New(color.FgHiBlack).Fprintf(w, "------------------------\n\n") //nolint:errcheck
+	})
 }
 
 // LogCommand writes a top-level command heading using the command symbol.
 // This is the most prominent log level, used for task names.
 func LogCommand(text string) {
-	fmt.Fprintln( //nolint:errcheck
-		Output,
-		color.MagentaString(" %s", Symbols.Command),
-		color.New(color.Bold).Sprint(text),
-	)
+	emit(slog.LevelInfo, "command", text)
 }
 
 // LogStep writes a secondary step line using the dot symbol.
 // Used for provisioning and sub-task progress.
 func LogStep(text string) {
-	fmt.Fprintln( //nolint:errcheck
-		Output,
-		color.BlueString(" %s", Symbols.Dot),
-		color.New(color.FgHiBlack).Sprint(text),
-	)
+	emit(slog.LevelInfo, "step", text)
 }
 
 // LogDetail writes an indented detail line using the detail symbol.
 func LogDetail(text string) {
-	fmt.Fprintln( //nolint:errcheck
-		Output,
-		color.New(color.FgHiBlack).Sprintf("   %s", Symbols.Detail),
-		color.New(color.FgHiBlack).Sprint(text),
-	)
+	emit(slog.LevelDebug, "detail", text)
+}
+
+// LogTrace writes a detail line only shown at [VerbosityDebug].
+func LogTrace(text string) {
+	emit(LevelTrace, "detail", text)
 }
 
 // LogSuccess writes a green success line with the success symbol.
 func LogSuccess(text string) {
-	color.New(color.FgGreen).Fprintf(Output, " %s %s\n", Symbols.Success, text) //nolint:errcheck
+	emit(slog.LevelInfo, "success", text)
 }
 
 // LogError writes a red error line with the error symbol.
 func LogError(text string) {
-	color.New(color.FgRed).Fprintf(Output, " %s %s\n", Symbols.Error, text) //nolint:errcheck
+	emit(slog.LevelError, "error", text)
 }
 
 // LogErrorItem writes an indented red error bullet using the dot symbol.
 func LogErrorItem(text string) {
-	color.New(color.FgRed).Fprintf(Output, "   %s %s\n", Symbols.Dot, text) //nolint:errcheck
+	emit(slog.LevelError, "erroritem", text)
 }
 
 // LogStatus writes an indented status indicator based on whether err is nil.
 func LogStatus(text string, err error) {
 	if err != nil {
-		color.New(color.FgRed).Fprintf(Output, "     %s %s\n", Symbols.Error, text) //nolint:errcheck
+		emit(slog.LevelError, "status", text, slog.Any(ErrorKey, MaskError(err)))
 		return
 	}
 
-	color.New(color.FgGreen).Fprintf(Output, "     %s %s\n", Symbols.Success, text) //nolint:errcheck
+	emit(slog.LevelInfo, "status", text)
 }
 
 // LogMessage writes a line in the specified color without any symbol prefix.
 func LogMessage(attr color.Attribute, text string) {
-	color.New(attr).Fprintln(Output, text) //nolint:errcheck
+	level := slog.LevelInfo
+	if attr == color.FgRed {
+		level = slog.LevelError
+	}
+
+	emit(level, "message", text, slog.Int(ColorKey, int(attr)))
 }
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SecretMask replaces secret values in logs and command output.
+const SecretMask = "****"
+
+var secrets struct {
+	mtx    sync.RWMutex
+	values []string
+}
+
+// RegisterSecrets adds values to the secrets masked in all logs and command output.
+func RegisterSecrets(values ...string) {
+	secrets.mtx.Lock()
+	defer secrets.mtx.Unlock()
+
+	for _, value := range values {
+		if value != "" && !slices.Contains(secrets.values, value) {
+			secrets.values = append(secrets.values, value)
+		}
+	}
+}
+
+// HasSecrets reports if there's anything to mask, along with the extra secrets.
+func HasSecrets(extra ...string) bool {
+	secrets.mtx.RLock()
+	defer secrets.mtx.RUnlock()
+
+	return len(secrets.values) > 0 || slices.ContainsFunc(extra, func(value string) bool { return value != "" })
+}
+
+// Mask replaces the registered secrets, along with the extra ones, in the text.
+func Mask(text string, extra ...string) string {
+	secrets.mtx.RLock()
+	values := append(slices.Clone(secrets.values), extra...)
+	secrets.mtx.RUnlock()
+
+	values = slices.DeleteFunc(values, func(value string) bool { return value == "" })
+	if len(values) == 0 {
+		return text
+	}
+
+	// longer secrets first, so secrets containing other secrets are fully masked
+	slices.SortFunc(values, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	pairs := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		pairs = append(pairs, value, SecretMask)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// MaskError returns an error whose message has the registered secrets masked, while still
+// unwrapping to the original error.
+func MaskError(err error) error {
+	if err == nil || !HasSecrets() {
+		return err
+	}
+	return maskederror{err}
+}
+
+type maskederror struct{ error }
+
+func (e maskederror) Error() string {
+	return Mask(e.error.Error())
+}
+
+func (e maskederror) Unwrap() error {
+	return e.error
+}
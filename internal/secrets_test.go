@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMask(t *testing.T) {
+	t.Run("masks extra secrets",
+		func(t *testing.T) {
+			assert.Equal(t, "login -p **** user", Mask("login -p hunter2 user", "hunter2"))
+			assert.Equal(t, "nothing to mask", Mask("nothing to mask", ""))
+		},
+	)
+
+	t.Run("masks longer secrets first",
+		func(t *testing.T) {
+			assert.Equal(t, "token=****", Mask("token=abc123", "abc", "abc123"))
+		},
+	)
+
+	t.Run("masks registered secrets everywhere",
+		func(t *testing.T) {
+			prev := secrets.values
+			t.Cleanup(func() { secrets.values = prev })
+
+			RegisterSecrets("s3cr3t", "")
+			assert.True(t, HasSecrets())
+			assert.Equal(t, "Bearer ****", Mask("Bearer s3cr3t"))
+
+			base := errors.New("auth with s3cr3t failed")
+			err := MaskError(base)
+			assert.Equal(t, "auth with **** failed", err.Error())
+			assert.ErrorIs(t, err, base)
+		},
+	)
+}
+
+func TestMaskWriter(t *testing.T) {
+	t.Run("masks secrets split across writes",
+		func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewMaskWriter(&out, "hunter2")
+
+			_, _ = w.Write([]byte("password is hun"))
+			assert.Empty(t, out.String())
+
+			_, _ = w.Write([]byte("ter2\nand again hunter2"))
+			assert.Equal(t, "password is ****\n", out.String())
+
+			require.NoError(t, w.Flush())
+			assert.Equal(t, "password is ****\nand again ****", out.String())
+		},
+	)
+}
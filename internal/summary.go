@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// SummaryRow is a row of the execution summary table.
+type SummaryRow struct {
+	Name     string
+	Status   string // passed, failed, skipped or cached
+	Duration time.Duration
+}
+
+// LogSummary writes an aligned table with the name, status and duration of every task.
+// Other handlers than the pretty one receive a record per row.
+func LogSummary(rows []SummaryRow) {
+	layout(func(w io.Writer) {
+		fmt.Fprint(w, RenderSummary(rows, true)) //nolint:errcheck
+	})
+
+	logmtx.RLock()
+	_, pretty := handler.(*PrettyHandler)
+	logmtx.RUnlock()
+
+	if pretty {
+		return
+	}
+
+	for _, row := range rows {
+		emit(slog.LevelInfo, "summary", row.Name,
+			slog.String("status", row.Status),
+			slog.Duration("duration", row.Duration),
+		)
+	}
+}
+
+// RenderSummary renders the summary table, optionally colored.
+func RenderSummary(rows []SummaryRow, colored bool) string {
+	var namewidth, statuswidth int
+	for _, row := range rows {
+		namewidth = max(namewidth, utf8.RuneCountInString(row.Name))
+		statuswidth = max(statuswidth, utf8.RuneCountInString(summarystatus(row.Status)))
+	}
+
+	var sb strings.Builder
+	for _, row := range rows {
+		status := summarystatus(row.Status)
+		padding := strings.Repeat(" ", statuswidth-utf8.RuneCountInString(status))
+
+		duration := "-"
+		if row.Status != "skipped" && row.Status != "cached" {
+			duration = row.Duration.Round(time.Millisecond).String()
+		}
+
+		if colored {
+			status = summarycolor(row.Status).Sprint(status)
+			duration = color.New(color.FgHiBlack).Sprint(duration)
+		}
+
+		fmt.Fprintf(&sb, "   %-*s  %s%s  %s\n", namewidth, row.Name, status, padding, duration)
+	}
+
+	return sb.String()
+}
+
+func summarystatus(status string) string {
+	switch status {
+	case "passed", "cached":
+		return Symbols.Success + " " + status
+	case "failed":
+		return Symbols.Error + " " + status
+	default:
+		return Symbols.Dot + " " + status
+	}
+}
+
+func summarycolor(status string) *color.Color {
+	switch status {
+	case "passed", "cached":
+		return color.New(color.FgGreen)
+	case "failed":
+		return color.New(color.FgRed)
+	default:
+		return color.New(color.FgHiBlack)
+	}
+}
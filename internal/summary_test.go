@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSummary(t *testing.T) {
+	rows := []SummaryRow{
+		{Name: "lint", Status: "passed", Duration: 1234 * time.Millisecond},
+		{Name: "integration", Status: "failed", Duration: 3 * time.Second},
+		{Name: "e2e", Status: "skipped"},
+		{Name: "fmt", Status: "cached", Duration: time.Millisecond},
+	}
+
+	assert.Equal(t,
+		"   lint         "+Symbols.Success+" passed   1.234s\n"+
+			"   integration  "+Symbols.Error+" failed   3s\n"+
+			"   e2e          "+Symbols.Dot+" skipped  -\n"+
+			"   fmt          "+Symbols.Success+" cached   -\n",
+		RenderSummary(rows, false),
+	)
+}
@@ -9,6 +9,7 @@ type StatusSymbols struct {
 	Command string // ⌘ or >
 	Dot     string // • or o
 	Detail  string // └ or --
+	Spinner []string
 }
 
 var Symbols = func() StatusSymbols {
@@ -25,6 +26,7 @@ func defaultSymbols() StatusSymbols {
 		Command: "⌘",
 		Dot:     "•",
 		Detail:  "└",
+		Spinner: []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
 	}
 }
 
@@ -35,5 +37,6 @@ func fallbackSymbols() StatusSymbols {
 		Command: ">",
 		Dot:     "o",
 		Detail:  "--",
+		Spinner: []string{"|", "/", "-", "\\"},
 	}
 }
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Verbosity controls how much is shown while tasks run.
+type Verbosity int
+
+const (
+	// VerbosityQuiet shows errors only; command output is hidden, except the end of the
+	// stderr of failing commands.
+	VerbosityQuiet Verbosity = iota
+	// VerbosityNormal shows command lines, their output and outcome.
+	VerbosityNormal
+	// VerbosityVerbose shows per-step details too; it's the default.
+	VerbosityVerbose
+	// VerbosityDebug shows everything, including how commands are run.
+	VerbosityDebug
+)
+
+// LevelTrace is the log level of the debugging details only shown at [VerbosityDebug].
+const LevelTrace = slog.LevelDebug - 4
+
+// VerbosityEnv is the env variable the verbosity is read from, e.g. HARNESS_VERBOSITY=quiet.
+const VerbosityEnv = "HARNESS_VERBOSITY"
+
+func init() {
+	if value := os.Getenv(VerbosityEnv); value != "" {
+		if verbosity, err := ParseVerbosity(value); err == nil {
+			SetVerbosity(verbosity)
+		}
+	}
+}
+
+// ParseVerbosity parses the name of a verbosity: quiet, normal, verbose or debug.
+func ParseVerbosity(value string) (Verbosity, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "quiet":
+		return VerbosityQuiet, nil
+	case "normal":
+		return VerbosityNormal, nil
+	case "verbose":
+		return VerbosityVerbose, nil
+	case "debug":
+		return VerbosityDebug, nil
+	}
+	return 0, fmt.Errorf("invalid verbosity %q; expected quiet, normal, verbose or debug", value)
+}
+
+func (v Verbosity) String() string {
+	switch v {
+	case VerbosityQuiet:
+		return "quiet"
+	case VerbosityNormal:
+		return "normal"
+	case VerbosityVerbose:
+		return "verbose"
+	case VerbosityDebug:
+		return "debug"
+	}
+	return fmt.Sprintf("verbosity(%d)", int(v))
+}
+
+// SetVerbosity sets the log level matching the verbosity.
+func SetVerbosity(verbosity Verbosity) {
+	switch {
+	case verbosity <= VerbosityQuiet:
+		SetLogLevel(slog.LevelError)
+	case verbosity == VerbosityNormal:
+		SetLogLevel(slog.LevelInfo)
+	case verbosity == VerbosityVerbose:
+		SetLogLevel(slog.LevelDebug)
+	default:
+		SetLogLevel(LevelTrace)
+	}
+}
+
+// CurrentVerbosity returns the verbosity matching the current log level.
+func CurrentVerbosity() Verbosity {
+	switch level := loglevel.Level(); {
+	case level > slog.LevelWarn:
+		return VerbosityQuiet
+	case level > slog.LevelDebug:
+		return VerbosityNormal
+	case level > LevelTrace:
+		return VerbosityVerbose
+	}
+	return VerbosityDebug
+}
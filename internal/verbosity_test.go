@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerbosity(t *testing.T) {
+	t.Run("parses verbosity names",
+		func(t *testing.T) {
+			for _, verbosity := range []Verbosity{VerbosityQuiet, VerbosityNormal, VerbosityVerbose, VerbosityDebug} {
+				parsed, err := ParseVerbosity(verbosity.String())
+				require.NoError(t, err)
+				assert.Equal(t, verbosity, parsed)
+			}
+
+			parsed, err := ParseVerbosity(" QUIET ")
+			require.NoError(t, err)
+			assert.Equal(t, VerbosityQuiet, parsed)
+
+			_, err = ParseVerbosity("loud")
+			require.Error(t, err)
+		},
+	)
+
+	t.Run("maps verbosity to log levels",
+		func(t *testing.T) {
+			prev := loglevel.Level()
+			t.Cleanup(func() { SetLogLevel(prev) })
+
+			for verbosity, level := range map[Verbosity]slog.Level{
+				VerbosityQuiet:   slog.LevelError,
+				VerbosityNormal:  slog.LevelInfo,
+				VerbosityVerbose: slog.LevelDebug,
+				VerbosityDebug:   LevelTrace,
+			} {
+				SetVerbosity(verbosity)
+				assert.Equal(t, level, loglevel.Level())
+				assert.Equal(t, verbosity, CurrentVerbosity())
+			}
+		},
+	)
+}
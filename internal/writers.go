@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// SyncWriter serializes writes to the underlying writer so it can be shared
+// between multiple goroutines.
+type SyncWriter struct {
+	mtx sync.Mutex
+	w   io.Writer
+}
+
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.w.Write(p)
+}
+
+// PrefixWriter writes every line it receives to the underlying writer preceded by
+// a prefix. Partial lines are buffered until a newline is received or the writer
+// is flushed, so each line reaches the underlying writer in a single write call.
+type PrefixWriter struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    bytes.Buffer
+}
+
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (p *PrefixWriter) Write(data []byte) (int, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.buf.Write(data)
+
+	for {
+		idx := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := p.buf.Next(idx + 1)
+		if _, err := p.w.Write(append(append([]byte{}, p.prefix...), line...)); err != nil {
+			return len(data), err
+		}
+	}
+
+	return len(data), nil
+}
+
+// Flush writes any buffered partial line, terminating it with a newline.
+func (p *PrefixWriter) Flush() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.buf.Len() == 0 {
+		return nil
+	}
+
+	line := append(append([]byte{}, p.prefix...), p.buf.Bytes()...)
+	p.buf.Reset()
+
+	_, err := p.w.Write(append(line, '\n'))
+	return err
+}
+
+// TailWriter keeps only the last bytes written to it, up to a limit, so the end of
+// a potentially long output can be kept around without holding all of it.
+type TailWriter struct {
+	mtx       sync.Mutex
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func NewTailWriter(limit int) *TailWriter {
+	return &TailWriter{limit: limit}
+}
+
+func (t *TailWriter) Write(data []byte) (int, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.buf = append(t.buf, data...)
+	if excess := len(t.buf) - t.limit; excess > 0 {
+		t.buf = append(t.buf[:0], t.buf[excess:]...)
+		t.truncated = true
+	}
+
+	return len(data), nil
+}
+
+// Reset discards everything written so far.
+func (t *TailWriter) Reset() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.buf = t.buf[:0]
+	t.truncated = false
+}
+
+// String returns the kept bytes; if older output was discarded, the first line is
+// dropped too as it's most likely incomplete.
+func (t *TailWriter) String() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	tail := t.buf
+	if t.truncated {
+		if idx := bytes.IndexByte(tail, '\n'); idx >= 0 {
+			tail = tail[idx+1:]
+		}
+	}
+
+	return string(tail)
+}
+
+// MaskWriter masks secrets in everything it writes to the underlying writer, see [Mask].
+// Output is buffered until a line is complete, so secrets split across writes are masked
+// too; partial lines are written once the writer is flushed.
+type MaskWriter struct {
+	mtx     sync.Mutex
+	w       io.Writer
+	secrets []string
+	buf     bytes.Buffer
+}
+
+func NewMaskWriter(w io.Writer, secrets ...string) *MaskWriter {
+	return &MaskWriter{w: w, secrets: secrets}
+}
+
+func (m *MaskWriter) Write(data []byte) (int, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.buf.Write(data)
+
+	// carriage returns end lines too, so progress output keeps being written as it comes
+	idx := bytes.LastIndexAny(m.buf.Bytes(), "\r\n")
+	if idx < 0 {
+		return len(data), nil
+	}
+
+	lines := m.buf.Next(idx + 1)
+	if _, err := io.WriteString(m.w, Mask(string(lines), m.secrets...)); err != nil {
+		return len(data), err
+	}
+
+	return len(data), nil
+}
+
+// Flush writes any buffered partial line.
+func (m *MaskWriter) Flush() error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(m.w, Mask(m.buf.String(), m.secrets...))
+	m.buf.Reset()
+	return err
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	t.Run("prefixes every line",
+		func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewPrefixWriter(&out, "[pkg] ")
+
+			_, err := w.Write([]byte("uno\ndos\n"))
+			require.NoError(t, err)
+
+			assert.Equal(t, "[pkg] uno\n[pkg] dos\n", out.String())
+		},
+	)
+
+	t.Run("buffers partial lines until newline",
+		func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewPrefixWriter(&out, "[pkg] ")
+
+			_, err := w.Write([]byte("un"))
+			require.NoError(t, err)
+			assert.Empty(t, out.String())
+
+			_, err = w.Write([]byte("o\ndo"))
+			require.NoError(t, err)
+			assert.Equal(t, "[pkg] uno\n", out.String())
+
+			require.NoError(t, w.Flush())
+			assert.Equal(t, "[pkg] uno\n[pkg] do\n", out.String())
+		},
+	)
+
+	t.Run("flush without pending data is a noop",
+		func(t *testing.T) {
+			var out bytes.Buffer
+			w := NewPrefixWriter(&out, "[pkg] ")
+
+			require.NoError(t, w.Flush())
+			assert.Empty(t, out.String())
+		},
+	)
+
+	t.Run("lines from concurrent writers are not mixed",
+		func(t *testing.T) {
+			var out bytes.Buffer
+			shared := NewSyncWriter(&out)
+
+			var wg sync.WaitGroup
+			for i := range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					w := NewPrefixWriter(shared, fmt.Sprintf("[%d] ", i))
+					for range 50 {
+						_, _ = w.Write([]byte("some "))
+						_, _ = w.Write([]byte("line\n"))
+					}
+				}()
+			}
+			wg.Wait()
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			require.Len(t, lines, 200)
+			for _, line := range lines {
+				assert.Regexp(t, `^\[\d\] some line$`, line)
+			}
+		},
+	)
+}
+
+func TestTailWriter(t *testing.T) {
+	t.Run("keeps everything under the limit",
+		func(t *testing.T) {
+			w := NewTailWriter(64)
+			_, _ = w.Write([]byte("first\n"))
+			_, _ = w.Write([]byte("second\n"))
+
+			assert.Equal(t, "first\nsecond\n", w.String())
+		},
+	)
+
+	t.Run("keeps only the last complete lines over the limit",
+		func(t *testing.T) {
+			w := NewTailWriter(16)
+			for _, line := range []string{"line one\n", "line two\n", "line three\n"} {
+				n, err := w.Write([]byte(line))
+				require.NoError(t, err)
+				assert.Equal(t, len(line), n)
+			}
+
+			assert.Equal(t, "line three\n", w.String())
+		},
+	)
+}
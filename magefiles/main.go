@@ -21,6 +21,8 @@ var h = harness.New(
 			return harness.Run(ctx, "go", harness.WithArgs("mod", "download"))
 		},
 	),
+	harness.WithGitHubActions(commons.IsGitHubActions()),
+	harness.WithGitHubStepSummary(commons.IsGitHubActions()),
 )
 
 // format codebase using gofmt and goimports
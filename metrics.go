@@ -0,0 +1,107 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// MetricsSink exports the metrics collected by the harness, encoded using the
+// prometheus text exposition format.
+//
+// Exported metrics are task durations, task success and failure counters, execution
+// durations and binary provisioning durations.
+type MetricsSink func(ctx context.Context, metrics []byte) error
+
+// PushgatewaySink pushes the metrics to a prometheus pushgateway under the specified job,
+// replacing the metrics previously pushed for it.
+func PushgatewaySink(gateway, job string) MetricsSink {
+	return func(ctx context.Context, metrics []byte) error {
+		endpoint := strings.TrimSuffix(gateway, "/") + "/metrics/job/" + url.PathEscape(job)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(metrics))
+		if err != nil {
+			return fmt.Errorf("invalid pushgateway url %s: %w", gateway, err)
+		}
+		req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to push metrics: %w", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("failed to push metrics: unexpected status %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// TextfileSink writes the metrics to a file, replacing it atomically so it can be picked
+// up by the node exporter textfile collector; the file name must end in .prom for that.
+func TextfileSink(path string) MetricsSink {
+	return func(_ context.Context, metrics []byte) (err error) {
+		tmp, err := os.CreateTemp(filepath.Dir(path), ".metrics-")
+		if err != nil {
+			return fmt.Errorf("failed to create metrics file: %w", err)
+		}
+		defer func() {
+			if err != nil {
+				_ = os.Remove(tmp.Name())
+			}
+		}()
+
+		if _, err := tmp.Write(metrics); err != nil {
+			return errors.Join(fmt.Errorf("failed to write metrics file: %w", err), tmp.Close())
+		}
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("failed to close metrics file: %w", err)
+		}
+		if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+			return fmt.Errorf("failed to set metrics file permissions: %w", err)
+		}
+
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return fmt.Errorf("failed to replace metrics file %s: %w", path, err)
+		}
+
+		return nil
+	}
+}
+
+// WithMetrics exports the collected metrics to the specified sinks at the end of every execution.
+// Failing to export metrics is logged but doesn't fail the execution.
+func WithMetrics(sinks ...MetricsSink) Option {
+	return func(h *Harness) {
+		h.metrics = append(h.metrics, sinks...)
+	}
+}
+
+// exportmetrics sends the collected metrics to all configured sinks.
+func (h *Harness) exportmetrics(ctx context.Context) {
+	if len(h.metrics) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := internal.Metrics.WriteText(&buf); err != nil {
+		internal.LogDetail(fmt.Sprintf("failed to encode metrics: %s", err))
+		return
+	}
+
+	for _, sink := range h.metrics {
+		if err := sink(ctx, buf.Bytes()); err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to export metrics: %s", err))
+		}
+	}
+}
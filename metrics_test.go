@@ -0,0 +1,91 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aexvir/harness/internal"
+)
+
+func TestMetrics(t *testing.T) {
+	// start from a clean registry so counters don't depend on other tests
+	prev := internal.Metrics
+	internal.Metrics = internal.NewMetricsRegistry()
+	t.Cleanup(func() { internal.Metrics = prev })
+
+	t.Run("pushes metrics to pushgateway",
+		func(t *testing.T) {
+			var (
+				method, path, contenttype string
+				body                      []byte
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path, contenttype = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer srv.Close()
+
+			h := New(WithMetrics(PushgatewaySink(srv.URL, "mage lint")))
+
+			err := h.Execute(t.Context(),
+				Named("metrics-ok", func(_ context.Context) error { return nil }),
+				Named("metrics-fail", func(_ context.Context) error { return errors.New("boom") }),
+			)
+			require.Error(t, err)
+
+			assert.Equal(t, http.MethodPut, method)
+			assert.Equal(t, "/metrics/job/mage lint", path)
+			assert.Equal(t, "text/plain; version=0.0.4", contenttype)
+			assert.Contains(t, string(body), `harness_task_duration_seconds{task="metrics-ok"}`)
+			assert.Contains(t, string(body), `harness_task_success_total{task="metrics-ok"} 1`)
+			assert.Contains(t, string(body), `harness_task_failure_total{task="metrics-fail"} 1`)
+			assert.Contains(t, string(body), "harness_execution_duration_seconds ")
+		},
+	)
+
+	t.Run("pushgateway errors don't fail the execution",
+		func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer srv.Close()
+
+			h := New(WithMetrics(PushgatewaySink(srv.URL, "ci")))
+
+			err := h.Execute(t.Context(), func(_ context.Context) error { return nil })
+			require.NoError(t, err)
+
+			err = PushgatewaySink(srv.URL, "ci")(t.Context(), []byte{})
+			assert.ErrorContains(t, err, "unexpected status 500")
+		},
+	)
+
+	t.Run("writes metrics to textfile",
+		func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "harness.prom")
+			h := New(WithMetrics(TextfileSink(path)))
+
+			err := h.Execute(t.Context(), Named("metrics-textfile", func(_ context.Context) error { return nil }))
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Contains(t, string(content), `harness_task_success_total{task="metrics-textfile"} 1`)
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		},
+	)
+}
@@ -2,6 +2,7 @@ package harness
 
 import (
 	"io"
+	"log/slog"
 
 	"github.com/aexvir/harness/internal"
 )
@@ -10,3 +11,35 @@ import (
 func SetOutput(w io.Writer) {
 	internal.SetOutput(w)
 }
+
+// NewPrettyHandler returns the default log handler, which renders logs as colored lines
+// with status symbols on the output set via [SetOutput].
+func NewPrettyHandler() slog.Handler {
+	return &internal.PrettyHandler{}
+}
+
+// RegisterSecrets masks the values in all harness logs and in the output of all commands,
+// so tokens don't end up in ci logs. See [WithSecrets] for masking secrets of a single command.
+func RegisterSecrets(values ...string) {
+	internal.RegisterSecrets(values...)
+}
+
+// Verbosity controls how much is shown while tasks run, see [WithVerbosity].
+type Verbosity = internal.Verbosity
+
+const (
+	// VerbosityQuiet shows errors only; command output is hidden, except the end of the
+	// output of failing commands.
+	VerbosityQuiet = internal.VerbosityQuiet
+	// VerbosityNormal shows command lines, their output and outcome.
+	VerbosityNormal = internal.VerbosityNormal
+	// VerbosityVerbose shows per-step details too; it's the default.
+	VerbosityVerbose = internal.VerbosityVerbose
+	// VerbosityDebug shows everything, including how commands are run.
+	VerbosityDebug = internal.VerbosityDebug
+)
+
+// ParseVerbosity parses the name of a verbosity: quiet, normal, verbose or debug.
+func ParseVerbosity(value string) (Verbosity, error) {
+	return internal.ParseVerbosity(value)
+}
@@ -0,0 +1,97 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// Pipe runs the commands as a pipeline, wiring the stdout of every command into the stdin
+// of the next one, like "go test -json ./... | gotestfmt" would in a shell.
+// Commands are connected using os pipes, so output is streamed rather than buffered.
+// The stdin of the first command and the stdout of the last one are kept as configured.
+// Like a shell with pipefail set, the pipeline fails if any of the commands fails.
+func Pipe(ctx context.Context, runners ...*TaskRunner) (err error) {
+	if len(runners) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(runners) > 1 && slices.ContainsFunc(runners, func(r *TaskRunner) bool { return r.remote != nil }) {
+		return errors.New("commands running over ssh can't be piped")
+	}
+
+	var (
+		descriptions []string
+		pipes        []*os.File
+	)
+
+	// the parent copies of the pipes need to be closed once the commands are started,
+	// so each command sees the end of its input when the previous one exits
+	closepipes := func() {
+		for _, pipe := range pipes {
+			_ = pipe.Close()
+		}
+		pipes = nil
+	}
+	defer closepipes()
+
+	for idx, r := range runners {
+		descriptions = append(descriptions, r.describe())
+
+		if idx == len(runners)-1 {
+			break
+		}
+
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to create pipe: %w", err)
+		}
+		pipes = append(pipes, reader, writer)
+
+		r.cmd.Stdout = writer
+		runners[idx+1].cmd.Stdin = reader
+	}
+
+	LogStep(strings.Join(descriptions, " | "))
+
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			internal.LogError(elapsed.String())
+			internal.LogBlank()
+			return
+		}
+		internal.LogSuccess(elapsed.String())
+		internal.LogBlank()
+	}()
+
+	for idx, r := range runners {
+		if err := r.start(); err != nil {
+			for _, started := range runners[:idx] {
+				_ = started.kill()
+				_ = started.wait()
+			}
+			return r.fail(err)
+		}
+	}
+
+	closepipes()
+
+	var errs []error
+	for _, r := range runners {
+		if err := r.checkexit(r.wait()); err != nil && !r.allowerr {
+			errs = append(errs, r.fail(err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
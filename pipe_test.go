@@ -0,0 +1,70 @@
+package harness
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("util script requires a posix shell")
+	}
+
+	t.Run("wires output of each command into the next",
+		func(t *testing.T) {
+			var out bytes.Buffer
+
+			first, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("mixed"), WithStdErr(io.Discard))
+			require.NoError(t, err)
+			second, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("print"))
+			require.NoError(t, err)
+			third, err := Cmd(t.Context(), "grep", WithArgs("three"), WithStdOut(&out))
+			require.NoError(t, err)
+
+			require.NoError(t, Pipe(t.Context(), first, second, third))
+			assert.Equal(t, "three\n", out.String())
+		},
+	)
+
+	t.Run("fails if any command fails",
+		func(t *testing.T) {
+			var out bytes.Buffer
+
+			first, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("fail"), WithStdErr(io.Discard))
+			require.NoError(t, err)
+			second, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("print"), WithStdOut(&out))
+			require.NoError(t, err)
+
+			err = Pipe(t.Context(), first, second)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "util.sh: exit status 3")
+		},
+	)
+
+	t.Run("allowed errors don't fail the pipeline",
+		func(t *testing.T) {
+			first, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("fail"), WithStdErr(io.Discard), WithAllowErrors())
+			require.NoError(t, err)
+			second, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("print"), WithStdOut(io.Discard))
+			require.NoError(t, err)
+
+			require.NoError(t, Pipe(t.Context(), first, second))
+		},
+	)
+
+	t.Run("fails when a command can't be started",
+		func(t *testing.T) {
+			first, err := Cmd(t.Context(), "testdata/util.sh", WithArgs("fork"))
+			require.NoError(t, err)
+			second, err := Cmd(t.Context(), "testdata/missing.sh")
+			require.NoError(t, err)
+
+			require.Error(t, Pipe(t.Context(), first, second))
+		},
+	)
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package harness
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// procgroup is the process group a command and all its children run in.
+type procgroup struct {
+	pgid int
+}
+
+// setupprocgroup makes the command start in its own process group.
+func setupprocgroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// attachprocgroup returns the process group of a started command.
+func attachprocgroup(cmd *exec.Cmd) (*procgroup, error) {
+	return &procgroup{pgid: cmd.Process.Pid}, nil
+}
+
+// kill all processes of the group.
+func (g *procgroup) kill() error {
+	return g.signal(syscall.SIGKILL)
+}
+
+// terminate asks all processes of the group to shut down.
+func (g *procgroup) terminate() error {
+	return g.signal(syscall.SIGTERM)
+}
+
+// release the resources associated with the group.
+func (g *procgroup) release() {}
+
+func (g *procgroup) signal(sig syscall.Signal) error {
+	err := syscall.Kill(-g.pgid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
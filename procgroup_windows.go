@@ -0,0 +1,75 @@
+package harness
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW         = kernel32.NewProc("CreateJobObjectW")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	processSetQuota  = 0x0100
+	processTerminate = 0x0001
+)
+
+// procgroup is the job object a command and all its children run in.
+type procgroup struct {
+	job syscall.Handle
+}
+
+// setupprocgroup makes the command start in its own process group.
+func setupprocgroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// attachprocgroup assigns a started command to a new job object, so it can be killed along
+// with all the processes it spawns.
+func attachprocgroup(cmd *exec.Cmd) (*procgroup, error) {
+	job, _, err := procCreateJobObjectW.Call(0, uintptr(unsafe.Pointer(nil)))
+	if job == 0 {
+		return nil, fmt.Errorf("failed to create job object: %w", err)
+	}
+
+	process, err := syscall.OpenProcess(processSetQuota|processTerminate, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		_ = syscall.CloseHandle(syscall.Handle(job))
+		return nil, fmt.Errorf("failed to open process: %w", err)
+	}
+	defer syscall.CloseHandle(process) //nolint:errcheck
+
+	if ok, _, err := procAssignProcessToJobObject.Call(job, uintptr(process)); ok == 0 {
+		_ = syscall.CloseHandle(syscall.Handle(job))
+		return nil, fmt.Errorf("failed to assign process to job object: %w", err)
+	}
+
+	return &procgroup{job: syscall.Handle(job)}, nil
+}
+
+// kill all processes of the job.
+func (g *procgroup) kill() error {
+	if ok, _, err := procTerminateJobObject.Call(uintptr(g.job), 1); ok == 0 {
+		return fmt.Errorf("failed to terminate job object: %w", err)
+	}
+	return nil
+}
+
+// terminate kills all processes of the job, as windows has no way of asking
+// processes to shut down.
+func (g *procgroup) terminate() error {
+	return g.kill()
+}
+
+// release the resources associated with the job.
+func (g *procgroup) release() {
+	_ = syscall.CloseHandle(g.job)
+}
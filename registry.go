@@ -0,0 +1,99 @@
+package harness
+
+import "github.com/aexvir/harness/internal"
+
+// TaskInfo holds the metadata of a task registered in a harness.
+type TaskInfo struct {
+	Name        string
+	Description string
+	Tags        []string
+	DependsOn   []string
+}
+
+// TaskOpt allows attaching metadata to a registered task.
+type TaskOpt func(info *TaskInfo)
+
+// WithTaskDescription attaches a human-readable description to the task.
+func WithTaskDescription(description string) TaskOpt {
+	return func(info *TaskInfo) {
+		info.Description = description
+	}
+}
+
+// WithTaskTags attaches tags to the task, e.g. "lint" or "ci".
+func WithTaskTags(tags ...string) TaskOpt {
+	return func(info *TaskInfo) {
+		info.Tags = append(info.Tags, tags...)
+	}
+}
+
+// WithTaskDependencies declares the names of the tasks that need to run before this one
+// when running it via [Harness.ExecuteGraph].
+func WithTaskDependencies(names ...string) TaskOpt {
+	return func(info *TaskInfo) {
+		info.DependsOn = append(info.DependsOn, names...)
+	}
+}
+
+type registration struct {
+	info TaskInfo
+	task Task
+}
+
+// Register records a task in the harness under the specified name along with its metadata,
+// returning the task [Named] after it and [Tagged] with its tags, so it can be passed to
+// [Harness.Execute] directly.
+// Registering a task under a name that's already taken replaces the previous registration.
+//
+// Registered tasks can be listed via [Harness.List], which allows tools like clis,
+// pickers or editor integrations to be built from a single source of truth.
+func (h *Harness) Register(name string, task Task, opts ...TaskOpt) Task {
+	info := TaskInfo{Name: name}
+	for _, opt := range opts {
+		opt(&info)
+	}
+
+	task = Named(name, Tagged(task, info.Tags...))
+
+	for idx, reg := range h.registry {
+		if reg.info.Name == name {
+			h.registry[idx] = registration{info: info, task: task}
+			return task
+		}
+	}
+
+	h.registry = append(h.registry, registration{info: info, task: task})
+	return task
+}
+
+// List returns the metadata of all registered tasks in registration order.
+func (h *Harness) List() []TaskInfo {
+	infos := make([]TaskInfo, 0, len(h.registry))
+	for _, reg := range h.registry {
+		info := reg.info
+		info.Tags = append([]string(nil), reg.info.Tags...)
+		info.DependsOn = append([]string(nil), reg.info.DependsOn...)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Lookup returns the task registered under the specified name, or under the name the
+// alias maps to in the aliases of the config file.
+func (h *Harness) Lookup(name string) (Task, bool) {
+	name = resolvealias(name)
+	for _, reg := range h.registry {
+		if reg.info.Name == name {
+			return reg.task, true
+		}
+	}
+	return nil, false
+}
+
+// resolvealias returns the task name an alias in the config file maps to, if any.
+func resolvealias(name string) string {
+	if target, ok := internal.CurrentConfig().Aliases[name]; ok {
+		return target
+	}
+	return name
+}
@@ -0,0 +1,71 @@
+package harness
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("lists registered tasks in order",
+		func(t *testing.T) {
+			h := New()
+
+			h.Register("lint", noop, WithTaskDescription("lint the code"), WithTaskTags("ci", "quality"))
+			h.Register("test", noop, WithTaskDescription("run unit tests"))
+
+			assert.Equal(t,
+				[]TaskInfo{
+					{Name: "lint", Description: "lint the code", Tags: []string{"ci", "quality"}},
+					{Name: "test", Description: "run unit tests"},
+				},
+				h.List(),
+			)
+		},
+	)
+
+	t.Run("register returns the task",
+		func(t *testing.T) {
+			h := New()
+			called := false
+
+			task := h.Register("uno", func(_ context.Context) error { called = true; return nil })
+			require.NoError(t, h.Execute(t.Context(), task))
+
+			assert.True(t, called)
+		},
+	)
+
+	t.Run("registering an existing name replaces it",
+		func(t *testing.T) {
+			h := New()
+
+			h.Register("uno", noop, WithTaskDescription("first"))
+			h.Register("dos", noop)
+			h.Register("uno", noop, WithTaskDescription("second"))
+
+			tasks := h.List()
+			require.Len(t, tasks, 2)
+			assert.Equal(t, "uno", tasks[0].Name)
+			assert.Equal(t, "second", tasks[0].Description)
+		},
+	)
+
+	t.Run("lookup",
+		func(t *testing.T) {
+			h := New()
+			h.Register("uno", noop)
+
+			task, ok := h.Lookup("uno")
+			assert.True(t, ok)
+			assert.NotNil(t, task)
+
+			_, ok = h.Lookup("dos")
+			assert.False(t, ok)
+		},
+	)
+}
+
+func noop(_ context.Context) error { return nil }
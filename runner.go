@@ -1,16 +1,26 @@
 package harness
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math/rand/v2"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/aexvir/harness/internal"
 )
@@ -20,37 +30,82 @@ type TaskRunner struct {
 	Executable string
 	Arguments  []string
 
-	cmd      *exec.Cmd
-	okmsg    string
-	errmsg   string
-	quiet    bool
-	allowerr bool
+	ctx         context.Context
+	cmd         *exec.Cmd
+	groupmtx    sync.Mutex
+	group       *procgroup
+	nogroup     bool
+	okmsg       string
+	errmsg      string
+	quiet       bool
+	allowerr    bool
+	expected    []int
+	retries     int
+	backoff     time.Duration
+	stderr      *internal.TailWriter
+	prefix      string
+	secrets     []string
+	teefile     *teefile
+	tempdir     *tempdir
+	flushers    []flushwriter
+	shell       bool
+	interpreter []string
+	script      string
+	reldir      bool
+	interactive bool
+	remote      *sshconf
+	session     *sshsession
+	container   *containerconf
 }
 
 // Cmd builds a command runner for a specific Executable.
 func Cmd(ctx context.Context, executable string, opts ...RunnerOpt) (*TaskRunner, error) {
-	// always resolve binary to their absolute path
-	if strings.Contains(executable, "/") {
-		if !filepath.IsAbs(executable) {
-			abs, err := filepath.Abs(executable)
-			if err != nil {
-				return nil, fmt.Errorf("failed to resolve executable path %q: %w", executable, err)
-			}
-			executable = abs
-		}
-	}
-
 	cmd := exec.CommandContext(ctx, executable)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = internal.Stdout
+	cmd.Stderr = internal.Stderr
 	cmd.Stdin = os.Stdin
 
+	// command output is hidden when quiet, stderr is still kept for reporting failures
+	if internal.CurrentVerbosity() == internal.VerbosityQuiet {
+		cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
+	}
+
 	r := TaskRunner{
 		Executable: executable,
+		ctx:        ctx,
 		cmd:        cmd,
 	}
 
+	cmd.Cancel = r.kill
+
+	if state := currenttask(ctx); state != nil {
+		state.begin()
+
+		// give commands a chance to shut down cleanly when the execution is interrupted
+		cmd.Cancel = terminate(ctx, &r)
+		cmd.WaitDelay = state.grace
+
+		// keep a copy of the output when running inside a task that captures it
+		if state.writer != nil {
+			cmd.Stdout = io.MultiWriter(cmd.Stdout, state.writer)
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, state.writer)
+		}
+	}
+
+	// ask commands to avoid colors too
+	if internal.IsPlainOutput() && os.Getenv("NO_COLOR") == "" {
+		r.setenv("NO_COLOR=1")
+	}
+
+	if env := internal.CurrentConfig().Env; len(env) > 0 {
+		if err := WithEnvMap(env)(&r); err != nil {
+			return nil, fmt.Errorf("invalid env in config file: %w", err)
+		}
+	}
+
+	stdout, stderr := cmd.Stdout, cmd.Stderr
+
 	for _, opt := range opts {
 		err := opt(&r)
 		if err != nil {
@@ -58,7 +113,75 @@ func Cmd(ctx context.Context, executable string, opts ...RunnerOpt) (*TaskRunner
 		}
 	}
 
-	cmd.Args = append([]string{executable}, r.Arguments...)
+	// interactive commands talk to the terminal directly, unless told otherwise
+	if r.interactive {
+		if samewriter(cmd.Stdout, stdout) {
+			cmd.Stdout = os.Stdout
+		}
+		if samewriter(cmd.Stderr, stderr) {
+			cmd.Stderr = os.Stderr
+		}
+		stdout, stderr = nil, nil
+	}
+
+	if r.teefile != nil {
+		stdout, stderr = r.wrapoutput(stdout, stderr, true, r.teefile.tee)
+	}
+
+	// secrets are only masked on the default output, as explicitly captured output
+	// may need them, like when reading a token from a command
+	if internal.HasSecrets(r.secrets...) && !r.interactive {
+		stdout, stderr = r.wrapoutput(stdout, stderr, false, func(w io.Writer) flushwriter {
+			return internal.NewMaskWriter(w, r.secrets...)
+		})
+	}
+
+	// tasks running in parallel get their default output prefixed automatically
+	if r.prefix != "" && !r.interactive {
+		r.wrapoutput(stdout, stderr, true, r.prefixwriter)
+	} else if state := currenttask(ctx); state != nil && state.prefixwidth > 0 && !r.interactive {
+		r.prefix = internal.LinePrefix(state.displayname(), state.prefixwidth)
+		r.wrapoutput(stdout, stderr, false, r.prefixwriter)
+	}
+
+	if r.remote != nil && r.container != nil {
+		return nil, errors.New("commands can't run both over ssh and in a container")
+	}
+
+	if r.remote != nil {
+		if err := r.useremote(); err != nil {
+			return nil, err
+		}
+	} else if r.container != nil {
+		if err := r.usecontainer(); err != nil {
+			return nil, err
+		}
+	} else if r.shell {
+		if err := r.useshell(); err != nil {
+			return nil, err
+		}
+	} else {
+		// always resolve binary to their absolute path
+		if filepath.Base(executable) != executable && !filepath.IsAbs(executable) {
+			path := executable
+			if r.reldir {
+				path = filepath.Join(cmd.Dir, executable)
+			}
+
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve executable path %q: %w", executable, err)
+			}
+			r.Executable = resolveextension(abs)
+			cmd.Path = r.Executable
+		}
+
+		cmd.Args = append([]string{r.Executable}, r.Arguments...)
+	}
+
+	if !r.nogroup {
+		setupprocgroup(cmd)
+	}
 
 	return &r, nil
 }
@@ -80,19 +203,43 @@ func (r *TaskRunner) Exec() error {
 	}()
 
 	if !r.quiet {
-		LogStep(fmt.Sprint(filepath.Base(r.Executable), " ", strings.Join(r.Arguments, " ")))
-		if filepath.IsAbs(r.Executable) {
+		LogStep(r.describe())
+		if !r.shell && r.remote == nil && r.container == nil && filepath.IsAbs(r.Executable) {
 			internal.LogDetail(fmt.Sprintf("from path %s", r.Executable))
 		}
+
+		if internal.CurrentVerbosity() >= internal.VerbosityDebug {
+			words := make([]string, len(r.cmd.Args))
+			for idx, arg := range r.cmd.Args {
+				words[idx] = shellquote(arg)
+			}
+			internal.LogTrace(internal.Mask("running "+strings.Join(words, " "), r.secrets...))
+			if r.cmd.Dir != "" {
+				internal.LogTrace(fmt.Sprintf("in %s", r.cmd.Dir))
+			}
+		}
 	}
 
-	err = r.cmd.Run()
+	if r.interactive {
+		defer internal.PauseLiveStatus()()
+	}
+
+	err = r.run()
 
 	if !r.allowerr && err != nil {
 		if !r.quiet && r.errmsg != "" {
 			internal.LogMessage(color.FgRed, r.errmsg)
 		}
-		return fmt.Errorf("%s: %w", r.Executable, err)
+
+		err = r.fail(err)
+
+		// the output of failing commands is still worth showing when quiet
+		var cmderr *CmdError
+		if internal.CurrentVerbosity() == internal.VerbosityQuiet && errors.As(err, &cmderr) && cmderr.Stderr != "" {
+			internal.LogMessage(color.FgRed, strings.TrimRight(cmderr.Stderr, "\n"))
+		}
+
+		return err
 	}
 
 	if !r.quiet && r.okmsg != "" {
@@ -102,6 +249,358 @@ func (r *TaskRunner) Exec() error {
 	return nil
 }
 
+// run the command until it exits, retrying it if it fails and retries were configured.
+func (r *TaskRunner) run() error {
+	backoff := r.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := r.start()
+		if err == nil {
+			err = r.checkexit(r.wait())
+		}
+
+		// only commands that actually ran are retried; failing to start is not transient
+		if err == nil || r.retries == 0 || !r.exited() {
+			return err
+		}
+
+		if r.ctx.Err() != nil {
+			return fmt.Errorf("retry cancelled: %w", err)
+		}
+
+		if attempt > r.retries {
+			return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		}
+
+		delay := jitter(backoff)
+		internal.LogDetail(fmt.Sprintf("attempt %d of %d failed, retrying in %s", attempt, r.retries+1, delay.Round(time.Millisecond)))
+
+		select {
+		case <-time.After(delay):
+		case <-r.ctx.Done():
+			return fmt.Errorf("retry cancelled: %w", errors.Join(err, r.ctx.Err()))
+		}
+
+		backoff *= 2
+		r.reset()
+	}
+}
+
+// reset prepares the command to be run again, as an [exec.Cmd] can only be run once.
+func (r *TaskRunner) reset() {
+	next := exec.CommandContext(r.ctx, r.cmd.Path)
+	next.Args = r.cmd.Args
+	next.Env = r.cmd.Env
+	next.Dir = r.cmd.Dir
+	next.Stdin = r.cmd.Stdin
+	next.Stdout = r.cmd.Stdout
+	next.Stderr = r.cmd.Stderr
+	next.ExtraFiles = r.cmd.ExtraFiles
+	next.SysProcAttr = r.cmd.SysProcAttr
+	next.Cancel = r.cmd.Cancel
+	next.WaitDelay = r.cmd.WaitDelay
+
+	r.cmd = next
+
+	r.groupmtx.Lock()
+	r.group = nil
+	r.session = nil
+	r.groupmtx.Unlock()
+
+	if r.stderr != nil {
+		r.stderr.Reset()
+	}
+}
+
+// jitter randomizes the delay between half and all of it, so commands retried at
+// the same time don't all hit the same service at once again.
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// Start a command in the background, without waiting for it to finish.
+// This is useful for processes that need to be running while other tasks run, like
+// the server an end-to-end test suite is run against.
+// Commands started this way must be terminated using [TaskRunner.Stop].
+func (r *TaskRunner) Start() error {
+	if !r.quiet {
+		LogStep("starting " + r.describe())
+	}
+
+	if err := r.start(); err != nil {
+		return r.fail(err)
+	}
+
+	return nil
+}
+
+// Stop terminates a command started with [TaskRunner.Start] and waits for it to exit.
+// The command is stopped the same way it would be if its context was cancelled, so
+// [WithGracefulStop] can be used to allow it to shut down cleanly.
+// Exiting due to being stopped isn't considered an error.
+func (r *TaskRunner) Stop() error {
+	if r.cmd.Process == nil && r.currentsession() == nil {
+		return nil
+	}
+
+	if !r.quiet {
+		LogStep(fmt.Sprint("stopping ", filepath.Base(r.Executable)))
+	}
+
+	if err := r.cmd.Cancel(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to stop %s: %w", r.Executable, err)
+	}
+
+	// the wait delay only applies after the context is done, so force it
+	if r.cmd.WaitDelay > 0 {
+		timer := time.AfterFunc(r.cmd.WaitDelay, func() { _ = r.kill() })
+		defer timer.Stop()
+	}
+
+	if err := r.wait(); err != nil && !isexiterror(err) {
+		return fmt.Errorf("%s: %w", r.Executable, err)
+	}
+
+	return nil
+}
+
+// ExitCode returns the exit code of the command once it exited, or -1 if it hasn't
+// exited yet or was terminated by a signal.
+func (r *TaskRunner) ExitCode() int {
+	if remote := r.currentsession(); remote != nil {
+		return remote.exitcode()
+	}
+	if r.cmd.ProcessState == nil {
+		return -1
+	}
+	return r.cmd.ProcessState.ExitCode()
+}
+
+// checkexit decides if the result of waiting for the command is a failure based on
+// the expected exit codes, if any were specified.
+func (r *TaskRunner) checkexit(err error) error {
+	if r.expected == nil || !r.exited() {
+		return err
+	}
+
+	if err != nil && !isexiterror(err) {
+		return err
+	}
+
+	code := r.ExitCode()
+	if slices.Contains(r.expected, code) {
+		return nil
+	}
+
+	codes := make([]string, len(r.expected))
+	for idx, expected := range r.expected {
+		codes[idx] = strconv.Itoa(expected)
+	}
+
+	if err == nil {
+		return fmt.Errorf("unexpected exit status %d; expected %s", code, strings.Join(codes, ", "))
+	}
+	return fmt.Errorf("unexpected %w; expected %s", err, strings.Join(codes, ", "))
+}
+
+// exited reports if the command ran until it exited, locally or on the remote host.
+func (r *TaskRunner) exited() bool {
+	if remote := r.currentsession(); remote != nil {
+		remote.mtx.Lock()
+		defer remote.mtx.Unlock()
+		return remote.exited
+	}
+	return r.cmd.ProcessState != nil
+}
+
+// isexiterror reports if the error is the command exiting with a failure,
+// rather than failing to run it.
+func isexiterror(err error) bool {
+	var exiterr *exec.ExitError
+	var remoteerr *ssh.ExitError
+	return errors.As(err, &exiterr) || errors.As(err, &remoteerr)
+}
+
+// describe returns a human readable representation of the command.
+func (r *TaskRunner) describe() string {
+	if r.remote != nil {
+		return internal.Mask(r.remote.host+": "+r.script, r.secrets...)
+	}
+	if r.container != nil {
+		command := r.script
+		if !r.shell {
+			command = strings.Join(append([]string{r.Executable}, r.Arguments...), " ")
+		}
+		return internal.Mask(r.container.image+": "+command, r.secrets...)
+	}
+	if r.shell {
+		return internal.Mask(r.script, r.secrets...)
+	}
+	return internal.Mask(fmt.Sprint(filepath.Base(r.Executable), " ", strings.Join(r.Arguments, " ")), r.secrets...)
+}
+
+// useshell makes the command run its executable and arguments as a shell script.
+func (r *TaskRunner) useshell() error {
+	interpreter := r.interpreter
+	if interpreter == nil {
+		interpreter = []string{"sh", "-c"}
+		if runtime.GOOS == "windows" {
+			interpreter = []string{"cmd", "/C"}
+		}
+	}
+
+	path, err := exec.LookPath(interpreter[0])
+	if err != nil {
+		return fmt.Errorf("failed to find shell %s: %w", interpreter[0], err)
+	}
+
+	r.script = strings.Join(append([]string{r.Executable}, r.Arguments...), " ")
+	r.cmd.Path, r.cmd.Err = path, nil
+	r.cmd.Args = append(slices.Clone(interpreter), r.script)
+
+	return nil
+}
+
+// start the command, placing it in its own process group.
+func (r *TaskRunner) start() error {
+	r.capturestderr()
+
+	if r.remote != nil {
+		return r.startremote()
+	}
+
+	if r.tempdir != nil {
+		if err := r.tempdir.create(r); err != nil {
+			return err
+		}
+	}
+
+	if err := r.cmd.Start(); err != nil {
+		if r.tempdir != nil {
+			err = errors.Join(err, r.tempdir.remove(false))
+		}
+		return err
+	}
+
+	if !r.nogroup {
+		group, err := attachprocgroup(r.cmd)
+		if err != nil {
+			internal.LogDetail(fmt.Sprintf("failed to set up process group: %s", err))
+			return nil
+		}
+
+		r.groupmtx.Lock()
+		r.group = group
+		r.groupmtx.Unlock()
+	}
+
+	return nil
+}
+
+// wait for the command to exit and release its process group and temp dir.
+func (r *TaskRunner) wait() error {
+	defer func() {
+		if group := r.currentgroup(); group != nil {
+			group.release()
+		}
+	}()
+
+	var err error
+	if r.remote != nil {
+		err = r.waitremote()
+	} else {
+		err = r.cmd.Wait()
+	}
+	for _, flusher := range r.flushers {
+		_ = flusher.Flush()
+	}
+
+	if r.tempdir != nil {
+		err = errors.Join(err, r.tempdir.remove(true))
+	}
+
+	return err
+}
+
+type flushwriter interface {
+	io.Writer
+	Flush() error
+}
+
+func (r *TaskRunner) prefixwriter(w io.Writer) flushwriter {
+	return internal.NewPrefixWriter(w, r.prefix)
+}
+
+// wrapoutput wraps the stdout and stderr writers of the command, flushing the wrappers
+// once the command exits. Unless all is set, only writers matching the ones passed are
+// wrapped; it returns what those writers became.
+func (r *TaskRunner) wrapoutput(stdout, stderr io.Writer, all bool, wrap func(w io.Writer) flushwriter) (io.Writer, io.Writer) {
+	apply := func(w, match io.Writer) (io.Writer, io.Writer) {
+		matches := samewriter(w, match)
+		if w == nil || (!all && !matches) {
+			return w, match
+		}
+
+		wrapped := wrap(w)
+		r.flushers = append(r.flushers, wrapped)
+
+		if matches {
+			return wrapped, wrapped
+		}
+		return wrapped, match
+	}
+
+	if samewriter(r.cmd.Stdout, r.cmd.Stderr) {
+		original := r.cmd.Stderr
+		r.cmd.Stdout, stdout = apply(r.cmd.Stdout, stdout)
+		if samewriter(original, stderr) {
+			stderr = r.cmd.Stdout
+		}
+		r.cmd.Stderr = r.cmd.Stdout
+		return stdout, stderr
+	}
+
+	r.cmd.Stdout, stdout = apply(r.cmd.Stdout, stdout)
+	r.cmd.Stderr, stderr = apply(r.cmd.Stderr, stderr)
+	return stdout, stderr
+}
+
+func (r *TaskRunner) currentgroup() *procgroup {
+	r.groupmtx.Lock()
+	defer r.groupmtx.Unlock()
+
+	return r.group
+}
+
+// kill the command along with all processes in its group.
+func (r *TaskRunner) kill() error {
+	if remote := r.currentsession(); remote != nil {
+		return remote.kill()
+	}
+	if group := r.currentgroup(); group != nil {
+		return group.kill()
+	}
+	return r.cmd.Process.Kill()
+}
+
+// terminate asks the command and all processes in its group to shut down.
+// Windows doesn't support it, so there the processes are killed.
+func (r *TaskRunner) terminate() error {
+	if remote := r.currentsession(); remote != nil {
+		return remote.terminate()
+	}
+	if group := r.currentgroup(); group != nil {
+		return group.terminate()
+	}
+	if runtime.GOOS == "windows" {
+		return r.cmd.Process.Kill()
+	}
+	return r.cmd.Process.Signal(syscall.SIGTERM)
+}
+
 // Run is a helper function to avoid repetition while gracefully handling errors.
 func Run(ctx context.Context, program string, opts ...RunnerOpt) error {
 	rnr, err := Cmd(ctx, program, opts...)
@@ -112,24 +611,91 @@ func Run(ctx context.Context, program string, opts ...RunnerOpt) error {
 	return rnr.Exec()
 }
 
+// RunShell runs a script through the system shell, sh on unix and cmd on windows, for the
+// cases where pipes, globs or redirections are needed, e.g. "go test ./... | tee out.txt".
+// It accepts the same options as [Run].
+func RunShell(ctx context.Context, script string, opts ...RunnerOpt) error {
+	return Run(ctx, script, append(opts[:len(opts):len(opts)], WithShell())...)
+}
+
+// Output runs a command the same way [Run] does, returning its stdout with the trailing
+// newline trimmed; stderr keeps being displayed as usual.
+func Output(ctx context.Context, program string, opts ...RunnerOpt) (string, error) {
+	var out bytes.Buffer
+
+	err := Run(ctx, program, append(opts[:len(opts):len(opts)], WithStdOut(&out))...)
+	return strings.TrimRight(out.String(), "\r\n"), err
+}
+
+// CombinedOutput runs a command the same way [Run] does, returning both its stdout and
+// stderr with the trailing newline trimmed.
+func CombinedOutput(ctx context.Context, program string, opts ...RunnerOpt) (string, error) {
+	var out bytes.Buffer
+
+	err := Run(ctx, program, append(opts[:len(opts):len(opts)], WithCombinedOutput(&out))...)
+	return strings.TrimRight(out.String(), "\r\n"), err
+}
+
 // RunnerOpt allows customizing the behavior of the command runner.
 type RunnerOpt func(r *TaskRunner) error
 
-// WithEnv sets up environment variables for the command.
+// WithEnv sets up environment variables for the command, on top of the environment
+// of the current process. Variables are specified as NAME=value; only the first '='
+// separates the name from the value, so values can contain '=' themselves.
 func WithEnv(vars ...string) RunnerOpt {
 	return func(r *TaskRunner) error {
-		r.cmd.Env = os.Environ()
 		for _, vrb := range vars {
-			items := strings.SplitN(vrb, "=", 2)
-			if len(items) != 2 {
+			name, _, found := strings.Cut(vrb, "=")
+			if !found || name == "" {
 				return fmt.Errorf("invalid env format; %s doesn't match NAME=value expectation", vrb)
 			}
-			r.cmd.Env = append(r.cmd.Env, vrb)
+			r.setenv(vrb)
+		}
+		return nil
+	}
+}
+
+// WithEnvMap sets up the environment variables in the map for the command.
+// Variables are added sorted by name, so the resulting environment is deterministic.
+func WithEnvMap(vars map[string]string) RunnerOpt {
+	return func(r *TaskRunner) error {
+		for _, name := range slices.Sorted(maps.Keys(vars)) {
+			if name == "" || strings.Contains(name, "=") {
+				return fmt.Errorf("invalid env variable name %q", name)
+			}
+			r.setenv(name + "=" + vars[name])
+		}
+		return nil
+	}
+}
+
+// WithEnvFile sets up the environment variables defined in a dotenv file for the command.
+//
+// Every line is expected to be NAME=value, optionally prefixed by export; empty lines and
+// lines starting with # are ignored. Values can be wrapped in single or double quotes;
+// double quoted values support the \n, \t, \" and \\ escape sequences.
+func WithEnvFile(path string) RunnerOpt {
+	return func(r *TaskRunner) error {
+		vars, err := readenvfile(path)
+		if err != nil {
+			return err
+		}
+		for _, vrb := range vars {
+			r.setenv(vrb)
 		}
 		return nil
 	}
 }
 
+// setenv appends the variable to the command environment, starting off the environment
+// of the current process the first time a variable is set.
+func (r *TaskRunner) setenv(vrb string) {
+	if r.cmd.Env == nil {
+		r.cmd.Env = os.Environ()
+	}
+	r.cmd.Env = append(r.cmd.Env, vrb)
+}
+
 // WithArgs command arguments.
 func WithArgs(args ...string) RunnerOpt {
 	return func(r *TaskRunner) error {
@@ -181,6 +747,27 @@ func WithStdOut(w io.Writer) RunnerOpt {
 	}
 }
 
+// WithStdErr set up stderr writer.
+// Combined with [WithStdOut], it allows handling diagnostics separately from the
+// output of the tool.
+func WithStdErr(w io.Writer) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.cmd.Stderr = w
+		return nil
+	}
+}
+
+// WithCombinedOutput sends both stdout and stderr to the same writer.
+// The child process shares a single pipe for both streams, so the order in which
+// lines are written is preserved, unlike using separate writers.
+func WithCombinedOutput(w io.Writer) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.cmd.Stdout = w
+		r.cmd.Stderr = w
+		return nil
+	}
+}
+
 // WithStdIn set up stdin reader.
 func WithStdIn(read io.Reader) RunnerOpt {
 	return func(r *TaskRunner) error {
@@ -189,6 +776,147 @@ func WithStdIn(read io.Reader) RunnerOpt {
 	}
 }
 
+// WithStdInString set up stdin to read the specified string.
+func WithStdInString(input string) RunnerOpt {
+	return WithStdIn(strings.NewReader(input))
+}
+
+// WithInteractive runs the command attached to the terminal, for flows that need real
+// interaction like "gh auth login" or "docker login". Stdin is kept attached and output is
+// written straight to the terminal without any buffering, so line prefixes and secret masking
+// don't apply; the command also stays in the foreground process group so it can read from the
+// terminal, and the live status is paused while it runs.
+func WithInteractive() RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.interactive = true
+		r.nogroup = true
+		return nil
+	}
+}
+
+// WithGracefulStop changes how the command is stopped when its context is cancelled.
+// Instead of killing the process straight away, it's sent a SIGTERM, and only if it
+// hasn't exited after the timeout, it gets killed.
+// This allows servers or tools like docker compose to shut down cleanly.
+// Windows doesn't support sending SIGTERM, so there the process is killed right away.
+func WithGracefulStop(timeout time.Duration) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.cmd.Cancel = r.terminate
+		r.cmd.WaitDelay = timeout
+		return nil
+	}
+}
+
+// WithShell runs the command through the system shell, sh on unix and cmd on windows, treating
+// the executable and arguments as a script. Arguments are appended to the script as they are,
+// without any quoting.
+func WithShell() RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.shell = true
+		return nil
+	}
+}
+
+// WithCmdExe runs the command through cmd.exe on windows, like [WithShell] does, which
+// allows running batch scripts and cmd builtins like "dir" or "mklink".
+func WithCmdExe() RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.shell = true
+		r.interpreter = []string{"cmd", "/C"}
+		return nil
+	}
+}
+
+// WithPowerShell runs the command through powershell, treating the executable and arguments
+// as a script, like [WithShell] does; useful for running .ps1 scripts, e.g. "./build.ps1".
+// PowerShell 7 (pwsh) is preferred over Windows PowerShell when both are installed.
+func WithPowerShell() RunnerOpt {
+	return func(r *TaskRunner) error {
+		shell := "pwsh"
+		if _, err := exec.LookPath(shell); err != nil {
+			shell = "powershell"
+		}
+
+		r.shell = true
+		r.interpreter = []string{shell, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command"}
+		return nil
+	}
+}
+
+// WithoutProcessGroup runs the command in the process group of the harness instead of its own.
+// Commands run in their own group so that all processes they spawn can be stopped along
+// with them; but processes outside the foreground group of a terminal can't read from it,
+// so interactive commands need to opt out.
+func WithoutProcessGroup() RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.nogroup = true
+		return nil
+	}
+}
+
+// WithExpectedExitCodes specifies which exit codes mean the command succeeded, for tools
+// that use exit codes to signal results rather than failures, like diff or grep.
+// Any other exit code, including 0 if it's not listed, makes the command fail; the actual
+// exit code is available via [TaskRunner.ExitCode].
+func WithExpectedExitCodes(codes ...int) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.expected = append(r.expected, codes...)
+		return nil
+	}
+}
+
+// WithRetries retries the command up to the specified amount of times if it fails, for
+// flaky commands that depend on the network like "go mod download" or "docker pull".
+// Between attempts it waits for the backoff, randomized with some jitter, which is doubled
+// after every failed attempt. Retrying stops as soon as the context is cancelled.
+//
+// Retries only apply to [TaskRunner.Exec]; the output of every attempt is written to the
+// configured writers and stdin can't be replayed, so commands reading from a stdin reader
+// should not be retried.
+func WithRetries(retries int, backoff time.Duration) RunnerOpt {
+	return func(r *TaskRunner) error {
+		if retries < 0 {
+			return fmt.Errorf("invalid amount of retries %d", retries)
+		}
+		r.retries = retries
+		r.backoff = backoff
+		return nil
+	}
+}
+
+// WithLinePrefix prefixes every line the command writes to stdout and stderr with the
+// label, in a color derived from it, like "docker compose logs" does; useful for telling
+// apart the output of commands running at the same time.
+// Tasks run in parallel by [Harness.ExecuteGraph] get their name as prefix automatically.
+func WithLinePrefix(label string) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.prefix = internal.LinePrefix(label, 0)
+		return nil
+	}
+}
+
+// WithSecrets masks the values in the logged command line and in the output the command
+// writes to the harness output, so tokens passed via arguments or env don't end up in ci logs.
+// Secrets used by many commands can be registered once via [RegisterSecrets].
+//
+// Output is masked line by line, so it's only written once each line is complete.
+func WithSecrets(values ...string) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.secrets = append(r.secrets, values...)
+		return nil
+	}
+}
+
+// WithExecRelativeToDir resolves relative executable paths against the directory set via
+// [WithDir] instead of the current working directory, for commands that live inside it,
+// like "node_modules/.bin/eslint" in a frontend directory.
+func WithExecRelativeToDir() RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.reldir = true
+		return nil
+	}
+}
+
 // WithAllowErrors allow errors in the command.
 func WithAllowErrors() RunnerOpt {
 	return func(r *TaskRunner) error {
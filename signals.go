@@ -0,0 +1,72 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// ErrInterrupted is returned when an execution is interrupted by a SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("execution interrupted")
+
+// interruption is the cause of the cancellation of tasks interrupted by a signal.
+type interruption struct {
+	signal os.Signal
+}
+
+func (i *interruption) Error() string {
+	return fmt.Sprintf("%s by %s", ErrInterrupted, i.signal)
+}
+
+func (i *interruption) Unwrap() error {
+	return ErrInterrupted
+}
+
+// trapsignals cancels the tasks of an execution on SIGINT or SIGTERM instead of letting
+// the process die, so commands can shut down cleanly and hooks and summary still run.
+// A second signal terminates the process right away.
+// The returned function stops trapping signals.
+func trapsignals(cancel context.CancelCauseFunc, interrupted func()) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			// let a second signal kill the process
+			signal.Stop(signals)
+
+			internal.LogBlank()
+			internal.LogError(fmt.Sprintf("received %s, shutting down", sig))
+			internal.LogDetail("send it again to terminate immediately")
+
+			interrupted()
+			cancel(&interruption{signal: sig})
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// terminate asks the command to shut down on interruptions, otherwise it's killed.
+// This is used as cancel function of commands run inside a harness; if they don't
+// exit within the grace period, they get killed anyway.
+func terminate(ctx context.Context, r *TaskRunner) func() error {
+	return func() error {
+		if errors.Is(context.Cause(ctx), ErrInterrupted) {
+			return r.terminate()
+		}
+		return r.kill()
+	}
+}
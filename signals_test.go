@@ -0,0 +1,81 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHarnessSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals to the own process isn't supported on windows")
+	}
+
+	interrupt := func(t *testing.T) {
+		process, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, process.Signal(os.Interrupt))
+	}
+
+	t.Run("interruption cancels tasks and still runs post hooks",
+		func(t *testing.T) {
+			var received []error
+			ran := false
+
+			h := New(
+				WithPostExecHook(
+					func(_ context.Context, errs []error) error {
+						received = errs
+						return nil
+					},
+				),
+			)
+
+			results, err := h.ExecuteWithResults(t.Context(),
+				func(ctx context.Context) error {
+					interrupt(t)
+					<-ctx.Done()
+					return context.Cause(ctx)
+				},
+				func(_ context.Context) error { ran = true; return nil },
+			)
+
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInterrupted)
+			assert.EqualError(t, err, "execution interrupted by interrupt")
+
+			assert.False(t, ran)
+			assert.True(t, results[1].Skipped)
+			require.Len(t, received, 1)
+			assert.ErrorIs(t, received[0], ErrInterrupted)
+		},
+	)
+
+	t.Run("commands are asked to shut down",
+		func(t *testing.T) {
+			var output bytes.Buffer
+			h := New()
+
+			start := time.Now()
+			err := h.Execute(t.Context(),
+				func(ctx context.Context) error {
+					go func() {
+						time.Sleep(200 * time.Millisecond)
+						interrupt(t)
+					}()
+					return Run(ctx, "testdata/util.sh", WithArgs("wait"), WithStdOut(&output))
+				},
+			)
+
+			assert.ErrorIs(t, err, ErrInterrupted)
+			assert.Contains(t, output.String(), "stopped")
+			assert.Less(t, time.Since(start), 4*time.Second)
+		},
+	)
+}
@@ -0,0 +1,431 @@
+package harness
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// WithSSH runs the command on a remote machine over ssh, streaming its output back as if
+// it was run locally; the exit code of the remote command is the one reported.
+// The host is either "host" or "user@host", the user defaulting to the local one.
+//
+// The command and its arguments are quoted for the remote shell, unless [WithShell] is used,
+// in which case they're sent as a script. Options like [WithEnv] and [WithDir] apply locally;
+// use [WithSSHEnv] and [WithSSHDir] for the remote command.
+//
+// Keys are taken from the ssh agent, the file passed via [WithSSHIdentity] and the default
+// unencrypted keys in ~/.ssh, and the host key is verified against ~/.ssh/known_hosts, or the
+// files passed via [WithSSHKnownHosts]. When [WithInteractive] is used, a terminal is
+// requested for the remote command.
+func WithSSH(host string, opts ...SSHOpt) RunnerOpt {
+	return func(r *TaskRunner) error {
+		if host == "" {
+			return errors.New("ssh host can't be empty")
+		}
+
+		conf := sshconf{host: host, port: 22}
+		for _, opt := range opts {
+			opt(&conf)
+		}
+
+		r.remote = &conf
+		return nil
+	}
+}
+
+type sshconf struct {
+	host       string
+	port       int
+	identity   string
+	knownhosts []string
+	env        []string
+	dir        string
+	timeout    time.Duration
+
+	// command run on the remote host
+	command string
+}
+
+type SSHOpt func(c *sshconf)
+
+// WithSSHPort specifies the port ssh connects to, 22 by default.
+func WithSSHPort(port int) SSHOpt {
+	return func(c *sshconf) {
+		c.port = port
+	}
+}
+
+// WithSSHIdentity specifies the private key file used to authenticate.
+// Keys protected by a passphrase need to be added to the ssh agent instead.
+func WithSSHIdentity(path string) SSHOpt {
+	return func(c *sshconf) {
+		c.identity = path
+	}
+}
+
+// WithSSHKnownHosts specifies the known_hosts files the host key of the remote machine
+// is verified against, instead of ~/.ssh/known_hosts.
+func WithSSHKnownHosts(files ...string) SSHOpt {
+	return func(c *sshconf) {
+		c.knownhosts = append(c.knownhosts, files...)
+	}
+}
+
+// WithSSHTimeout bounds the time spent connecting to the remote machine, 30s by default.
+func WithSSHTimeout(timeout time.Duration) SSHOpt {
+	return func(c *sshconf) {
+		c.timeout = timeout
+	}
+}
+
+// WithSSHEnv sets up environment variables, as NAME=value, for the remote command.
+func WithSSHEnv(vars ...string) SSHOpt {
+	return func(c *sshconf) {
+		c.env = append(c.env, vars...)
+	}
+}
+
+// WithSSHDir specifies the directory the remote command runs in.
+func WithSSHDir(dir string) SSHOpt {
+	return func(c *sshconf) {
+		c.dir = dir
+	}
+}
+
+// useremote makes the command run on the remote host through ssh.
+func (r *TaskRunner) useremote() error {
+	if r.shell {
+		r.script = strings.Join(append([]string{r.Executable}, r.Arguments...), " ")
+	} else {
+		words := make([]string, 0, len(r.Arguments)+1)
+		for _, word := range append([]string{r.Executable}, r.Arguments...) {
+			words = append(words, shellquote(word))
+		}
+		r.script = strings.Join(words, " ")
+	}
+
+	command := r.script
+	if len(r.remote.env) > 0 {
+		vars := make([]string, 0, len(r.remote.env))
+		for _, vrb := range r.remote.env {
+			if name, _, found := strings.Cut(vrb, "="); !found || name == "" {
+				return fmt.Errorf("invalid env format; %s doesn't match NAME=value expectation", vrb)
+			}
+			vars = append(vars, shellquote(vrb))
+		}
+		// exported rather than passed to env, so scripts sent with [WithShell] see them too
+		command = fmt.Sprintf("export %s && %s", strings.Join(vars, " "), command)
+	}
+	if r.remote.dir != "" {
+		command = fmt.Sprintf("cd %s && %s", shellquote(r.remote.dir), command)
+	}
+	r.remote.command = command
+
+	// stdin is only forwarded when it's set explicitly or the command is interactive,
+	// as the terminal of the harness is rarely meant for remote commands
+	if r.cmd.Stdin == os.Stdin && !r.interactive {
+		r.cmd.Stdin = nil
+	}
+
+	r.cmd.Err = nil
+	r.cmd.Args = []string{r.remote.host, command}
+
+	return nil
+}
+
+// sshsession is a command running on a remote host.
+type sshsession struct {
+	client  *ssh.Client
+	session *ssh.Session
+	// stops watching the context of the command
+	unwatch func() bool
+
+	mtx    sync.Mutex
+	exited bool
+	code   int
+}
+
+// startremote connects to the remote host and starts the command there.
+func (r *TaskRunner) startremote() error {
+	client, err := r.remote.dial(r.ctx)
+	if err != nil {
+		return err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to open ssh session: %w", err), client.Close())
+	}
+
+	stdout, stderr := r.cmd.Stdout, r.cmd.Stderr
+	// both streams are copied concurrently, so a shared writer needs to be synchronized
+	if stdout != nil && samewriter(stdout, stderr) {
+		stdout = internal.NewSyncWriter(stdout)
+		stderr = stdout
+	}
+	session.Stdout, session.Stderr = stdout, stderr
+
+	if r.cmd.Stdin != nil {
+		stdin, err := session.StdinPipe()
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to open ssh stdin: %w", err), client.Close())
+		}
+		// the session doesn't wait for stdin, which may never end, like the terminal's
+		go func() {
+			_, _ = io.Copy(stdin, r.cmd.Stdin)
+			_ = stdin.Close()
+		}()
+	}
+
+	if r.interactive {
+		modes := ssh.TerminalModes{ssh.ECHO: 1}
+		if err := session.RequestPty(cmp.Or(os.Getenv("TERM"), "xterm"), 40, 80, modes); err != nil {
+			return errors.Join(fmt.Errorf("failed to request terminal: %w", err), client.Close())
+		}
+	}
+
+	if err := session.Start(r.remote.command); err != nil {
+		return errors.Join(fmt.Errorf("failed to start remote command: %w", err), client.Close())
+	}
+
+	remote := &sshsession{client: client, session: session}
+	r.groupmtx.Lock()
+	r.session = remote
+	r.groupmtx.Unlock()
+
+	// stop the remote command like local commands are stopped when the context is done
+	remote.unwatch = context.AfterFunc(r.ctx, func() {
+		_ = r.cmd.Cancel()
+		if r.cmd.WaitDelay > 0 {
+			time.AfterFunc(r.cmd.WaitDelay, func() { _ = remote.kill() })
+		}
+	})
+
+	return nil
+}
+
+// waitremote waits for the remote command to exit and closes the connection.
+func (r *TaskRunner) waitremote() error {
+	remote := r.currentsession()
+	if remote == nil {
+		return errors.New("remote command not started")
+	}
+	defer remote.unwatch()
+
+	err := remote.session.Wait()
+
+	remote.mtx.Lock()
+	remote.exited = true
+	remote.code = 0
+	var exiterr *ssh.ExitError
+	switch {
+	case errors.As(err, &exiterr) && exiterr.Signal() == "":
+		remote.code = exiterr.ExitStatus()
+	case err != nil:
+		remote.code = -1
+	}
+	remote.mtx.Unlock()
+
+	if closerr := remote.client.Close(); closerr != nil && !errors.Is(closerr, net.ErrClosed) {
+		err = errors.Join(err, fmt.Errorf("failed to close ssh connection: %w", closerr))
+	}
+
+	if err != nil && r.ctx.Err() != nil {
+		return errors.Join(err, r.ctx.Err())
+	}
+	return err
+}
+
+func (r *TaskRunner) currentsession() *sshsession {
+	r.groupmtx.Lock()
+	defer r.groupmtx.Unlock()
+
+	return r.session
+}
+
+// exitcode returns the exit code of the remote command, or -1 if it hasn't exited
+// or was terminated by a signal.
+func (s *sshsession) exitcode() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.exited {
+		return -1
+	}
+	return s.code
+}
+
+// terminate asks the remote command to shut down.
+func (s *sshsession) terminate() error {
+	return s.session.Signal(ssh.SIGTERM)
+}
+
+// kill the remote command, closing the connection so it's not waited for any longer.
+func (s *sshsession) kill() error {
+	_ = s.session.Signal(ssh.SIGKILL)
+	return s.client.Close()
+}
+
+// dial connects to the remote host.
+func (c *sshconf) dial(ctx context.Context) (*ssh.Client, error) {
+	username, host := "", c.host
+	if idx := strings.LastIndex(host, "@"); idx >= 0 {
+		username, host = host[:idx], host[idx+1:]
+	}
+	if username == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the current user: %w", err)
+		}
+		username = current.Username
+	}
+
+	auth, closeagent, err := c.auth()
+	if err != nil {
+		return nil, err
+	}
+	defer closeagent()
+
+	hostkeys, err := c.hostkeys()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(host, strconv.Itoa(c.port))
+	conn, err := new(net.Dialer).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	// the handshake doesn't take a context, so it's aborted by closing the connection
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	config := &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostkeys,
+	}
+
+	sshconn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	return ssh.NewClient(sshconn, channels, requests), nil
+}
+
+// auth returns the authentication with the keys in the agent, the identity file and the
+// default keys, along with a function closing the connection to the agent.
+// The keys are offered through a single method, as ssh only tries each method once.
+func (c *sshconf) auth() (ssh.AuthMethod, func(), error) {
+	var (
+		signers []ssh.Signer
+		agentcl agent.ExtendedAgent
+		closeit = func() {}
+	)
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentcl = agent.NewClient(conn)
+			closeit = func() { _ = conn.Close() }
+		}
+	}
+
+	if c.identity != "" {
+		signer, err := readsigner(c.identity)
+		if err != nil {
+			closeit()
+			return nil, nil, err
+		}
+		signers = append(signers, signer)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			// default keys are best effort, as they may be missing or need a passphrase
+			if signer, err := readsigner(filepath.Join(home, ".ssh", name)); err == nil {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	auth := ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		if agentcl == nil {
+			return signers, nil
+		}
+		fromagent, err := agentcl.Signers()
+		if err != nil {
+			return signers, nil
+		}
+		return append(fromagent, signers...), nil
+	})
+
+	return auth, closeit, nil
+}
+
+// readsigner reads the unencrypted private key file.
+func readsigner(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// hostkeys returns the verification of host keys against the known hosts files.
+func (c *sshconf) hostkeys() (ssh.HostKeyCallback, error) {
+	files := c.knownhosts
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known hosts: %w", err)
+		}
+		files = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// shellquote quotes the word for posix shells, unless it doesn't need to.
+func shellquote(word string) string {
+	unsafe := func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./=:,@%+", c))
+	}
+
+	if word != "" && !strings.ContainsFunc(word, unsafe) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,282 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSH(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test server runs commands through sh")
+	}
+
+	host, port, identity, known := sshserver(t)
+	opts := []SSHOpt{WithSSHPort(port), WithSSHIdentity(identity), WithSSHKnownHosts(known)}
+
+	t.Run("builds the remote command",
+		func(t *testing.T) {
+			r, err := Cmd(t.Context(), "go",
+				WithArgs("test", "./..."),
+				WithSSH("ci@agent",
+					WithSSHEnv("CGO_ENABLED=0"),
+					WithSSHDir("/srv/build dir"),
+				),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, "cd '/srv/build dir' && export CGO_ENABLED=0 && go test ./...", r.remote.command)
+			assert.Equal(t, "ci@agent: go test ./...", r.describe())
+		},
+	)
+
+	t.Run("quotes arguments for the remote shell",
+		func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := Run(t.Context(), "printf", WithArgs("%s|", "it's", "$HOME", "a b"), WithSSH(host, opts...), WithStdOut(&out))
+			require.NoError(t, err)
+			assert.Equal(t, "it's|$HOME|a b|", out.String())
+		},
+	)
+
+	t.Run("runs in the remote dir with the remote env",
+		func(t *testing.T) {
+			dir := t.TempDir()
+			out, err := Output(t.Context(), `echo "$GREETING from $(pwd)"`,
+				WithShell(),
+				WithSSH(host, append(opts, WithSSHEnv("GREETING=hello"), WithSSHDir(dir))...),
+			)
+			require.NoError(t, err)
+
+			resolved, err := filepath.EvalSymlinks(dir)
+			require.NoError(t, err)
+			assert.Equal(t, "hello from "+resolved, out)
+		},
+	)
+
+	t.Run("forwards stdin",
+		func(t *testing.T) {
+			out, err := Output(t.Context(), "cat", WithSSH(host, opts...), WithStdInString("piped"))
+			require.NoError(t, err)
+			assert.Equal(t, "piped", out)
+		},
+	)
+
+	t.Run("reports the remote exit code and stderr",
+		func(t *testing.T) {
+			var stderr bytes.Buffer
+			err := Run(t.Context(), "echo broken >&2; exit 3", WithShell(), WithSSH(host, opts...), WithStdErr(&stderr))
+
+			var cmderr *CmdError
+			require.ErrorAs(t, err, &cmderr)
+			assert.Equal(t, 3, cmderr.ExitCode)
+			assert.Equal(t, "broken\n", cmderr.Stderr)
+			assert.Equal(t, "broken\n", stderr.String())
+		},
+	)
+
+	t.Run("accepts expected exit codes",
+		func(t *testing.T) {
+			err := Run(t.Context(), "exit 1", WithShell(), WithSSH(host, opts...), WithExpectedExitCodes(0, 1))
+			require.NoError(t, err)
+		},
+	)
+
+	t.Run("stops the remote command when cancelled",
+		func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := Run(ctx, "sleep", WithArgs("10"), WithSSH(host, opts...))
+			require.Error(t, err)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		},
+	)
+
+	t.Run("rejects unknown host keys",
+		func(t *testing.T) {
+			empty := filepath.Join(t.TempDir(), "known_hosts")
+			require.NoError(t, os.WriteFile(empty, nil, 0o600))
+
+			err := Run(t.Context(), "true", WithSSH(host, WithSSHPort(port), WithSSHIdentity(identity), WithSSHKnownHosts(empty)))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "key is unknown")
+		},
+	)
+
+	t.Run("returns error for invalid remote env",
+		func(t *testing.T) {
+			_, err := Cmd(t.Context(), "go", WithSSH("agent", WithSSHEnv("INVALID")))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "doesn't match NAME=value expectation")
+		},
+	)
+}
+
+// sshserver starts an ssh server running the commands it gets locally through sh, returning
+// the host and port to connect to, the key to authenticate with and the known hosts file
+// trusting its host key.
+func sshserver(t *testing.T) (host string, port int, identity, known string) {
+	t.Helper()
+
+	_, hostkey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostsigner, err := ssh.NewSignerFromKey(hostkey)
+	require.NoError(t, err)
+
+	clientpub, clientkey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authorized, err := ssh.NewPublicKey(clientpub)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostsigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go servessh(conn, config)
+		}
+	}()
+
+	dir := t.TempDir()
+
+	block, err := ssh.MarshalPrivateKey(clientkey, "")
+	require.NoError(t, err)
+	identity = filepath.Join(dir, "id_ed25519")
+	require.NoError(t, os.WriteFile(identity, pem.EncodeToMemory(block), 0o600))
+
+	address := listener.Addr().String()
+	known = filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, hostsigner.PublicKey())
+	require.NoError(t, os.WriteFile(known, []byte(line+"\n"), 0o600))
+
+	// keys in the agent of whoever runs the tests are irrelevant
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, portstr, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	port, err = strconv.Atoi(portstr)
+	require.NoError(t, err)
+
+	return "tester@127.0.0.1", port, identity, known
+}
+
+// servessh runs the commands of the sessions opened on the connection.
+func servessh(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for incoming := range channels {
+		if incoming.ChannelType() != "session" {
+			_ = incoming.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+
+		channel, requests, err := incoming.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			defer channel.Close()
+
+			var cmd *exec.Cmd
+			exited := make(chan struct{})
+
+			for req := range requests {
+				switch req.Type {
+				case "pty-req":
+					_ = req.Reply(true, nil)
+
+				case "exec":
+					var payload struct{ Command string }
+					if ssh.Unmarshal(req.Payload, &payload) != nil || cmd != nil {
+						_ = req.Reply(false, nil)
+						continue
+					}
+
+					cmd = exec.Command("sh", "-c", payload.Command)
+					cmd.Stdout, cmd.Stderr = channel, channel.Stderr()
+					stdin, err := cmd.StdinPipe()
+					if err == nil {
+						err = cmd.Start()
+					}
+					if err != nil {
+						_ = req.Reply(false, nil)
+						return
+					}
+					_ = req.Reply(true, nil)
+
+					go func() {
+						_, _ = io.Copy(stdin, channel)
+						_ = stdin.Close()
+					}()
+
+					go func() {
+						defer close(exited)
+
+						status := 0
+						var exiterr *exec.ExitError
+						if err := cmd.Wait(); errors.As(err, &exiterr) {
+							status = max(exiterr.ExitCode(), 0)
+						}
+						_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+						_ = channel.Close()
+					}()
+
+				case "signal":
+					if cmd != nil && cmd.Process != nil {
+						_ = cmd.Process.Signal(syscall.SIGKILL)
+					}
+
+				default:
+					if req.WantReply {
+						_ = req.Reply(false, nil)
+					}
+				}
+			}
+
+			if cmd != nil {
+				<-exited
+			}
+		}()
+	}
+}
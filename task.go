@@ -0,0 +1,275 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/aexvir/harness/internal"
+)
+
+// TaskResult holds the outcome of a task run inside a harness.
+type TaskResult struct {
+	// Name of the task as specified via [Named] or its [TaskDef], or its position in the
+	// execution e.g. "task 2" when it wasn't named.
+	// Tasks skipped in fail fast mode never run, so only the ones run via [Harness.ExecuteGraph]
+	// keep their name; the rest are identified by their position.
+	Name string
+	// Duration is how long the task took to run.
+	Duration time.Duration
+	// Err is the error returned by the task, nil if it succeeded.
+	Err error
+	// Skipped is set when the task wasn't run, either because a previous task failed
+	// in fail fast mode or because its tags were filtered out.
+	Skipped bool
+	// Cached is set when the task wasn't run because its inputs didn't change; see [Cached].
+	Cached bool
+	// Output contains the output of the commands run by the task; only captured
+	// when the harness is configured with [WithTaskOutputCapture].
+	Output string
+}
+
+// status describes the outcome of the task: passed, failed, skipped or cached.
+func (r TaskResult) status() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.Err != nil:
+		return "failed"
+	case r.Cached:
+		return "cached"
+	default:
+		return "passed"
+	}
+}
+
+// Named gives a name to a task, which is used to identify it in execution results and summaries.
+// When named multiple times, the outermost name is the one used.
+func Named(name string, task Task) Task {
+	return func(ctx context.Context) error {
+		if state := currenttask(ctx); state != nil {
+			state.setname(name)
+			state.begin()
+		}
+		return task(ctx)
+	}
+}
+
+// WithTimeout bounds how long the task can run; its context is cancelled once the timeout
+// is reached, which also stops any command run with it.
+func WithTimeout(task Task, timeout time.Duration) Task {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := task(ctx)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		return err
+	}
+}
+
+// WithRetry runs the task up to the specified amount of attempts until it succeeds.
+// Between attempts it waits for the backoff, which is doubled after every failed attempt.
+// Retrying stops as soon as the context is cancelled, returning the last error.
+func WithRetry(task Task, attempts int, backoff time.Duration) Task {
+	return func(ctx context.Context) error {
+		var err error
+
+		for attempt := 1; attempt <= attempts; attempt++ {
+			if err = task(ctx); err == nil {
+				return nil
+			}
+
+			if ctx.Err() != nil {
+				return fmt.Errorf("retry cancelled: %w", err)
+			}
+
+			if attempt == attempts {
+				break
+			}
+
+			internal.LogDetail(fmt.Sprintf("attempt %d of %d failed, retrying in %s", attempt, attempts, backoff))
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return fmt.Errorf("retry cancelled: %w", errors.Join(err, ctx.Err()))
+			}
+
+			backoff *= 2
+		}
+
+		if attempts > 1 {
+			return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+		}
+		return err
+	}
+}
+
+// Tagged attaches tags to the task, e.g. "slow", "network" or "ci-only", which allow
+// filtering which tasks are run via [WithIncludeTags] and [WithExcludeTags].
+// Tasks with excluded tags are skipped without being run.
+func Tagged(task Task, tags ...string) Task {
+	return func(ctx context.Context) error {
+		if state := currenttask(ctx); state != nil && state.exclude != nil {
+			if reason, skip := state.exclude(tags); skip {
+				internal.LogStep(fmt.Sprintf("skipping %s, %s", state.displayname(), reason))
+				state.setskipped()
+				return nil
+			}
+		}
+		return task(ctx)
+	}
+}
+
+// ContinueOnError marks the task so its failure doesn't stop the execution when the harness
+// is in fail fast mode. The failure is still reported as part of the execution result.
+func ContinueOnError(task Task) Task {
+	return func(ctx context.Context) error {
+		if state := currenttask(ctx); state != nil {
+			state.mtx.Lock()
+			state.continueonerror = true
+			state.mtx.Unlock()
+		}
+		return task(ctx)
+	}
+}
+
+// PanicError is returned for tasks that panicked while running inside a harness.
+type PanicError struct {
+	// Value passed to panic.
+	Value any
+	// Stack of the goroutine at the moment of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// runtask runs the task, converting a panic into a [PanicError].
+func runtask(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			perr := &PanicError{Value: value, Stack: debug.Stack()}
+			internal.LogError(perr.Error())
+			internal.LogMessage(color.FgHiBlack, string(perr.Stack))
+			err = perr
+		}
+	}()
+
+	return task(ctx)
+}
+
+var errAborted = errors.New("skipped after a previous task failed")
+
+type taskctxkey struct{}
+
+// taskstate holds what's known about a task while it runs inside a harness; it's passed
+// down to the task via its context so task wrappers and runners can enrich it.
+type taskstate struct {
+	mtx  sync.Mutex
+	idx  int
+	name string
+
+	// called once, with the task name, right before the task produces any output
+	onbegin func(name string)
+	began   sync.Once
+
+	continueonerror bool
+	cached          bool
+	skipped         bool
+
+	// how long commands are given to exit when the execution is interrupted
+	grace time.Duration
+
+	// width the task name is padded to when prefixing command output; 0 disables prefixing
+	prefixwidth int
+
+	// decides if a task with the specified tags is skipped, and why
+	exclude func(tags []string) (string, bool)
+
+	// output of the commands run by the task; nil when output isn't captured
+	output *bytes.Buffer
+	writer io.Writer
+}
+
+// currenttask returns the state of the task running with the context, if any.
+func currenttask(ctx context.Context) *taskstate {
+	state, _ := ctx.Value(taskctxkey{}).(*taskstate)
+	return state
+}
+
+func (s *taskstate) setname(name string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.name == "" {
+		s.name = name
+	}
+}
+
+func (s *taskstate) setcached() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.cached = true
+}
+
+func (s *taskstate) setskipped() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.skipped = true
+}
+
+// displayname returns the name of the task, defaulting to its position in the execution.
+func (s *taskstate) displayname() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.name == "" {
+		return fmt.Sprintf("task %d", s.idx+1)
+	}
+	return s.name
+}
+
+// begin signals that the task is about to produce output; as tasks are only named once
+// they start running, this is the earliest point at which their name is known.
+func (s *taskstate) begin() {
+	s.began.Do(func() {
+		if s.onbegin != nil {
+			s.onbegin(s.displayname())
+		}
+	})
+}
+
+// result builds the result of the task once it finished.
+func (s *taskstate) result(duration time.Duration, err error) TaskResult {
+	result := TaskResult{
+		Name:     s.displayname(),
+		Duration: duration,
+		Err:      err,
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	result.Cached = s.cached
+	result.Skipped = s.skipped
+
+	if s.output != nil {
+		result.Output = s.output.String()
+	}
+
+	return result
+}
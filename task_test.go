@@ -0,0 +1,111 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("passes through when task finishes in time",
+		func(t *testing.T) {
+			task := WithTimeout(func(_ context.Context) error { return nil }, time.Second)
+			assert.NoError(t, task(t.Context()))
+		},
+	)
+
+	t.Run("cancels the task after the timeout",
+		func(t *testing.T) {
+			task := WithTimeout(
+				func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+				10*time.Millisecond,
+			)
+
+			err := task(t.Context())
+			require.Error(t, err)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Contains(t, err.Error(), "timed out after 10ms")
+		},
+	)
+
+	t.Run("stops commands run by the task",
+		func(t *testing.T) {
+			task := WithTimeout(
+				func(ctx context.Context) error {
+					return Run(ctx, "sleep", WithArgs("5"))
+				},
+				50*time.Millisecond,
+			)
+
+			start := time.Now()
+			require.Error(t, task(t.Context()))
+			assert.Less(t, time.Since(start), 2*time.Second)
+		},
+	)
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Run("retries until the task succeeds",
+		func(t *testing.T) {
+			var calls int
+			task := WithRetry(
+				func(_ context.Context) error {
+					calls++
+					if calls < 3 {
+						return errors.New("flaky")
+					}
+					return nil
+				},
+				5, time.Millisecond,
+			)
+
+			require.NoError(t, task(t.Context()))
+			assert.Equal(t, 3, calls)
+		},
+	)
+
+	t.Run("returns last error when attempts are exhausted",
+		func(t *testing.T) {
+			var calls int
+			task := WithRetry(
+				func(_ context.Context) error {
+					calls++
+					return errors.New("boom")
+				},
+				3, time.Millisecond,
+			)
+
+			err := task(t.Context())
+			assert.EqualError(t, err, "failed after 3 attempts: boom")
+			assert.Equal(t, 3, calls)
+		},
+	)
+
+	t.Run("stops retrying when context is cancelled",
+		func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+
+			var calls int
+			task := WithRetry(
+				func(_ context.Context) error {
+					calls++
+					cancel()
+					return errors.New("boom")
+				},
+				5, time.Hour,
+			)
+
+			err := task(ctx)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "boom")
+			assert.Equal(t, 1, calls)
+		},
+	)
+}
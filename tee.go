@@ -0,0 +1,85 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithTeeFile duplicates the stdout and stderr of the command into a file, while still
+// writing them wherever they're configured to go; useful for archiving full logs as ci
+// artifacts. Missing directories are created and output is appended if the file exists.
+//
+// The path can contain a {time} placeholder, replaced with the time the command was set
+// up, e.g. "logs/build-{time}.log", so every run writes to a new file.
+func WithTeeFile(path string) RunnerOpt {
+	return func(r *TaskRunner) error {
+		if path == "" {
+			return errors.New("tee file path can't be empty")
+		}
+		r.teefile = &teefile{
+			path: strings.ReplaceAll(path, "{time}", time.Now().Format("20060102-150405")),
+		}
+		return nil
+	}
+}
+
+// teefile is a file that's opened on the first write and closed when flushed, so it's
+// only created for commands that actually run and is released once they exit.
+type teefile struct {
+	mtx  sync.Mutex
+	path string
+	file *os.File
+}
+
+// tee returns a writer writing both to w and the file.
+func (t *teefile) tee(w io.Writer) flushwriter {
+	return teewriter{Writer: io.MultiWriter(w, t), file: t}
+}
+
+func (t *teefile) Write(data []byte) (int, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.file == nil {
+		if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create directory for tee file %s: %w", t.path, err)
+		}
+
+		file, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open tee file %s: %w", t.path, err)
+		}
+		t.file = file
+	}
+
+	return t.file.Write(data)
+}
+
+// Flush closes the file; it's opened again if written to afterwards.
+func (t *teefile) Flush() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+type teewriter struct {
+	io.Writer
+	file *teefile
+}
+
+func (w teewriter) Flush() error {
+	return w.file.Flush()
+}
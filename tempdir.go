@@ -0,0 +1,60 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// WithTempDir runs the command inside a fresh temporary directory, which is removed once
+// the command exits; handy for commands producing scratch artifacts. The directory replaces
+// the one set via [WithDir], so relative paths in arguments are resolved against it.
+//
+// If collect isn't nil, it's called with the path of the directory after the command exits
+// and before the directory is removed, so artifacts worth keeping can be copied out.
+func WithTempDir(collect func(dir string) error) RunnerOpt {
+	return func(r *TaskRunner) error {
+		r.tempdir = &tempdir{collect: collect}
+		return nil
+	}
+}
+
+type tempdir struct {
+	collect func(dir string) error
+	path    string
+}
+
+// create the directory and make the command run inside it.
+func (t *tempdir) create(r *TaskRunner) error {
+	path, err := os.MkdirTemp("", "harness-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	t.path = path
+	r.cmd.Dir = path
+
+	return nil
+}
+
+// remove the directory, collecting its contents first if requested.
+func (t *tempdir) remove(ran bool) error {
+	if t.path == "" {
+		return nil
+	}
+
+	var errs []error
+
+	if ran && t.collect != nil {
+		if err := t.collect(t.path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to collect from temp dir: %w", err))
+		}
+	}
+
+	if err := os.RemoveAll(t.path); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove temp dir %s: %w", t.path, err))
+	}
+	t.path = ""
+
+	return errors.Join(errs...)
+}
@@ -4,5 +4,9 @@ case "$1" in
   fail)    printf "boom" >&2; exit 3 ;;
   print)   cat; exit 0 ;;
   pwd)     pwd; exit 0 ;;
+  mixed)   echo "one"; echo "two" >&2; echo "three"; exit 0 ;;
+  fork)    sleep 10 & wait; exit 0 ;;
+  wait)    trap 'kill $!; echo "stopped"; exit 0' TERM; sleep 5 & wait; exit 0 ;;
+  flaky)   n=$(cat "$2" 2>/dev/null || echo 0); echo $((n+1)) > "$2"; [ "$n" -ge "$3" ]; exit $? ;;
   *)       exit 2 ;;
 esac